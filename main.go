@@ -2,25 +2,79 @@ package main
 
 import (
 	"api/src/config"
+	"api/src/controllers"
+	"api/src/database"
+	"api/src/database/migrations"
+	"api/src/mail"
 	"api/src/router"
+	"api/src/storage"
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	_ "api/docs"
 
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
+// shutdownTimeout bounds how long the server waits for in-flight requests on shutdown
+const shutdownTimeout = 15 * time.Second
+
 // @title SocialMedia-API
 // @description RESTful API developed in Golang, intended to serve as the backend for a social networking application
 // @securityDefinitions.apikey Bearer
 // @in header
 // @name Authorization
-// @description Provide the JWT token with prefix 'Bearer ' in the text box.
+// @description Provide the JWT token, or a personal access token starting with pat_, with prefix 'Bearer ' in the text box. Personal access tokens only open the routes their scopes allow.
 func main() {
 	config.Load()
-	r := router.Generate()
+
+	db, err := database.Connect()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err = migrate(db, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	applied, err := migrations.Up(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, migration := range applied {
+		fmt.Printf("applied  %04d_%s\n", migration.Version, migration.Name)
+	}
+
+	files, err := storage.FromConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mailer, err := mail.FromConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	controller := controllers.NewController(db, files, mailer)
+	r := router.Generate(controller)
+
+	// The S3 backend serves the files from the bucket, the local one needs the API to serve them
+	if local, ok := files.(*storage.Local); ok {
+		r.PathPrefix(config.MediaURL + "/").Handler(http.StripPrefix(config.MediaURL, local))
+	}
 
 	r.PathPrefix("/swagger/").Handler(httpSwagger.Handler(
 		httpSwagger.URL("/docs/swagger.json"),
@@ -28,6 +82,90 @@ func main() {
 
 	r.PathPrefix("/docs/").Handler(http.StripPrefix("/docs/", http.FileServer(http.Dir("./docs"))))
 
-	fmt.Println("Escutando na porta", config.Port)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", config.Port), r))
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", config.Port),
+		Handler: r,
+	}
+
+	// Event streams never finish on their own, closing the broker ends them so Shutdown does not wait for the timeout
+	server.RegisterOnShutdown(controller.Events.Close)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// A server that fails to start or stops on its own returns here, so the deferred cleanup still runs
+	serveErr := make(chan error, 1)
+	go func() {
+		fmt.Println("Escutando na porta", config.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		log.Printf("Error serving the API: %v", err)
+		return
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Stop accepting connections and let in-flight requests finish before the pool is closed
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down the server: %v", err)
+	}
+}
+
+// migrate runs the migrate subcommand: up, down [steps], status or seed
+func migrate(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up | down [steps] | status | seed")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrations.Up(db)
+		for _, migration := range applied {
+			fmt.Printf("applied  %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errors.New("the number of steps must be a positive number")
+			}
+		}
+
+		reverted, err := migrations.Down(db, steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+
+	case "status":
+		states, err := migrations.Status(db)
+		if err != nil {
+			return err
+		}
+
+		for _, state := range states {
+			appliedAt := "pending"
+			if state.AppliedAt != nil {
+				appliedAt = state.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%-40s %s\n", state.Version, state.Name, appliedAt)
+		}
+		return nil
+
+	case "seed":
+		return migrations.Seed(db)
+	}
+
+	return fmt.Errorf("unknown migrate command %q", args[0])
 }
@@ -0,0 +1,106 @@
+package authentication
+
+import (
+	"api/src/models"
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+)
+
+// Principal represents who is making an authenticated request
+type Principal struct {
+	UserID    uint64
+	SessionID string
+	Role      string
+	// AccessTokenID is set instead of SessionID when a personal access token authenticated
+	// the request, the token can only do what its Scopes allow
+	AccessTokenID uint64
+	Scopes        []string
+}
+
+// roleRanks orders the roles, a role is granted everything the lower ranks are
+var roleRanks = map[string]int{
+	models.RoleUser:      0,
+	models.RoleModerator: 1,
+	models.RoleAdmin:     2,
+}
+
+// HasRole reports whether the principal has one of the roles, or a role above it
+func (principal Principal) HasRole(roles ...string) bool {
+	rank, ok := roleRanks[principal.Role]
+	if !ok {
+		return false
+	}
+
+	for _, role := range roles {
+		if required, ok := roleRanks[role]; ok && rank >= required {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasScopes reports whether the principal can use a route that needs the scopes, sessions are not limited
+// by scopes and personal access tokens cannot use the routes that do not declare any
+func (principal Principal) HasScopes(scopes ...string) bool {
+	if principal.AccessTokenID == 0 {
+		return true
+	}
+
+	if len(scopes) == 0 {
+		return false
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(principal.Scopes, scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of the context carrying the principal
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored in the context by the authentication middleware
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// ExtractPrincipal returns the principal of an authenticated request
+func ExtractPrincipal(r *http.Request) (Principal, error) {
+	principal, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		return Principal{}, errors.New("the request is not authenticated")
+	}
+
+	return principal, nil
+}
+
+// ExtractUserID returns the ID of the user making an authenticated request
+func ExtractUserID(r *http.Request) (uint64, error) {
+	principal, err := ExtractPrincipal(r)
+	if err != nil {
+		return 0, err
+	}
+
+	return principal.UserID, nil
+}
+
+// ExtractSessionID returns the ID of the session behind an authenticated request
+func ExtractSessionID(r *http.Request) (string, error) {
+	principal, err := ExtractPrincipal(r)
+	if err != nil {
+		return "", err
+	}
+
+	return principal.SessionID, nil
+}
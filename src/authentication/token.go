@@ -2,6 +2,7 @@ package authentication
 
 import (
 	"api/src/config"
+	"api/src/models"
 	"errors"
 	"fmt"
 	"net/http"
@@ -12,31 +13,25 @@ import (
 	"github.com/dgrijalva/jwt-go"
 )
 
-func CreateToken(userID uint64) (string, error) {
+const (
+	// AccessTokenDuration is how long a JWT is accepted, sessions outlive it through refresh tokens
+	AccessTokenDuration = 15 * time.Minute
+	// RefreshTokenDuration is how long a session can stay idle before it has to log in again
+	RefreshTokenDuration = 30 * 24 * time.Hour
+)
+
+// CreateToken creates the access token of a session, the role is read back by the authorization middleware
+func CreateToken(userID uint64, sessionID, role string) (string, error) {
 	permissions := jwt.MapClaims{}
 	permissions["authorized"] = true
-	permissions["exp"] = time.Now().Add(time.Hour * 6).Unix()
+	permissions["exp"] = time.Now().Add(AccessTokenDuration).Unix()
 	permissions["userID"] = userID
+	permissions["jti"] = sessionID
+	permissions["role"] = role
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, permissions)
 	return token.SignedString([]byte(config.SecretKey))
 }
 
-// Checks whether the token passed in the request is valid
-func ValidateToken(r *http.Request) error {
-	tokenString := extractToken(r)
-	token, err := jwt.Parse(tokenString, returnVerificationKey)
-
-	if err != nil {
-		return err
-	}
-
-	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return nil
-	}
-
-	return errors.New("invalid token")
-}
-
 func extractToken(r *http.Request) string {
 	token := r.Header.Get("Authorization")
 	if len(strings.Split(token, " ")) == 2 {
@@ -46,6 +41,15 @@ func extractToken(r *http.Request) string {
 	return ""
 }
 
+// ExtractAccessToken returns the personal access token sent in the request, it is empty when the request carries a JWT
+func ExtractAccessToken(r *http.Request) string {
+	if token := extractToken(r); strings.HasPrefix(token, models.AccessTokenPrefix) {
+		return token
+	}
+
+	return ""
+}
+
 func returnVerificationKey(token *jwt.Token) (interface{}, error) {
 	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 		return nil, fmt.Errorf("unexpected subscription method %v", token.Header["alg"])
@@ -54,22 +58,33 @@ func returnVerificationKey(token *jwt.Token) (interface{}, error) {
 	return config.SecretKey, nil
 }
 
-// ExtractUserID returns the userID that is saved in the token
-func ExtractUserID(r *http.Request) (uint64, error) {
+// ParseToken validates the token sent in the request and returns the principal it was issued for
+func ParseToken(r *http.Request) (Principal, error) {
 	tokenString := extractToken(r)
 	token, err := jwt.Parse(tokenString, returnVerificationKey)
 	if err != nil {
-		return 0, err
+		return Principal{}, err
 	}
 
-	if permissions, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID, err := strconv.ParseUint(fmt.Sprintf("%.0f", permissions["userID"]), 10, 64)
-		if err != nil {
-			return 0, err
-		}
+	permissions, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return Principal{}, errors.New("invalid token")
+	}
+
+	userID, err := strconv.ParseUint(fmt.Sprintf("%.0f", permissions["userID"]), 10, 64)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	sessionID, ok := permissions["jti"].(string)
+	if !ok || sessionID == "" {
+		return Principal{}, errors.New("invalid token")
+	}
 
-		return userID, nil
+	role, _ := permissions["role"].(string)
+	if role == "" {
+		role = models.RoleUser
 	}
 
-	return 0, errors.New("invalid token")
+	return Principal{UserID: userID, SessionID: sessionID, Role: role}, nil
 }
@@ -13,6 +13,38 @@ var (
 	ConnectionString = ""
 	Port             = 0
 	SecretKey        []byte
+
+	// TrustProxy makes the rate limits read the client address from X-Forwarded-For,
+	// it must only be set when a proxy in front of the API overwrites that header
+	TrustProxy = false
+
+	// StorageBackend chooses where uploaded images are kept, local or s3
+	StorageBackend = "local"
+	MediaDir       = "./media"
+	MediaURL       = "/media"
+
+	S3Endpoint        = ""
+	S3Region          = ""
+	S3Bucket          = ""
+	S3AccessKeyID     = ""
+	S3SecretAccessKey = ""
+	S3PublicURL       = ""
+
+	// AppURL is the address of the web application, the links sent by email point to it
+	AppURL = "http://localhost:3000"
+
+	// TOTPIssuer names the account in the authenticator apps of the users who turn on two-factor authentication
+	TOTPIssuer = "SocialMedia-API"
+
+	// MailBackend chooses how emails are delivered, outbox keeps them in files and smtp sends them
+	MailBackend   = "outbox"
+	MailOutboxDir = "./outbox"
+	MailFrom      = ""
+
+	SMTPHost     = ""
+	SMTPPort     = 587
+	SMTPUsername = ""
+	SMTPPassword = ""
 )
 
 // Initialize environment variables
@@ -29,11 +61,46 @@ func Load() {
 	}
 
 	ConnectionString = fmt.Sprintf(
-		"%s:%s@tcp(%s:3306)/%s?charset=utf8&parseTime=True&loc=Local",
+		"%s:%s@tcp(%s:3306)/%s?charset=utf8mb4,utf8&parseTime=True&loc=Local",
 		os.Getenv("DB_USER"),
 		os.Getenv("DB_PASSWORD"),
 		os.Getenv("DB_IP"),
 		os.Getenv("DB_NAME"),
 	)
 	SecretKey = []byte(os.Getenv("SECRET_KEY"))
+	TrustProxy, _ = strconv.ParseBool(os.Getenv("TRUST_PROXY"))
+
+	StorageBackend = getenv("STORAGE_BACKEND", StorageBackend)
+	MediaDir = getenv("MEDIA_DIR", MediaDir)
+	MediaURL = getenv("MEDIA_URL", MediaURL)
+
+	S3Endpoint = os.Getenv("S3_ENDPOINT")
+	S3Region = getenv("S3_REGION", "us-east-1")
+	S3Bucket = os.Getenv("S3_BUCKET")
+	S3AccessKeyID = os.Getenv("S3_ACCESS_KEY_ID")
+	S3SecretAccessKey = os.Getenv("S3_SECRET_ACCESS_KEY")
+	S3PublicURL = os.Getenv("S3_PUBLIC_URL")
+
+	AppURL = getenv("APP_URL", AppURL)
+	TOTPIssuer = getenv("TOTP_ISSUER", TOTPIssuer)
+
+	MailBackend = getenv("MAIL_BACKEND", MailBackend)
+	MailOutboxDir = getenv("MAIL_OUTBOX_DIR", MailOutboxDir)
+	MailFrom = os.Getenv("MAIL_FROM")
+
+	SMTPHost = os.Getenv("SMTP_HOST")
+	if SMTPPort, err = strconv.Atoi(os.Getenv("SMTP_PORT")); err != nil {
+		SMTPPort = 587
+	}
+	SMTPUsername = os.Getenv("SMTP_USERNAME")
+	SMTPPassword = os.Getenv("SMTP_PASSWORD")
+}
+
+// getenv returns the environment variable or the fallback when it is not set
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
 }
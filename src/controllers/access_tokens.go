@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/responses"
+	"api/src/security"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// @Summary Create a personal access token
+// @Description Create a token for scripts and integrations that works in place of a session on the routes its scopes open, until it expires or is revoked. The token is only shown in this answer
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body models.AccessTokenRequest true "Name, scopes and optional expiry" example({"name": "bot", "scopes": ["posts:read", "posts:write"], "expiresAt": "2030-01-01T00:00:00Z"})
+// @Success 201 {object} models.NewAccessToken
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 422 {object} responses.Problem "Unprocessable Entity"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /auth/tokens [post]
+func (controller *Controller) CreateAccessToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	requestBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var request models.AccessTokenRequest
+	if err = json.Unmarshal(requestBody, &request); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	secret, err := security.RandomToken(32)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+	token := models.AccessTokenPrefix + secret
+
+	accessToken := models.AccessToken{
+		UserID:    userID,
+		Name:      request.Name,
+		TokenHash: security.HashToken(token),
+		Scopes:    request.Scopes,
+		ExpiresAt: request.ExpiresAt,
+	}
+
+	repository := controller.AccessTokens
+	accessToken.ID, err = repository.Create(accessToken)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	accessToken.TokenHash = ""
+	responses.JSON(w, http.StatusCreated, models.NewAccessToken{AccessToken: accessToken, Token: token})
+}
+
+// @Summary Get the personal access tokens
+// @Description Retrieve the personal access tokens of the authenticated user with when they were last used, newest first
+// @Tags authentication
+// @Produce json
+// @Security Bearer
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.AccessToken]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /auth/tokens [get]
+func (controller *Controller) GetAccessTokens(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.AccessTokens
+	tokens, err := repository.SearchByUser(userID, page)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, pagination.NewPage(tokens, page, accessTokenCursorID))
+}
+
+// @Summary Revoke a personal access token
+// @Description Delete a personal access token of the authenticated user, it stops working right away
+// @Tags authentication
+// @Security Bearer
+// @Param tokenId path int true "Token ID"
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /auth/tokens/{tokenId} [delete]
+func (controller *Controller) DeleteAccessToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	parameters := mux.Vars(r)
+	tokenID, err := strconv.ParseUint(parameters["tokenId"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.AccessTokens
+	if err = repository.Delete(tokenID, userID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+func accessTokenCursorID(token models.AccessToken) uint64 {
+	return token.ID
+}
@@ -0,0 +1,268 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/responses"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// @Summary Suspend a user
+// @Description Suspend a user and end all of their sessions, moderators can only suspend regular users
+// @Tags admin
+// @Security Bearer
+// @Param userID path int true "User ID"
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /admin/users/{userID}/suspend [post]
+func (controller *Controller) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	principal, err := authentication.ExtractPrincipal(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	parameters := mux.Vars(r)
+	userID, err := strconv.ParseUint(parameters["userID"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if userID == principal.UserID {
+		responses.Error(w, http.StatusForbidden, errors.New("It is not possible to suspend yourself"))
+		return
+	}
+
+	repository := controller.Users
+	user, err := repository.SearchByID(userID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if user.Role != models.RoleUser && !principal.HasRole(models.RoleAdmin) {
+		responses.Error(w, http.StatusForbidden, errors.New("Only admins can suspend moderators and admins"))
+		return
+	}
+
+	if err = repository.Suspend(userID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	sessions := controller.Sessions
+	if err = sessions.RevokeAllExcept(userID, ""); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// @Summary Lift the suspension of a user
+// @Description Allow a suspended user to log in again, moderators can only lift the suspension of regular users
+// @Tags admin
+// @Security Bearer
+// @Param userID path int true "User ID"
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /admin/users/{userID}/unsuspend [post]
+func (controller *Controller) UnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	principal, err := authentication.ExtractPrincipal(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	parameters := mux.Vars(r)
+	userID, err := strconv.ParseUint(parameters["userID"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.Users
+	user, err := repository.SearchByID(userID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if user.Role != models.RoleUser && !principal.HasRole(models.RoleAdmin) {
+		responses.Error(w, http.StatusForbidden, errors.New("Only admins can lift the suspension of moderators and admins"))
+		return
+	}
+
+	if err = repository.Unsuspend(userID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// @Summary Change the role of a user
+// @Description Promote or demote a user, the user has to log in again for the new role to apply
+// @Tags admin
+// @Accept json
+// @Security Bearer
+// @Param userID path int true "User ID"
+// @Param role body models.RoleRequest true "New role" example({"role": "moderator"})
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 422 {object} responses.Problem "Unprocessable Entity"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /admin/users/{userID}/role [put]
+func (controller *Controller) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	principal, err := authentication.ExtractPrincipal(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	parameters := mux.Vars(r)
+	userID, err := strconv.ParseUint(parameters["userID"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if userID == principal.UserID {
+		responses.Error(w, http.StatusForbidden, errors.New("It is not possible to change your own role"))
+		return
+	}
+
+	requestBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var request models.RoleRequest
+	if err = json.Unmarshal(requestBody, &request); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !models.ValidRole(request.Role) {
+		responses.Error(w, http.StatusBadRequest, errors.New("The role must be user, moderator or admin"))
+		return
+	}
+
+	repository := controller.Users
+	if _, err = repository.SearchByID(userID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err = repository.UpdateRole(userID, request.Role); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Tokens carry the role, so the old ones must not outlive the change
+	sessions := controller.Sessions
+	if err = sessions.RevokeAllExcept(userID, ""); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// @Summary Delete any post
+// @Description Delete a post regardless of its author
+// @Tags admin
+// @Security Bearer
+// @Param postId path int true "Post ID"
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /admin/posts/{postId} [delete]
+func (controller *Controller) AdminDeletePost(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	postID, err := strconv.ParseUint(params["postId"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	attachments, err := controller.Attachments.SearchByPosts([]uint64{postID})
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	repository := controller.Posts
+	if err = repository.Delete(postID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	controller.deleteFiles(attachments)
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// @Summary Get the login attempts of a user
+// @Description Retrieve the audit trail of the logins to the account of a user, newest first
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param userID path int true "User ID"
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.LoginAttempt]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /admin/users/{userID}/login-attempts [get]
+func (controller *Controller) GetLoginAttempts(w http.ResponseWriter, r *http.Request) {
+	parameters := mux.Vars(r)
+	userID, err := strconv.ParseUint(parameters["userID"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.LoginAttempts
+	attempts, err := repository.SearchByUser(userID, page)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, pagination.NewPage(attempts, page, loginAttemptCursorID))
+}
+
+func loginAttemptCursorID(attempt models.LoginAttempt) uint64 {
+	return attempt.ID
+}
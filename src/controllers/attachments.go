@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/media"
+	"api/src/models"
+	"api/src/repositories"
+	"api/src/responses"
+	"api/src/security"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// maxUploadSize leaves room for the multipart headers and the alt text around the image
+const maxUploadSize = media.MaxSize + 64<<10
+
+// errTooManyAttachments is returned when the post already carries all the images it can
+var errTooManyAttachments = fmt.Errorf("A post cannot have more than %d images", models.MaxAttachments)
+
+// @Summary Attach an image to a post
+// @Description Upload a JPEG, PNG or GIF image of at most 5 MB to a post, a post carries at most 4 images. The image metadata is removed and a thumbnail is generated
+// @Tags posts
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param postId path int true "Post ID"
+// @Param image formData file true "Image file"
+// @Param altText formData string false "Description of the image for screen readers"
+// @Success 201 {object} models.Attachment
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 409 {object} responses.Problem "Conflict"
+// @Failure 413 {object} responses.Problem "Request Entity Too Large"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /posts/{postId}/attachments [post]
+func (controller *Controller) CreateAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	params := mux.Vars(r)
+	postID, err := strconv.ParseUint(params["postId"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	post, err := controller.Posts.SearchByID(postID, userID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if post.AuthorID != userID {
+		responses.Error(w, http.StatusForbidden, errors.New("It is not possible to attach images to a post that is not yours"))
+		return
+	}
+
+	repository := controller.Attachments
+	attachments, err := repository.SearchByPosts([]uint64{postID})
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Checked again when saving, this one only avoids processing an image that would be refused
+	if len(attachments) >= models.MaxAttachments {
+		responses.Error(w, http.StatusConflict, errTooManyAttachments)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			responses.Error(w, http.StatusRequestEntityTooLarge, media.ErrTooLarge)
+			return
+		}
+
+		responses.Error(w, http.StatusBadRequest, errors.New("The image must be sent in the image field of a multipart form"))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	attachment := models.Attachment{PostID: postID, AltText: r.FormValue("altText")}
+	if err = attachment.Prepare(); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	image, err := media.Process(data)
+	if err != nil {
+		if errors.Is(err, media.ErrTooLarge) {
+			responses.Error(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	name, err := security.RandomToken(16)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	prefix := fmt.Sprintf("posts/%d/%s", postID, name)
+	attachment.Key = prefix + image.Extension
+	attachment.ThumbnailKey = prefix + "_thumb" + image.ThumbnailExtension
+	attachment.ContentType = image.ContentType
+	attachment.Width, attachment.Height = image.Width, image.Height
+	attachment.Size = len(image.Data)
+
+	if err = controller.Storage.Put(r.Context(), attachment.Key, image.Data, image.ContentType); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err = controller.Storage.Put(r.Context(), attachment.ThumbnailKey, image.Thumbnail, image.ThumbnailContentType); err != nil {
+		controller.deleteFiles([]models.Attachment{attachment})
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if attachment.ID, err = repository.Create(attachment); err != nil {
+		controller.deleteFiles([]models.Attachment{attachment})
+		if errors.Is(err, repositories.ErrConflict) {
+			responses.Error(w, http.StatusConflict, errTooManyAttachments)
+			return
+		}
+
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	attachment, err = repository.SearchByID(attachment.ID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusCreated, controller.attachmentURLs(attachment))
+}
+
+// @Summary Remove an image from a post
+// @Description Delete an image attached to a post of the authenticated user
+// @Tags posts
+// @Security Bearer
+// @Param postId path int true "Post ID"
+// @Param attachmentId path int true "Attachment ID"
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /posts/{postId}/attachments/{attachmentId} [delete]
+func (controller *Controller) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	params := mux.Vars(r)
+	postID, err := strconv.ParseUint(params["postId"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	attachmentID, err := strconv.ParseUint(params["attachmentId"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	post, err := controller.Posts.SearchByID(postID, userID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if post.AuthorID != userID {
+		responses.Error(w, http.StatusForbidden, errors.New("It is not possible to remove images from a post that is not yours"))
+		return
+	}
+
+	repository := controller.Attachments
+	attachment, err := repository.SearchByID(attachmentID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if attachment.PostID != postID {
+		responses.Error(w, http.StatusNotFound, errors.New("The image does not belong to this post"))
+		return
+	}
+
+	if err = repository.Delete(attachmentID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	controller.deleteFiles([]models.Attachment{attachment})
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// withAttachments fills the attachments of the posts, every post gets a list even when it has no images
+func (controller *Controller) withAttachments(posts ...models.Post) ([]models.Post, error) {
+	postIDs := make([]uint64, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+
+	attachments, err := controller.Attachments.SearchByPosts(postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byPost := make(map[uint64][]models.Attachment, len(posts))
+	for _, attachment := range attachments {
+		byPost[attachment.PostID] = append(byPost[attachment.PostID], controller.attachmentURLs(attachment))
+	}
+
+	for i := range posts {
+		posts[i].Attachments = byPost[posts[i].ID]
+		if posts[i].Attachments == nil {
+			posts[i].Attachments = []models.Attachment{}
+		}
+	}
+
+	return posts, nil
+}
+
+// attachmentURLs sets the addresses the clients download the image and its thumbnail from
+func (controller *Controller) attachmentURLs(attachment models.Attachment) models.Attachment {
+	attachment.URL = controller.Storage.URL(attachment.Key)
+	attachment.ThumbnailURL = controller.Storage.URL(attachment.ThumbnailKey)
+	return attachment
+}
+
+// deleteFiles removes the stored images, the rows are already gone so failures are only logged
+func (controller *Controller) deleteFiles(attachments []models.Attachment) {
+	for _, attachment := range attachments {
+		for _, key := range []string{attachment.Key, attachment.ThumbnailKey} {
+			if err := controller.Storage.Delete(context.Background(), key); err != nil {
+				log.Printf("Error deleting stored file %s: %v", key, err)
+			}
+		}
+	}
+}
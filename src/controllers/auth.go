@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/models"
+	"api/src/repositories"
+	"api/src/responses"
+	"api/src/security"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// @Summary Refresh the access token
+// @Description Exchange a refresh token for a new access token, the refresh token is rotated and cannot be used again
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param refresh body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.AuthTokens
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 422 {object} responses.Problem "Unprocessable Entity"
+// @Failure 429 {object} responses.Problem "Too Many Requests"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /auth/refresh [post]
+func (controller *Controller) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	requestBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var request models.RefreshRequest
+	if err = json.Unmarshal(requestBody, &request); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if request.RefreshToken == "" {
+		responses.Error(w, http.StatusBadRequest, errors.New("The refresh token is mandatory and cannot be blank"))
+		return
+	}
+
+	repository := controller.Sessions
+	oldHash := security.HashToken(request.RefreshToken)
+	session, err := repository.SearchByRefreshToken(oldHash)
+	if errors.Is(err, repositories.ErrNotFound) {
+		responses.Error(w, http.StatusUnauthorized, errors.New("invalid refresh token"))
+		return
+	}
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		responses.Error(w, http.StatusUnauthorized, errors.New("invalid refresh token"))
+		return
+	}
+
+	refreshToken, err := security.RandomToken(32)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	rotated, err := repository.Rotate(session.ID, oldHash, security.HashToken(refreshToken), time.Now().Add(authentication.RefreshTokenDuration))
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !rotated {
+		responses.Error(w, http.StatusUnauthorized, errors.New("invalid refresh token"))
+		return
+	}
+
+	user, err := controller.Users.SearchByID(session.UserID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if user.SuspendedAt != nil {
+		responses.Error(w, http.StatusForbidden, errors.New("this account is suspended"))
+		return
+	}
+
+	tokens, err := newAuthTokens(user, session.ID, refreshToken)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, tokens)
+}
+
+// @Summary Log out
+// @Description Revoke the session of the access token, its refresh token stops working as well
+// @Tags authentication
+// @Security Bearer
+// @Success 204 {object} object
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /auth/logout [post]
+func (controller *Controller) Logout(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := authentication.ExtractSessionID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	repository := controller.Sessions
+	if err = repository.Revoke(sessionID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// startSession creates a new session for the user and returns its tokens
+func (controller *Controller) startSession(user models.User) (models.AuthTokens, error) {
+	sessionID, err := security.RandomToken(24)
+	if err != nil {
+		return models.AuthTokens{}, err
+	}
+
+	refreshToken, err := security.RandomToken(32)
+	if err != nil {
+		return models.AuthTokens{}, err
+	}
+
+	repository := controller.Sessions
+	if err = repository.Create(models.Session{
+		ID:               sessionID,
+		UserID:           user.ID,
+		RefreshTokenHash: security.HashToken(refreshToken),
+		ExpiresAt:        time.Now().Add(authentication.RefreshTokenDuration),
+	}); err != nil {
+		return models.AuthTokens{}, err
+	}
+
+	return newAuthTokens(user, sessionID, refreshToken)
+}
+
+func newAuthTokens(user models.User, sessionID, refreshToken string) (models.AuthTokens, error) {
+	accessToken, err := authentication.CreateToken(user.ID, sessionID, user.Role)
+	if err != nil {
+		return models.AuthTokens{}, err
+	}
+
+	return models.AuthTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(authentication.AccessTokenDuration.Seconds()),
+	}, nil
+}
@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/pagination"
+	"api/src/repositories"
+	"api/src/responses"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// errBlocked is returned when one of the users blocked the other
+var errBlocked = errors.New("It is not possible to interact with this user")
+
+// @Summary Block user by ID
+// @Description Block a user: the follows between both users are removed, they cannot follow or message each other and neither sees the profile or posts of the other
+// @Tags users
+// @Security Bearer
+// @Param userID path int true "User ID"
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /users/{userID}/block [post]
+func (controller *Controller) BlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, otherID, ok := controller.relationUsers(w, r, "It is not possible to block yourself")
+	if !ok {
+		return
+	}
+
+	repository := controller.Blocks
+	if err := repository.Block(userID, otherID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// @Summary Unblock user by ID
+// @Description Remove the block of a user, the follows removed by the block are not restored
+// @Tags users
+// @Security Bearer
+// @Param userID path int true "User ID"
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /users/{userID}/block [delete]
+func (controller *Controller) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	userID, otherID, ok := controller.relationUsers(w, r, "It is not possible to unblock yourself")
+	if !ok {
+		return
+	}
+
+	repository := controller.Blocks
+	if err := repository.Unblock(userID, otherID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// @Summary Mute user by ID
+// @Description Hide the posts of a user from the feed of the authenticated user, the user is not told and the follows are kept
+// @Tags users
+// @Security Bearer
+// @Param userID path int true "User ID"
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /users/{userID}/mute [post]
+func (controller *Controller) MuteUser(w http.ResponseWriter, r *http.Request) {
+	userID, otherID, ok := controller.relationUsers(w, r, "It is not possible to mute yourself")
+	if !ok {
+		return
+	}
+
+	repository := controller.Blocks
+	if err := repository.Mute(userID, otherID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// @Summary Unmute user by ID
+// @Description Show the posts of a muted user in the feed again
+// @Tags users
+// @Security Bearer
+// @Param userID path int true "User ID"
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /users/{userID}/mute [delete]
+func (controller *Controller) UnmuteUser(w http.ResponseWriter, r *http.Request) {
+	userID, otherID, ok := controller.relationUsers(w, r, "It is not possible to unmute yourself")
+	if !ok {
+		return
+	}
+
+	repository := controller.Blocks
+	if err := repository.Unmute(userID, otherID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// @Summary Get the blocked users
+// @Description Retrieve the users the authenticated user blocked
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.User]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /blocks [get]
+func (controller *Controller) GetBlocks(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.Blocks
+	users, err := repository.SearchBlocked(userID, page)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, pagination.NewPage(users, page, userCursorID))
+}
+
+// @Summary Get the muted users
+// @Description Retrieve the users the authenticated user muted
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.User]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /mutes [get]
+func (controller *Controller) GetMutes(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.Blocks
+	users, err := repository.SearchMuted(userID, page)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, pagination.NewPage(users, page, userCursorID))
+}
+
+// relationUsers reads the authenticated user and the existing user of the path, who must be someone else
+func (controller *Controller) relationUsers(w http.ResponseWriter, r *http.Request, selfMessage string) (uint64, uint64, bool) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return 0, 0, false
+	}
+
+	parameters := mux.Vars(r)
+	otherID, err := strconv.ParseUint(parameters["userID"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return 0, 0, false
+	}
+
+	if otherID == userID {
+		responses.Error(w, http.StatusForbidden, errors.New(selfMessage))
+		return 0, 0, false
+	}
+
+	if _, err = controller.Users.SearchByID(otherID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return 0, 0, false
+	}
+
+	return userID, otherID, true
+}
+
+// checkNotBlocked writes the error response and returns false when one of the users blocked the other,
+// the blocked users are not found as if they did not exist
+func (controller *Controller) checkNotBlocked(w http.ResponseWriter, viewerID, userID uint64) bool {
+	if viewerID == userID {
+		return true
+	}
+
+	blocked, err := controller.Blocks.IsBlocked(userID, viewerID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return false
+	}
+
+	if blocked {
+		responses.Error(w, http.StatusNotFound, repositories.ErrNotFound)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,302 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/repositories"
+	"api/src/responses"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// @Summary Comment on a post
+// @Description Create a comment on a post, send parentCommentId to reply to another comment of the same post
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param postId path int true "Post ID"
+// @Param comment body string true "Comment data" example({"content": "string", "parentCommentId": 1})
+// @Success 201 {object} models.Comment
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 422 {object} responses.Problem "Unprocessable Entity"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /posts/{postId}/comments [post]
+func (controller *Controller) CreateComment(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	params := mux.Vars(r)
+	postID, err := strconv.ParseUint(params["postId"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	bodyRequest, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var comment models.Comment
+	if err = json.Unmarshal(bodyRequest, &comment); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = comment.Prepare(); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	comment.PostID = postID
+	comment.AuthorID = userID
+
+	post, err := controller.Posts.SearchByID(postID, userID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !controller.checkCanSeePost(w, userID, post) {
+		return
+	}
+
+	repository := controller.Comments
+	if comment.ParentCommentID != nil {
+		parent, err := repository.SearchByID(*comment.ParentCommentID)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			responses.Error(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if err != nil || parent.PostID != postID {
+			responses.Error(w, http.StatusBadRequest, errors.New("The parent comment does not belong to this post"))
+			return
+		}
+	}
+
+	comment.ID, err = repository.Create(comment)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	controller.notify(post.AuthorID, userID, models.NotificationComment, &post.ID)
+	responses.JSON(w, http.StatusCreated, comment)
+}
+
+// @Summary Get the comments of a post
+// @Description Retrieve the comments of a post in the order they were written, replies carry parentCommentId
+// @Tags comments
+// @Produce json
+// @Security Bearer
+// @Param postId path int true "Post ID"
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.Comment]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /posts/{postId}/comments [get]
+func (controller *Controller) GetComments(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	params := mux.Vars(r)
+	postID, err := strconv.ParseUint(params["postId"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	post, err := controller.Posts.SearchByID(postID, userID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !controller.checkCanSeePost(w, userID, post) {
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.Comments
+	comments, err := repository.SearchByPost(postID, page)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, pagination.NewPage(comments, page, commentCursorID))
+}
+
+// @Summary Update a comment
+// @Description Update the content of a comment, only its author can edit it
+// @Tags comments
+// @Accept json
+// @Security Bearer
+// @Param postId path int true "Post ID"
+// @Param commentId path int true "Comment ID"
+// @Param comment body string true "Comment data" example({"content": "string"})
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 422 {object} responses.Problem "Unprocessable Entity"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /posts/{postId}/comments/{commentId} [put]
+func (controller *Controller) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	params := mux.Vars(r)
+	postID, err := strconv.ParseUint(params["postId"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	commentID, err := strconv.ParseUint(params["commentId"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.Comments
+	commentOnBank, err := repository.SearchByID(commentID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if commentOnBank.PostID != postID {
+		responses.Error(w, http.StatusNotFound, repositories.ErrNotFound)
+		return
+	}
+
+	if commentOnBank.AuthorID != userID {
+		responses.Error(w, http.StatusForbidden, errors.New("It is not possible to update a comment that is not yours"))
+		return
+	}
+
+	bodyRequest, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var comment models.Comment
+	if err = json.Unmarshal(bodyRequest, &comment); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = comment.Prepare(); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = repository.Update(commentID, comment); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// @Summary Delete a comment
+// @Description Delete a comment and its replies, allowed for the comment author and the post author
+// @Tags comments
+// @Security Bearer
+// @Param postId path int true "Post ID"
+// @Param commentId path int true "Comment ID"
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /posts/{postId}/comments/{commentId} [delete]
+func (controller *Controller) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	params := mux.Vars(r)
+	postID, err := strconv.ParseUint(params["postId"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	commentID, err := strconv.ParseUint(params["commentId"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.Comments
+	commentOnBank, err := repository.SearchByID(commentID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if commentOnBank.PostID != postID {
+		responses.Error(w, http.StatusNotFound, repositories.ErrNotFound)
+		return
+	}
+
+	if commentOnBank.AuthorID != userID {
+		post, err := controller.Posts.SearchByID(postID, userID)
+		if err != nil {
+			responses.Error(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if post.AuthorID != userID {
+			responses.Error(w, http.StatusForbidden, errors.New("It is not possible to delete a comment that is not yours or on a post that is not yours"))
+			return
+		}
+	}
+
+	if err = repository.Delete(commentID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+func commentCursorID(comment models.Comment) uint64 {
+	return comment.ID
+}
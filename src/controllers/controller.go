@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"api/src/events"
+	"api/src/mail"
+	"api/src/ratelimit"
+	"api/src/repositories"
+	"api/src/storage"
+	"database/sql"
+	"time"
+)
+
+// Controller holds the dependencies shared by the API handlers
+type Controller struct {
+	Users          repositories.UserStore
+	Posts          repositories.PostStore
+	Comments       repositories.CommentStore
+	Sessions       repositories.SessionStore
+	Verifications  repositories.EmailVerificationStore
+	PasswordResets repositories.PasswordResetStore
+	LoginAttempts  repositories.LoginAttemptStore
+	TwoFactor      repositories.TwoFactorStore
+	AccessTokens   repositories.AccessTokenStore
+	FollowRequests repositories.FollowRequestStore
+	Blocks         repositories.BlockStore
+	Notifications  repositories.NotificationStore
+	Messages       repositories.MessageStore
+	Attachments    repositories.AttachmentStore
+	Hashtags       repositories.HashtagStore
+	Storage        storage.Storage
+	Mailer         mail.Mailer
+	Events         events.Broker
+	RateLimits     ratelimit.Store
+	// Clock reads the time the failed logins are delayed against, nil means time.Now
+	Clock func() time.Time
+}
+
+// NewController creates a controller backed by MySQL that serves every request from the given connection pool,
+// uploaded files are kept in files and emails are sent through mailer
+func NewController(db *sql.DB, files storage.Storage, mailer mail.Mailer) *Controller {
+	return &Controller{
+		Users:          repositories.NewUsersRepository(db),
+		Posts:          repositories.NewPostsRepository(db),
+		Comments:       repositories.NewCommentsRepository(db),
+		Sessions:       repositories.NewSessionsRepository(db),
+		Verifications:  repositories.NewEmailVerificationsRepository(db),
+		PasswordResets: repositories.NewPasswordResetsRepository(db),
+		LoginAttempts:  repositories.NewLoginAttemptsRepository(db),
+		TwoFactor:      repositories.NewTwoFactorRepository(db),
+		AccessTokens:   repositories.NewAccessTokensRepository(db),
+		FollowRequests: repositories.NewFollowRequestsRepository(db),
+		Blocks:         repositories.NewBlocksRepository(db),
+		Notifications:  repositories.NewNotificationsRepository(db),
+		Messages:       repositories.NewMessagesRepository(db),
+		Attachments:    repositories.NewAttachmentsRepository(db),
+		Hashtags:       repositories.NewHashtagsRepository(db),
+		Storage:        files,
+		Mailer:         mailer,
+		Events:         events.NewHub(events.DefaultHistory),
+		RateLimits:     ratelimit.NewMemory(time.Now),
+		Clock:          time.Now,
+	}
+}
+
+// now returns the current time of the controller clock
+func (controller *Controller) now() time.Time {
+	if controller.Clock == nil {
+		return time.Now()
+	}
+	return controller.Clock()
+}
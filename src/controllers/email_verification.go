@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/config"
+	"api/src/mail"
+	"api/src/models"
+	"api/src/responses"
+	"api/src/security"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// emailVerificationDuration is how long the link sent to confirm an email address works
+const emailVerificationDuration = 24 * time.Hour
+
+// errInvalidVerification does not tell a forged token from a used or expired one
+var errInvalidVerification = errors.New("The verification link is invalid or has expired, request a new one")
+
+// @Summary Confirm the email address
+// @Description Confirm the email address with the token sent to it when the account was created or the address changed. A token works once and for 24 hours
+// @Tags authentication
+// @Accept json
+// @Param verification body models.VerifyEmailRequest true "Verification token"
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 422 {object} responses.Problem "Unprocessable Entity"
+// @Failure 429 {object} responses.Problem "Too Many Requests"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /auth/verify-email [post]
+func (controller *Controller) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	requestBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var request models.VerifyEmailRequest
+	if err = json.Unmarshal(requestBody, &request); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if request.Token == "" {
+		responses.Error(w, http.StatusBadRequest, errors.New("The token is mandatory and cannot be blank"))
+		return
+	}
+
+	// Forged tokens are turned away without a query
+	token, err := security.Unsign(request.Token, config.SecretKey)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, errInvalidVerification)
+		return
+	}
+
+	repository := controller.Verifications
+	confirmed, err := repository.Confirm(security.HashToken(token))
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !confirmed {
+		responses.Error(w, http.StatusBadRequest, errInvalidVerification)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// @Summary Send the email confirmation again
+// @Description Send a new verification link to the email address of the authenticated user
+// @Tags authentication
+// @Security Bearer
+// @Success 204 {object} object
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 409 {object} responses.Problem "Conflict"
+// @Failure 429 {object} responses.Problem "Too Many Requests"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /auth/verify-email/resend [post]
+func (controller *Controller) ResendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	user, err := controller.Users.SearchByID(userID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if user.EmailVerifiedAt != nil {
+		responses.Error(w, http.StatusConflict, errors.New("The email address is already verified"))
+		return
+	}
+
+	if err = controller.sendEmailVerification(r.Context(), user); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// sendEmailVerification emails the user a signed link that confirms their current address
+func (controller *Controller) sendEmailVerification(ctx context.Context, user models.User) error {
+	token, err := security.RandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	repository := controller.Verifications
+	if err = repository.Create(models.EmailVerification{
+		TokenHash: security.HashToken(token),
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(emailVerificationDuration),
+	}); err != nil {
+		return err
+	}
+
+	signed := security.Sign(token, config.SecretKey)
+	return controller.Mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Confirm your email address by opening the link below, it works for the next 24 hours:\n\n"+
+			"%s/verify-email?token=%s\n\n"+
+			"If you did not create an account, ignore this email.\n",
+			user.Name, config.AppURL, url.QueryEscape(signed)),
+	})
+}
+
+// trySendEmailVerification only logs the failures, it is used once the account change
+// already succeeded and the user can still ask for a new link
+func (controller *Controller) trySendEmailVerification(ctx context.Context, user models.User) {
+	if err := controller.sendEmailVerification(ctx, user); err != nil {
+		log.Printf("Error sending the email verification of user %d: %v", user.ID, err)
+	}
+}
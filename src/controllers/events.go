@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/events"
+	"api/src/responses"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// streamHeartbeat is how often an idle stream sends a comment to keep proxies
+// from closing it, the session is checked again at the same pace
+const streamHeartbeat = 30 * time.Second
+
+// @Summary Stream real-time events
+// @Description Push new posts of followed users, likes on your posts, new followers and direct messages as Server-Sent Events.
+// @Description Reconnect with the Last-Event-ID header, or the lastEventId query parameter, to receive the events missed in between.
+// @Tags events
+// @Produce text/event-stream
+// @Security Bearer
+// @Param Last-Event-ID header int false "ID of the last event received"
+// @Param lastEventId query int false "ID of the last event received, for clients that cannot set headers"
+// @Success 200 {string} string "Stream of post.created, post.liked, user.followed and message.received events"
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /events [get]
+func (controller *Controller) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	principal, err := authentication.ExtractPrincipal(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+
+	var after uint64
+	if lastEventID != "" {
+		if after, err = strconv.ParseUint(lastEventID, 10, 64); err != nil {
+			responses.Error(w, http.StatusBadRequest, errors.New("The last event ID must be a number"))
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		responses.Error(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	subscription := controller.Events.Subscribe(principal.UserID, after)
+	defer subscription.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	for _, event := range subscription.Replay {
+		if err = writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case event, open := <-subscription.Events:
+			if !open {
+				return
+			}
+
+			if err = writeEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-heartbeat.C:
+			active, err := controller.Sessions.IsActive(principal.SessionID)
+			if err != nil || !active {
+				return
+			}
+
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes the event in the Server-Sent Events format
+func writeEvent(w http.ResponseWriter, event events.Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+// publishToFollowers sends the event to every follower of the user
+func (controller *Controller) publishToFollowers(userID uint64, kind string, data any) {
+	followerIDs, err := controller.Users.SearchFollowerIDs(userID)
+	if err != nil {
+		log.Printf("Error fanning out %s event of user %d: %v", kind, userID, err)
+		return
+	}
+
+	controller.Events.Publish(events.Event{Type: kind, Recipients: followerIDs, Data: data})
+}
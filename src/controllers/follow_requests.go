@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/repositories"
+	"api/src/responses"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// errPrivateAccount is returned to the users who do not follow a private account
+var errPrivateAccount = errors.New("This account is private, only its followers can see it")
+
+// @Summary Get the pending follow requests
+// @Description Retrieve the requests to follow the authenticated user, oldest first. Only private accounts receive requests
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.FollowRequest]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /follow-requests [get]
+func (controller *Controller) GetFollowRequests(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.FollowRequests
+	requests, err := repository.Search(userID, page)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, pagination.NewPage(requests, page, followRequestCursorID))
+}
+
+// @Summary Approve a follow request
+// @Description Let the user who made the request follow the authenticated user
+// @Tags users
+// @Security Bearer
+// @Param requestId path int true "Follow request ID"
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /follow-requests/{requestId}/approve [post]
+func (controller *Controller) ApproveFollowRequest(w http.ResponseWriter, r *http.Request) {
+	request, ok := controller.ownFollowRequest(w, r)
+	if !ok {
+		return
+	}
+
+	repository := controller.FollowRequests
+	if err := repository.Approve(request.ID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	controller.notify(request.Follower.ID, request.UserID, models.NotificationFollowAccepted, nil)
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// @Summary Reject a follow request
+// @Description Remove the request without letting its user follow the authenticated user
+// @Tags users
+// @Security Bearer
+// @Param requestId path int true "Follow request ID"
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /follow-requests/{requestId}/reject [post]
+func (controller *Controller) RejectFollowRequest(w http.ResponseWriter, r *http.Request) {
+	request, ok := controller.ownFollowRequest(w, r)
+	if !ok {
+		return
+	}
+
+	repository := controller.FollowRequests
+	if err := repository.Delete(request.ID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// @Summary Make the account private or public
+// @Description Private accounts approve who follows them and only their followers see their posts and follower lists. The pending requests are approved when the account becomes public
+// @Tags users
+// @Accept json
+// @Security Bearer
+// @Param userID path int true "User ID"
+// @Param settings body models.PrivacySettings true "Privacy settings" example({"private": true})
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /users/{userID}/privacy [put]
+func (controller *Controller) UpdatePrivacy(w http.ResponseWriter, r *http.Request) {
+	userIDToken, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	parameters := mux.Vars(r)
+	userID, err := strconv.ParseUint(parameters["userID"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if userIDToken != userID {
+		responses.Error(w, http.StatusForbidden, errors.New("It is not possible to change the settings of a user other than yours"))
+		return
+	}
+
+	requestBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var settings models.PrivacySettings
+	if err = json.Unmarshal(requestBody, &settings); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.Users
+	if err = repository.UpdatePrivacy(userID, settings.Private); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// requestToFollow asks the private account to accept the follower
+func (controller *Controller) requestToFollow(w http.ResponseWriter, userID, followerID uint64) {
+	repository := controller.FollowRequests
+	if err := repository.Create(userID, followerID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	controller.notify(userID, followerID, models.NotificationFollowRequest, nil)
+	responses.JSON(w, http.StatusAccepted, nil)
+}
+
+// ownFollowRequest reads the request of the path, the requests made to other users are not found
+func (controller *Controller) ownFollowRequest(w http.ResponseWriter, r *http.Request) (models.FollowRequest, bool) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return models.FollowRequest{}, false
+	}
+
+	parameters := mux.Vars(r)
+	requestID, err := strconv.ParseUint(parameters["requestId"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return models.FollowRequest{}, false
+	}
+
+	request, err := controller.FollowRequests.SearchByID(requestID)
+	if err == nil && request.UserID != userID {
+		err = repositories.ErrNotFound
+	}
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return models.FollowRequest{}, false
+	}
+
+	return request, true
+}
+
+// canSee reports whether the viewer can see the posts and follower lists of
+// the user, private accounts only show them to their followers and blocks
+// hide them both ways
+func (controller *Controller) canSee(viewerID uint64, user models.User) (bool, error) {
+	if user.ID == viewerID {
+		return true, nil
+	}
+
+	blocked, err := controller.Blocks.IsBlocked(user.ID, viewerID)
+	if err != nil || blocked {
+		return false, err
+	}
+
+	if !user.Private {
+		return true, nil
+	}
+
+	return controller.Users.IsFollowing(user.ID, viewerID)
+}
+
+// checkCanSee writes the error response and returns false when the viewer cannot see the user,
+// the users blocked either way are not found
+func (controller *Controller) checkCanSee(w http.ResponseWriter, viewerID, userID uint64) bool {
+	if !controller.checkNotBlocked(w, viewerID, userID) {
+		return false
+	}
+
+	user, err := controller.Users.SearchByID(userID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return false
+	}
+
+	visible, err := controller.canSee(viewerID, user)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return false
+	}
+
+	if !visible {
+		responses.Error(w, http.StatusForbidden, errPrivateAccount)
+		return false
+	}
+
+	return true
+}
+
+func followRequestCursorID(request models.FollowRequest) uint64 {
+	return request.ID
+}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/responses"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// defaultTrendingWindow is how far back the trending tags look unless the request asks otherwise
+	defaultTrendingWindow = 24 * time.Hour
+	// maxTrendingWindow bounds the window a request can ask for
+	maxTrendingWindow = 7 * 24 * time.Hour
+	// defaultTrendingLimit and maxTrendingLimit bound how many tags are listed
+	defaultTrendingLimit = 10
+	maxTrendingLimit     = 50
+)
+
+// @Summary Get the posts with a hashtag
+// @Description Retrieve the posts whose content has the hashtag, newest first. The tag is case insensitive and can be sent with or without the #
+// @Tags hashtags
+// @Produce json
+// @Security Bearer
+// @Param tag path string true "Hashtag"
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.Post]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /hashtags/{tag}/posts [get]
+func (controller *Controller) GetHashtagPosts(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	params := mux.Vars(r)
+	tag, ok := models.NormalizeHashtag(params["tag"])
+	if !ok {
+		responses.Error(w, http.StatusBadRequest, errors.New("A hashtag has letters, digits and underscores and at least one letter"))
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.Posts
+	posts, err := repository.SearchByHashtag(tag, userID, page)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if posts, err = controller.withDetails(posts...); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, pagination.NewPage(posts, page, postCursorID))
+}
+
+// @Summary Get the trending hashtags
+// @Description Retrieve the hashtags used the most in the last hours, recent uses weigh more: a use counts half as much for every quarter of the window that passed since it happened
+// @Tags hashtags
+// @Produce json
+// @Security Bearer
+// @Param hours query int false "Size of the window in hours, 24 by default and at most 168"
+// @Param limit query int false "Number of tags, 10 by default and at most 50"
+// @Success 200 {array} models.TrendingHashtag
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /hashtags/trending [get]
+func (controller *Controller) GetTrendingHashtags(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	window := defaultTrendingWindow
+	if hours := query.Get("hours"); hours != "" {
+		value, err := strconv.Atoi(hours)
+		if err != nil || value < 1 || time.Duration(value)*time.Hour > maxTrendingWindow {
+			responses.Error(w, http.StatusBadRequest, errors.New("The window must be between 1 and 168 hours"))
+			return
+		}
+
+		window = time.Duration(value) * time.Hour
+	}
+
+	limit := defaultTrendingLimit
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			responses.Error(w, http.StatusBadRequest, errors.New("The limit must be a positive number"))
+			return
+		}
+
+		limit = min(limit, maxTrendingLimit)
+	}
+
+	repository := controller.Hashtags
+	hashtags, err := repository.Trending(window, window/4, limit)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if hashtags == nil {
+		hashtags = []models.TrendingHashtag{}
+	}
+
+	responses.JSON(w, http.StatusOK, hashtags)
+}
@@ -1,30 +1,87 @@
 package controllers
 
 import (
-	"api/src/authentication"
-	"api/src/database"
+	"api/src/middlewares"
 	"api/src/models"
+	"api/src/ratelimit"
 	"api/src/repositories"
 	"api/src/responses"
 	"api/src/security"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
+// loginWindow is how long the failed logins count towards the delays and lockouts
+const loginWindow = 15 * time.Minute
+
+// loginThrottle makes the clients wait longer after each failed login once they
+// reach delayAfter failures, and locks them out for the rest of the window at lockAfter
+type loginThrottle struct {
+	delayAfter int
+	lockAfter  int
+}
+
+var (
+	// accountThrottle protects a single account from guessing
+	accountThrottle = loginThrottle{delayAfter: 3, lockAfter: 10}
+	// addressThrottle protects every account from a single address trying many of them
+	addressThrottle = loginThrottle{delayAfter: 20, lockAfter: 100}
+
+	// maxLoginDelay bounds the progressive delay before the lockout
+	maxLoginDelay = time.Minute
+
+	// errInvalidCredentials is the only answer to a failed login, it does not tell whether the email has an account
+	errInvalidCredentials = errors.New("The email or password is incorrect")
+
+	// unknownUserHash is checked against the password of the emails without an account, so they take as long as the others
+	unknownUserHash = sync.OnceValue(func() string {
+		hash, err := security.Hash("unknown-user")
+		if err != nil {
+			log.Printf("Error hashing the placeholder password: %v", err)
+		}
+		return string(hash)
+	})
+)
+
+// wait returns how long the client must wait before its next attempt
+func (throttle loginThrottle) wait(failures models.LoginFailures, now time.Time) time.Duration {
+	var delay time.Duration
+	switch {
+	case failures.Count >= throttle.lockAfter:
+		delay = loginWindow
+	case failures.Count >= throttle.delayAfter:
+		delay = min(time.Second<<(failures.Count-throttle.delayAfter), maxLoginDelay)
+	default:
+		return 0
+	}
+
+	return max(0, failures.Last.Add(delay).Sub(now))
+}
+
 // @Summary Authenticate user
-// @Description Authenticate the user by checking the provided credentials
+// @Description Authenticate the user by checking the provided credentials and start a new session. When two-factor authentication is enabled the answer is a challenge to complete at /login/2fa instead. Repeated failures for an email or from an address are slowed down and then locked out for 15 minutes
 // @Tags authentication
 // @Accept json
-// @Produce plain
+// @Produce json
 // @Param credentials body models.UserRequest true "User credentials"
-// @Success 200 {string} string "Authentication token"
-// @Failure 400 {object} object "Bad Request"
-// @Failure 401 {object} object "Unauthorized"
-// @Failure 500 {object} object "Internal Server Error"
+// @Success 200 {object} models.AuthTokens
+// @Success 202 {object} models.TwoFactorChallenge
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 429 {object} responses.Problem "Too Many Requests"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /login [post]
-func Login(w http.ResponseWriter, r *http.Request) {
+func (controller *Controller) Login(w http.ResponseWriter, r *http.Request) {
 	requestBody, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		responses.Error(w, http.StatusUnprocessableEntity, err)
@@ -38,33 +95,107 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := database.Connect()
+	email := strings.ToLower(strings.TrimSpace(user.Email))
+	attempt := models.LoginAttempt{
+		Email:     truncate(email, 50),
+		IP:        middlewares.ClientIP(r),
+		UserAgent: truncate(r.UserAgent(), 255),
+	}
+
+	repository := controller.Users
+	userSavedDatabase, err := repository.SearchByEmail(email)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
-	defer db.Close()
 
-	repository := repositories.NewUsersRepository(db)
-	userSavedDatabase, err := repository.SearchByEmail(user.Email)
+	savedPassword := userSavedDatabase.Password
+	if userSavedDatabase.ID == 0 {
+		savedPassword = unknownUserHash()
+	} else {
+		attempt.UserID = &userSavedDatabase.ID
+	}
+
+	now := controller.now()
+	account, address, err := controller.LoginAttempts.SearchFailures(attempt.Email, attempt.IP, now.Add(-loginWindow))
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	if err = security.VerifyPassword(
-		userSavedDatabase.Password, user.Password); err != nil {
-		responses.Error(w, http.StatusUnauthorized, err)
+	if wait := max(accountThrottle.wait(account, now), addressThrottle.wait(address, now)); wait > 0 {
+		attempt.Reason = models.LoginThrottled
+		controller.recordLoginAttempt(attempt)
+
+		seconds := ratelimit.Seconds(wait)
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		responses.Error(w, http.StatusTooManyRequests, fmt.Errorf("Too many failed logins, try again in %d seconds", seconds))
 		return
 	}
 
-	token, err := authentication.CreateToken(userSavedDatabase.ID)
+	if err = security.VerifyPassword(savedPassword, user.Password); err != nil || userSavedDatabase.ID == 0 {
+		attempt.Reason = models.LoginInvalidCredentials
+		controller.recordLoginAttempt(attempt)
+		responses.Error(w, http.StatusUnauthorized, errInvalidCredentials)
+		return
+	}
+
+	if userSavedDatabase.SuspendedAt != nil {
+		attempt.Reason = models.LoginSuspended
+		controller.recordLoginAttempt(attempt)
+		responses.Error(w, http.StatusForbidden, errors.New("this account is suspended"))
+		return
+	}
+
+	twoFactor, err := controller.TwoFactor.Search(userSavedDatabase.ID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if twoFactor.Enabled() {
+		challenge, err := controller.startLoginChallenge(userSavedDatabase.ID)
+		if err != nil {
+			responses.Error(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		attempt.Reason = models.LoginTwoFactorRequired
+		controller.recordLoginAttempt(attempt)
+
+		responses.JSON(w, http.StatusAccepted, challenge)
+		return
+	}
+
+	tokens, err := controller.startSession(userSavedDatabase)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	formattedToken := fmt.Sprintf("Bearer %s", token)
-	w.Write([]byte(formattedToken))
+	attempt.Succeeded, attempt.Reason = true, models.LoginSucceeded
+	controller.recordLoginAttempt(attempt)
+
+	responses.JSON(w, http.StatusOK, tokens)
+}
+
+// recordLoginAttempt adds the attempt to the audit trail, a failure to do so does not fail the login
+func (controller *Controller) recordLoginAttempt(attempt models.LoginAttempt) {
+	attempt.CreatedAt = controller.now()
+	if err := controller.LoginAttempts.Create(attempt); err != nil {
+		log.Printf("Error recording the login attempt of %s from %s: %v", attempt.Email, attempt.IP, err)
+	}
+}
+
+// truncate cuts the text to at most size bytes without splitting a character
+func truncate(text string, size int) string {
+	if len(text) <= size {
+		return text
+	}
+
+	for size > 0 && !utf8.RuneStart(text[size]) {
+		size--
+	}
 
+	return text[:size]
 }
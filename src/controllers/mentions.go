@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/responses"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// @Summary Get the posts mentioning a user
+// @Description Retrieve the posts that mention the user as @nick, newest first. A user who blocked the authenticated user or was blocked by them is not found
+// @Tags posts
+// @Produce json
+// @Security Bearer
+// @Param userID path int true "User ID"
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.Post]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /users/{userID}/mentions [get]
+func (controller *Controller) GetUserMentions(w http.ResponseWriter, r *http.Request) {
+	viewerID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	params := mux.Vars(r)
+	userID, err := strconv.ParseUint(params["userID"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !controller.checkNotBlocked(w, viewerID, userID) {
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.Posts
+	posts, err := repository.SearchByMention(userID, viewerID, page)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if posts, err = controller.withDetails(posts...); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, pagination.NewPage(posts, page, postCursorID))
+}
+
+// resolveMentions keeps the @nick tokens of the content that name a user the
+// author did not block nor was blocked by, the others are left as plain text
+func (controller *Controller) resolveMentions(post *models.Post) error {
+	post.Mentions = []models.Mention{}
+
+	tokens := models.ParseMentions(post.Content)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	nicks := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		nicks = append(nicks, token.Nick)
+	}
+
+	users, err := controller.Users.SearchByNicks(nicks)
+	if err != nil {
+		return err
+	}
+
+	blockedIDs, err := controller.Blocks.SearchBlockedIDs(post.AuthorID)
+	if err != nil {
+		return err
+	}
+
+	blocked := make(map[uint64]bool, len(blockedIDs))
+	for _, id := range blockedIDs {
+		blocked[id] = true
+	}
+
+	byNick := make(map[string]models.User, len(users))
+	for _, user := range users {
+		if !blocked[user.ID] {
+			byNick[strings.ToLower(user.Nick)] = user
+		}
+	}
+
+	for _, token := range tokens {
+		if user, found := byNick[strings.ToLower(token.Nick)]; found {
+			token.UserID, token.Nick = user.ID, user.Nick
+			post.Mentions = append(post.Mentions, token)
+		}
+	}
+
+	return nil
+}
+
+// notifyMentions notifies the users mentioned by the post that were not in the previous mentions
+func (controller *Controller) notifyMentions(post models.Post, previous []models.Mention) {
+	notified := map[uint64]bool{}
+	for _, mention := range previous {
+		notified[mention.UserID] = true
+	}
+
+	for _, mention := range post.Mentions {
+		if !notified[mention.UserID] {
+			notified[mention.UserID] = true
+			controller.notify(mention.UserID, post.AuthorID, models.NotificationMention, &post.ID)
+		}
+	}
+}
+
+// withMentions fills the mentions of the posts, every post gets a list even when it mentions nobody
+func (controller *Controller) withMentions(posts ...models.Post) ([]models.Post, error) {
+	postIDs := make([]uint64, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+
+	mentions, err := controller.Posts.SearchMentions(postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byPost := make(map[uint64][]models.Mention, len(posts))
+	for _, mention := range mentions {
+		byPost[mention.PostID] = append(byPost[mention.PostID], mention)
+	}
+
+	for i := range posts {
+		posts[i].Mentions = byPost[posts[i].ID]
+		if posts[i].Mentions == nil {
+			posts[i].Mentions = []models.Mention{}
+		}
+	}
+
+	return posts, nil
+}
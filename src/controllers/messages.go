@@ -0,0 +1,416 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/events"
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/repositories"
+	"api/src/responses"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// @Summary Send a direct message
+// @Description Send a message to a user, a first message can only be sent to mutual followers or to users who accept messages from anyone
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param userID path int true "Recipient ID"
+// @Param message body string true "Message" example({"content": "string"})
+// @Success 201 {object} models.Message
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 422 {object} responses.Problem "Unprocessable Entity"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /users/{userID}/messages [post]
+func (controller *Controller) SendMessage(w http.ResponseWriter, r *http.Request) {
+	senderID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	parameters := mux.Vars(r)
+	recipientID, err := strconv.ParseUint(parameters["userID"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if recipientID == senderID {
+		responses.Error(w, http.StatusBadRequest, errors.New("It is not possible to send a message to yourself"))
+		return
+	}
+
+	requestBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var message models.Message
+	if err = json.Unmarshal(requestBody, &message); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = message.Prepare(); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	recipient, err := controller.Users.SearchByID(recipientID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	blocked, err := controller.Blocks.IsBlocked(recipientID, senderID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if blocked {
+		responses.Error(w, http.StatusForbidden, errBlocked)
+		return
+	}
+
+	message.SenderID = senderID
+
+	repository := controller.Messages
+	message.ConversationID, err = repository.SearchConversationBetween(senderID, recipientID)
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		var allowed bool
+		if allowed, err = controller.canStartConversation(senderID, recipient); err != nil {
+			responses.Error(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !allowed {
+			responses.Error(w, http.StatusForbidden, errors.New("Only mutual followers can start a conversation with this user"))
+			return
+		}
+
+		// The conversation and its first message are saved together so a failure leaves neither behind
+		message.ConversationID, message.ID, err = repository.StartConversation(message, recipientID)
+	case err == nil:
+		message.ID, err = repository.Create(message)
+	}
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// The recipient still receives muted messages, only the real-time push is skipped
+	conversation, err := repository.SearchConversation(message.ConversationID, recipientID)
+	if err != nil {
+		log.Printf("Error loading conversation %d for user %d: %v", message.ConversationID, recipientID, err)
+	} else if !conversation.Muted {
+		controller.Events.Publish(events.Event{
+			Type:       events.MessageReceived,
+			Recipients: []uint64{recipientID},
+			Data:       message,
+		})
+	}
+
+	responses.JSON(w, http.StatusCreated, message)
+}
+
+// @Summary Get the conversations of the authenticated user
+// @Description Retrieve the conversations with the most recent message first, with the unread count of each one
+// @Tags messages
+// @Produce json
+// @Security Bearer
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.Conversation]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /conversations [get]
+func (controller *Controller) GetConversations(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.Messages
+	conversations, err := repository.SearchConversations(userID, page)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, pagination.NewPage(conversations, page, conversationCursorID))
+}
+
+// @Summary Get the messages of a conversation
+// @Description Retrieve the messages of a conversation of the authenticated user, newest first
+// @Tags messages
+// @Produce json
+// @Security Bearer
+// @Param conversationId path int true "Conversation ID"
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.Message]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /conversations/{conversationId}/messages [get]
+func (controller *Controller) GetConversationMessages(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	parameters := mux.Vars(r)
+	conversationID, err := strconv.ParseUint(parameters["conversationId"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.Messages
+	if _, err = repository.SearchConversation(conversationID, userID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	messages, err := repository.Search(conversationID, page)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, pagination.NewPage(messages, page, messageCursorID))
+}
+
+// @Summary Mark a conversation as read
+// @Description Move the read marker of the authenticated user up to a message, the latest one when no ID is sent
+// @Tags messages
+// @Accept json
+// @Security Bearer
+// @Param conversationId path int true "Conversation ID"
+// @Param marker body models.ReadMarkerRequest false "Last message read" example({"messageId": 1})
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /conversations/{conversationId}/read [post]
+func (controller *Controller) MarkConversationRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	parameters := mux.Vars(r)
+	conversationID, err := strconv.ParseUint(parameters["conversationId"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	requestBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var marker models.ReadMarkerRequest
+	if len(requestBody) > 0 {
+		if err = json.Unmarshal(requestBody, &marker); err != nil {
+			responses.Error(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	repository := controller.Messages
+	conversation, err := repository.SearchConversation(conversationID, userID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if conversation.LastMessage == nil {
+		responses.JSON(w, http.StatusNoContent, nil)
+		return
+	}
+
+	if marker.MessageID == 0 || marker.MessageID > conversation.LastMessage.ID {
+		marker.MessageID = conversation.LastMessage.ID
+	}
+
+	if err = repository.MarkRead(conversationID, userID, marker.MessageID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// @Summary Mute a conversation
+// @Description Stop the real-time delivery of new messages of a conversation, they are still counted as unread
+// @Tags messages
+// @Security Bearer
+// @Param conversationId path int true "Conversation ID"
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /conversations/{conversationId}/mute [post]
+func (controller *Controller) MuteConversation(w http.ResponseWriter, r *http.Request) {
+	controller.setConversationMuted(w, r, true)
+}
+
+// @Summary Unmute a conversation
+// @Description Resume the real-time delivery of new messages of a conversation
+// @Tags messages
+// @Security Bearer
+// @Param conversationId path int true "Conversation ID"
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /conversations/{conversationId}/unmute [post]
+func (controller *Controller) UnmuteConversation(w http.ResponseWriter, r *http.Request) {
+	controller.setConversationMuted(w, r, false)
+}
+
+// @Summary Change who can message the user
+// @Description Choose whether users who are not mutual followers can start a conversation
+// @Tags messages
+// @Accept json
+// @Security Bearer
+// @Param userID path int true "User ID"
+// @Param settings body models.MessageSettings true "Message settings" example({"messagesFromAnyone": true})
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /users/{userID}/message-settings [put]
+func (controller *Controller) UpdateMessageSettings(w http.ResponseWriter, r *http.Request) {
+	userIDToken, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	parameters := mux.Vars(r)
+	userID, err := strconv.ParseUint(parameters["userID"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if userIDToken != userID {
+		responses.Error(w, http.StatusForbidden, errors.New("It is not possible to change the settings of a user other than yours"))
+		return
+	}
+
+	requestBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var settings models.MessageSettings
+	if err = json.Unmarshal(requestBody, &settings); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.Users
+	if err = repository.UpdateMessageSettings(userID, settings.MessagesFromAnyone); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+func (controller *Controller) setConversationMuted(w http.ResponseWriter, r *http.Request, muted bool) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	parameters := mux.Vars(r)
+	conversationID, err := strconv.ParseUint(parameters["conversationId"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.Messages
+	if _, err = repository.SearchConversation(conversationID, userID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err = repository.SetMuted(conversationID, userID, muted); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// canStartConversation reports whether the sender can send the first message to the recipient
+func (controller *Controller) canStartConversation(senderID uint64, recipient models.User) (bool, error) {
+	if recipient.MessagesFromAnyone {
+		return true, nil
+	}
+
+	repository := controller.Users
+	following, err := repository.IsFollowing(recipient.ID, senderID)
+	if err != nil || !following {
+		return false, err
+	}
+
+	return repository.IsFollowing(senderID, recipient.ID)
+}
+
+func conversationCursorID(conversation models.Conversation) uint64 {
+	if conversation.LastMessage == nil {
+		return 0
+	}
+
+	return conversation.LastMessage.ID
+}
+
+func messageCursorID(message models.Message) uint64 {
+	return message.ID
+}
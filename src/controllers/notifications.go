@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/responses"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// @Summary Get the notifications of the authenticated user
+// @Description Retrieve the notifications newest first, events of the same kind on the same post are grouped
+// @Tags notifications
+// @Produce json
+// @Security Bearer
+// @Param unread query bool false "Only list unread notifications"
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.Notification]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /notifications [get]
+func (controller *Controller) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	unreadOnly := false
+	if unread := r.URL.Query().Get("unread"); unread != "" {
+		if unreadOnly, err = strconv.ParseBool(unread); err != nil {
+			responses.Error(w, http.StatusBadRequest, errors.New("The unread filter must be true or false"))
+			return
+		}
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.Notifications
+	notifications, err := repository.Search(userID, unreadOnly, page)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, pagination.NewPage(notifications, page, notificationCursorID))
+}
+
+// @Summary Mark notifications as read
+// @Description Mark the given notifications as read, every notification is marked when no ID is sent
+// @Tags notifications
+// @Accept json
+// @Security Bearer
+// @Param notifications body models.NotificationsReadRequest true "Notifications to mark" example({"ids": [1, 2]})
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /notifications/read [post]
+func (controller *Controller) MarkNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	requestBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var request models.NotificationsReadRequest
+	if len(requestBody) > 0 {
+		if err = json.Unmarshal(requestBody, &request); err != nil {
+			responses.Error(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	repository := controller.Notifications
+	if err = repository.MarkRead(userID, request.IDs); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// @Summary Count the unread notifications
+// @Description Retrieve how many unread notifications the authenticated user has
+// @Tags notifications
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.UnreadCount
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /notifications/unread-count [get]
+func (controller *Controller) GetUnreadNotificationsCount(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	repository := controller.Notifications
+	unread, err := repository.CountUnread(userID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, models.UnreadCount{Unread: unread})
+}
+
+// notify records an event for the user, users are not notified about their
+// own actions and a failure is only logged because the action already happened
+func (controller *Controller) notify(userID, actorID uint64, kind string, postID *uint64) {
+	if userID == actorID {
+		return
+	}
+
+	if err := controller.Notifications.Create(models.Notification{
+		UserID:  userID,
+		ActorID: actorID,
+		Type:    kind,
+		PostID:  postID,
+	}); err != nil {
+		log.Printf("Error creating %s notification for user %d: %v", kind, userID, err)
+	}
+}
+
+func notificationCursorID(notification models.Notification) uint64 {
+	return notification.ID
+}
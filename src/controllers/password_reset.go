@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"api/src/config"
+	"api/src/mail"
+	"api/src/models"
+	"api/src/responses"
+	"api/src/security"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// passwordResetDuration is how long the link sent to reset a password works
+	passwordResetDuration = time.Hour
+	// mailTimeout bounds the delivery of the emails sent after the response
+	mailTimeout = 30 * time.Second
+)
+
+// errInvalidPasswordReset does not tell a forged token from a used or expired one
+var errInvalidPasswordReset = errors.New("The reset link is invalid or has expired, request a new one")
+
+// @Summary Ask for a password reset link
+// @Description Send a link to choose a new password to the email address when it belongs to an account and was confirmed. The answer is the same whether it does or not
+// @Tags authentication
+// @Accept json
+// @Param request body models.ForgotPasswordRequest true "Email address of the account"
+// @Success 202 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 422 {object} responses.Problem "Unprocessable Entity"
+// @Failure 429 {object} responses.Problem "Too Many Requests"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /auth/forgot-password [post]
+func (controller *Controller) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	requestBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var request models.ForgotPasswordRequest
+	if err = json.Unmarshal(requestBody, &request); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(request.Email))
+	if email == "" {
+		responses.Error(w, http.StatusBadRequest, errors.New("The email is mandatory and cannot be blank"))
+		return
+	}
+
+	user, err := controller.Users.SearchByEmail(email)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// The email goes out after the response so its delay does not tell which addresses have an account,
+	// the addresses that were never confirmed may not belong to the user and get nothing
+	if user.ID != 0 && user.EmailVerifiedAt != nil {
+		go controller.sendPasswordReset(user)
+	}
+
+	responses.JSON(w, http.StatusAccepted, nil)
+}
+
+// @Summary Reset the password
+// @Description Choose a new password with the token of a reset link. A token works once and for an hour, every session of the user is ended
+// @Tags authentication
+// @Accept json
+// @Param request body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 422 {object} responses.Problem "Unprocessable Entity"
+// @Failure 429 {object} responses.Problem "Too Many Requests"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /auth/reset-password [post]
+func (controller *Controller) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	requestBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var request models.ResetPasswordRequest
+	if err = json.Unmarshal(requestBody, &request); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	token, err := security.Unsign(request.Token, config.SecretKey)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, errInvalidPasswordReset)
+		return
+	}
+
+	hashedPassword, err := security.Hash(request.Password)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.PasswordResets
+	userID, consumed, err := repository.Consume(security.HashToken(token))
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !consumed {
+		responses.Error(w, http.StatusBadRequest, errInvalidPasswordReset)
+		return
+	}
+
+	if err = controller.Users.UpdatePassword(userID, string(hashedPassword)); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Whoever knew the old password is logged out
+	if err = controller.Sessions.RevokeAllExcept(userID, ""); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// sendPasswordReset emails the user a signed link to choose a new password, it runs after
+// the response so the failures are only logged
+func (controller *Controller) sendPasswordReset(user models.User) {
+	ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+	defer cancel()
+
+	token, err := security.RandomToken(32)
+	if err != nil {
+		log.Printf("Error creating the password reset of user %d: %v", user.ID, err)
+		return
+	}
+
+	repository := controller.PasswordResets
+	if err = repository.Create(models.PasswordReset{
+		TokenHash: security.HashToken(token),
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(passwordResetDuration),
+	}); err != nil {
+		log.Printf("Error creating the password reset of user %d: %v", user.ID, err)
+		return
+	}
+
+	signed := security.Sign(token, config.SecretKey)
+	if err = controller.Mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello,\n\n"+
+			"Someone asked to reset the password of your account. Choose a new one by opening the link below, it works for the next hour:\n\n"+
+			"%s/reset-password?token=%s\n\n"+
+			"If it was not you, ignore this email, your password stays the same.\n",
+			config.AppURL, url.QueryEscape(signed)),
+	}); err != nil {
+		log.Printf("Error sending the password reset of user %d: %v", user.ID, err)
+	}
+}
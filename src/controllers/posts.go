@@ -2,8 +2,9 @@ package controllers
 
 import (
 	"api/src/authentication"
-	"api/src/database"
+	"api/src/events"
 	"api/src/models"
+	"api/src/pagination"
 	"api/src/repositories"
 	"api/src/responses"
 	"encoding/json"
@@ -16,20 +17,21 @@ import (
 )
 
 // @Summary Create a new post
-// @Description Create a new post with the data sent in the request body
+// @Description Create a new post with the data sent in the request body, the author must have confirmed their email address
 // @Tags posts
 // @Accept  json
 // @Produce  json
 // @Security Bearer
 // @Param post body string true "Create Post" example({"title": "string", "content": "string"})
 // @Success      201  {object}  models.Post
-// @Failure      400  {object}  object       "Bad Request"
-// @Failure      401  {object}  object       "Unauthorized"
-// @Failure      422  {object}  object       "Unprocessable Entity"
-// @Failure      500  {object}  object       "Internal Server Error"
+// @Failure      400  {object}  responses.Problem       "Bad Request"
+// @Failure      401  {object}  responses.Problem       "Unauthorized"
+// @Failure      403  {object}  responses.Problem       "Forbidden"
+// @Failure      422  {object}  responses.Problem       "Unprocessable Entity"
+// @Failure      500  {object}  responses.Problem       "Internal Server Error"
 // @Router       /posts [post]
 // @Security ApiKeyAuth
-func CreatePost(w http.ResponseWriter, r *http.Request) {
+func (controller *Controller) CreatePost(w http.ResponseWriter, r *http.Request) {
 	userID, err := authentication.ExtractUserID(r)
 	if err != nil {
 		responses.Error(w, http.StatusUnauthorized, err)
@@ -55,20 +57,22 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := database.Connect()
-	if err != nil {
+	if err = controller.resolveMentions(&post); err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
-	defer db.Close()
 
-	repository := repositories.NewPostsRepository(db)
+	repository := controller.Posts
 	post.ID, err = repository.Create(post)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	post.Attachments = []models.Attachment{}
+	controller.notifyMentions(post, nil)
+
+	controller.publishToFollowers(userID, events.PostCreated, post)
 	responses.JSON(w, http.StatusCreated, post)
 }
 
@@ -77,31 +81,38 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 // @Tags posts
 // @Produce json
 // @Security Bearer
-// @Success 200 {array} models.Post
-// @Failure 500 {object} object "Internal Server Error"
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.Post]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /posts [get]
-func GetPosts(w http.ResponseWriter, r *http.Request) {
+func (controller *Controller) GetPosts(w http.ResponseWriter, r *http.Request) {
 	userID, err := authentication.ExtractUserID(r)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	db, err := database.Connect()
+	page, err := pagination.FromRequest(r)
 	if err != nil {
-		responses.Error(w, http.StatusInternalServerError, err)
+		responses.Error(w, http.StatusBadRequest, err)
 		return
 	}
-	defer db.Close()
 
-	repository := repositories.NewPostsRepository(db)
-	posts, err := repository.Search(userID)
+	repository := controller.Posts
+	posts, err := repository.Search(userID, page)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, posts)
+	if posts, err = controller.withDetails(posts...); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, pagination.NewPage(posts, page, postCursorID))
 }
 
 // @Summary Get a post by ID
@@ -111,10 +122,18 @@ func GetPosts(w http.ResponseWriter, r *http.Request) {
 // @Security Bearer
 // @Param postId path int true "Post ID"
 // @Success 200 {object} models.Post
-// @Failure 400 {object} object "Bad Request"
-// @Failure 500 {object} object "Internal Server Error"
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /posts/{postId} [get]
-func GetPost(w http.ResponseWriter, r *http.Request) {
+func (controller *Controller) GetPost(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
 	params := mux.Vars(r)
 	postID, err := strconv.ParseUint(params["postId"], 10, 64)
 	if err != nil {
@@ -122,21 +141,24 @@ func GetPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := database.Connect()
+	repository := controller.Posts
+	post, err := repository.SearchByID(postID, userID)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
-	defer db.Close()
 
-	repository := repositories.NewPostsRepository(db)
-	post, err := repository.SearchByID(postID)
+	if !controller.checkCanSeePost(w, userID, post) {
+		return
+	}
+
+	posts, err := controller.withDetails(post)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, post)
+	responses.JSON(w, http.StatusOK, posts[0])
 }
 
 // @Summary Update a post
@@ -148,13 +170,15 @@ func GetPost(w http.ResponseWriter, r *http.Request) {
 // @Param postId path int true "Post ID"
 // @Param post body string true "Post data" example({"title": "string", "content": "string"})
 // @Success 204 {object} object
-// @Failure 400 {object} object "Bad Request"
-// @Failure 401 {object} object "Unauthorized"
-// @Failure 422 {object} object "Unprocessable Entity"
-// @Failure 500 {object} object "Internal Server Error"
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 422 {object} responses.Problem "Unprocessable Entity"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /posts/{postId} [put]
 // @Security ApiKeyAuth
-func UpdatePost(w http.ResponseWriter, r *http.Request) {
+func (controller *Controller) UpdatePost(w http.ResponseWriter, r *http.Request) {
 	userID, err := authentication.ExtractUserID(r)
 	if err != nil {
 		responses.Error(w, http.StatusUnauthorized, err)
@@ -168,22 +192,15 @@ func UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := database.Connect()
-	if err != nil {
-		responses.Error(w, http.StatusInternalServerError, err)
-		return
-	}
-	defer db.Close()
-
-	repository := repositories.NewPostsRepository(db)
-	postOnBank, err := repository.SearchByID(postID)
+	repository := controller.Posts
+	postOnBank, err := repository.SearchByID(postID, userID)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
 	if postOnBank.AuthorID != userID {
-		responses.Error(w, http.StatusInternalServerError, errors.New("It is not possible to update a post that is not yours"))
+		responses.Error(w, http.StatusForbidden, errors.New("It is not possible to update a post that is not yours"))
 		return
 	}
 
@@ -204,11 +221,26 @@ func UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	post.AuthorID = userID
+	if err = controller.resolveMentions(&post); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	previous, err := repository.SearchMentions([]uint64{postID})
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	if err = repository.Update(postID, post); err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	post.ID = postID
+	controller.notifyMentions(post, previous)
+
 	responses.JSON(w, http.StatusNoContent, nil)
 }
 
@@ -218,12 +250,14 @@ func UpdatePost(w http.ResponseWriter, r *http.Request) {
 // @Security Bearer
 // @Param postId path int true "Post ID"
 // @Success 204 {object} object
-// @Failure 400 {object} object "Bad Request"
-// @Failure 401 {object} object "Unauthorized"
-// @Failure 500 {object} object "Internal Server Error"
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /posts/{postId} [delete]
 // @Security ApiKeyAuth
-func DeletePost(w http.ResponseWriter, r *http.Request) {
+func (controller *Controller) DeletePost(w http.ResponseWriter, r *http.Request) {
 	userID, err := authentication.ExtractUserID(r)
 	if err != nil {
 		responses.Error(w, http.StatusUnauthorized, err)
@@ -237,22 +271,21 @@ func DeletePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := database.Connect()
+	repository := controller.Posts
+	postOnBank, err := repository.SearchByID(postID, userID)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
-	defer db.Close()
 
-	repository := repositories.NewPostsRepository(db)
-	postOnBank, err := repository.SearchByID(postID)
-	if err != nil {
-		responses.Error(w, http.StatusInternalServerError, err)
+	if postOnBank.AuthorID != userID {
+		responses.Error(w, http.StatusForbidden, errors.New("It is not possible to delete a post that is not yours"))
 		return
 	}
 
-	if postOnBank.AuthorID != userID {
-		responses.Error(w, http.StatusInternalServerError, errors.New("It is not possible to delete a post that is not yours"))
+	attachments, err := controller.Attachments.SearchByPosts([]uint64{postID})
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -261,6 +294,7 @@ func DeletePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	controller.deleteFiles(attachments)
 	responses.JSON(w, http.StatusNoContent, nil)
 }
 
@@ -270,101 +304,231 @@ func DeletePost(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Security Bearer
 // @Param userId path int true "User ID"
-// @Success 200 {array} models.Post
-// @Failure 400 {object} object "Bad Request"
-// @Failure 500 {object} object "Internal Server Error"
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.Post]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /users/{userId}/posts [get]
-func GetPostsPerUser(w http.ResponseWriter, r *http.Request) {
+func (controller *Controller) GetPostsPerUser(w http.ResponseWriter, r *http.Request) {
+	viewerID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
 	params := mux.Vars(r)
-	userID, err := strconv.ParseUint(params["postId"], 10, 64)
+	userID, err := strconv.ParseUint(params["userId"], 10, 64)
 	if err != nil {
 		responses.Error(w, http.StatusBadRequest, err)
 		return
 	}
 
-	db, err := database.Connect()
+	page, err := pagination.FromRequest(r)
 	if err != nil {
-		responses.Error(w, http.StatusInternalServerError, err)
+		responses.Error(w, http.StatusBadRequest, err)
 		return
 	}
-	defer db.Close()
 
-	repository := repositories.NewPostsRepository(db)
-	posts, err := repository.SearchByUser(userID)
+	if !controller.checkCanSee(w, viewerID, userID) {
+		return
+	}
+
+	repository := controller.Posts
+	posts, err := repository.SearchByUser(userID, viewerID, page)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, posts)
+	if posts, err = controller.withDetails(posts...); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, pagination.NewPage(posts, page, postCursorID))
 }
 
 // @Summary Like a post
-// @Description Increment the likes count for a post
+// @Description Record that the authenticated user likes a post, liking it again has no effect
 // @Tags posts
 // @Security Bearer
 // @Param postId path int true "Post ID"
 // @Success 204 {object} object
-// @Failure 400 {object} object "Bad Request"
-// @Failure 500 {object} object "Internal Server Error"
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /posts/{postId}/like [post]
 // @Security ApiKeyAuth
-func LikePost(w http.ResponseWriter, r *http.Request) {
+func (controller *Controller) LikePost(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
 	params := mux.Vars(r)
-	userID, err := strconv.ParseUint(params["postId"], 10, 64)
+	postID, err := strconv.ParseUint(params["postId"], 10, 64)
 	if err != nil {
 		responses.Error(w, http.StatusBadRequest, err)
 		return
 	}
 
-	db, err := database.Connect()
+	repository := controller.Posts
+	post, err := repository.SearchByID(postID, userID)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
-	defer db.Close()
 
-	repository := repositories.NewPostsRepository(db)
-	if err = repository.Like(userID); err != nil {
+	if !controller.checkCanSeePost(w, userID, post) {
+		return
+	}
+
+	if err = repository.Like(postID, userID); err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	if !post.LikedByMe && post.AuthorID != userID {
+		controller.notify(post.AuthorID, userID, models.NotificationLike, &post.ID)
+		controller.Events.Publish(events.Event{
+			Type:       events.PostLiked,
+			Recipients: []uint64{post.AuthorID},
+			Data:       events.PostLike{PostID: post.ID, UserID: userID},
+		})
+	}
+
 	responses.JSON(w, http.StatusNoContent, nil)
 
 }
 
 // @Summary Dislike a post
-// @Description Decrement the likes count for a post
+// @Description Remove the like the authenticated user gave to a post, if there is one
 // @Tags posts
 // @Security Bearer
 // @Param postId path int true "Post ID"
 // @Success 204 {object} object
-// @Failure 400 {object} object "Bad Request"
-// @Failure 500 {object} object "Internal Server Error"
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /posts/{postId}/dislike [post]
 // @Security ApiKeyAuth
-func DislikePost(w http.ResponseWriter, r *http.Request) {
+func (controller *Controller) DislikePost(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	params := mux.Vars(r)
+	postID, err := strconv.ParseUint(params["postId"], 10, 64)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.Posts
+	if err = repository.Dislike(postID, userID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+
+}
+
+// @Summary Get the users who liked a post
+// @Description Retrieve the users who liked a post
+// @Tags posts
+// @Produce json
+// @Security Bearer
+// @Param postId path int true "Post ID"
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.User]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /posts/{postId}/likes [get]
+func (controller *Controller) GetPostLikes(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
 	params := mux.Vars(r)
-	userID, err := strconv.ParseUint(params["postId"], 10, 64)
+	postID, err := strconv.ParseUint(params["postId"], 10, 64)
 	if err != nil {
 		responses.Error(w, http.StatusBadRequest, err)
 		return
 	}
 
-	db, err := database.Connect()
+	repository := controller.Posts
+	post, err := repository.SearchByID(postID, userID)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
-	defer db.Close()
 
-	repository := repositories.NewPostsRepository(db)
-	if err = repository.Dislike(userID); err != nil {
+	if !controller.checkCanSeePost(w, userID, post) {
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	users, err := repository.SearchLikes(postID, page)
+	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusNoContent, nil)
+	responses.JSON(w, http.StatusOK, pagination.NewPage(users, page, userCursorID))
+}
+
+// checkCanSeePost writes the error response and returns false when the viewer cannot see the post,
+// the posts of private accounts and of blocked users are hidden as if they did not exist
+func (controller *Controller) checkCanSeePost(w http.ResponseWriter, viewerID uint64, post models.Post) bool {
+	author, err := controller.Users.SearchByID(post.AuthorID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return false
+	}
+
+	visible, err := controller.canSee(viewerID, author)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return false
+	}
+
+	if !visible {
+		responses.Error(w, http.StatusNotFound, repositories.ErrNotFound)
+		return false
+	}
+
+	return true
+}
+
+// withDetails fills the attachments and the mentions of the posts
+func (controller *Controller) withDetails(posts ...models.Post) ([]models.Post, error) {
+	posts, err := controller.withAttachments(posts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return controller.withMentions(posts...)
+}
 
+func postCursorID(post models.Post) uint64 {
+	return post.ID
 }
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/responses"
+	"api/src/search"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// @Summary Search posts
+// @Description Full-text search over the titles and contents of the posts, the most relevant first. Words match the words they start, "quoted phrases" match exactly and -word excludes the posts with the word. Words shorter than 3 characters are ignored
+// @Tags posts
+// @Produce json
+// @Security Bearer
+// @Param q query string true "Search query"
+// @Param authorId query int false "Only the posts of this user"
+// @Param since query string false "Only the posts created from this date on, as 2006-01-02 or RFC 3339"
+// @Param until query string false "Only the posts created up to this date, as 2006-01-02 (inclusive) or RFC 3339 (exclusive)"
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.PostSearchResult]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /search/posts [get]
+func (controller *Controller) SearchPosts(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	parameters := r.URL.Query()
+	query, err := search.Parse(parameters.Get("q"))
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if authorID := parameters.Get("authorId"); authorID != "" {
+		if query.AuthorID, err = strconv.ParseUint(authorID, 10, 64); err != nil {
+			responses.Error(w, http.StatusBadRequest, errors.New("The author ID must be a number"))
+			return
+		}
+	}
+
+	if query.Since, err = searchDate(parameters.Get("since"), false); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if query.Until, err = searchDate(parameters.Get("until"), true); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.Posts
+	results, err := repository.SearchText(query, userID, page)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	posts := make([]models.Post, 0, len(results))
+	for _, result := range results {
+		posts = append(posts, result.Post)
+	}
+
+	if posts, err = controller.withDetails(posts...); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	for i := range results {
+		results[i].Post = posts[i]
+		results[i].Title = query.Highlight(posts[i].Title)
+		results[i].Snippet = query.Snippet(posts[i].Content, search.SnippetLength)
+	}
+
+	responses.JSON(w, http.StatusOK, pagination.NewPage(results, page, searchResultCursorID))
+}
+
+// searchDate reads a date filter, a day given without a time ends the range at
+// the end of that day when it is the upper bound
+func searchDate(value string, upper bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		if upper {
+			date = date.AddDate(0, 0, 1)
+		}
+		return date, nil
+	}
+
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("The dates must be formatted as 2006-01-02 or 2006-01-02T15:04:05Z07:00")
+	}
+
+	return date, nil
+}
+
+// searchResultCursorID uses the rank as cursor because the results are not ordered by ID
+func searchResultCursorID(result models.PostSearchResult) uint64 {
+	return result.Rank
+}
@@ -0,0 +1,443 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/config"
+	"api/src/middlewares"
+	"api/src/models"
+	"api/src/repositories"
+	"api/src/responses"
+	"api/src/security"
+	"api/src/totp"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// loginChallengeDuration is how long the second step of a login can wait after the password
+	loginChallengeDuration = 5 * time.Minute
+	// maxChallengeAttempts is how many codes can be tried before the password has to be given again
+	maxChallengeAttempts = 5
+	// recoveryCodeCount is how many recovery codes are given when two-factor authentication is enabled
+	recoveryCodeCount = 10
+)
+
+var (
+	// errInvalidLoginChallenge does not tell an unknown challenge from a used or expired one
+	errInvalidLoginChallenge = errors.New("The login has expired, log in again")
+	errInvalidTwoFactorCode  = errors.New("The code is incorrect")
+)
+
+// @Summary Get the two-factor authentication status
+// @Description Tell whether the logins of the authenticated user ask for a code and how many recovery codes are left
+// @Tags authentication
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.TwoFactor
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /auth/2fa [get]
+func (controller *Controller) GetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	repository := controller.TwoFactor
+	twoFactor, err := repository.Search(userID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !twoFactor.Enabled() {
+		twoFactor = models.TwoFactor{}
+	}
+
+	responses.JSON(w, http.StatusOK, twoFactor)
+}
+
+// @Summary Set up two-factor authentication
+// @Description Create a new TOTP secret for the authenticated user, it is only used once a code from it confirms the setup. Setting up again replaces a secret that was not confirmed
+// @Tags authentication
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.TwoFactorSetup
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 409 {object} responses.Problem "Conflict"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /auth/2fa/setup [post]
+func (controller *Controller) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	repository := controller.TwoFactor
+	twoFactor, err := repository.Search(userID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if twoFactor.Enabled() {
+		responses.Error(w, http.StatusConflict, errors.New("Two-factor authentication is already enabled"))
+		return
+	}
+
+	user, err := controller.Users.SearchByID(userID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err = repository.Setup(userID, secret); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, models.TwoFactorSetup{
+		Secret: secret,
+		URI:    totp.URI(config.TOTPIssuer, user.Email, secret),
+	})
+}
+
+// @Summary Enable two-factor authentication
+// @Description Confirm the secret of the setup with a code from the authenticator app. The answer holds the recovery codes, they are not shown again
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body models.TwoFactorCodeRequest true "Code from the authenticator app"
+// @Success 200 {object} models.RecoveryCodes
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 409 {object} responses.Problem "Conflict"
+// @Failure 422 {object} responses.Problem "Unprocessable Entity"
+// @Failure 429 {object} responses.Problem "Too Many Requests"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /auth/2fa/enable [post]
+func (controller *Controller) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	requestBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var request models.TwoFactorCodeRequest
+	if err = json.Unmarshal(requestBody, &request); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.TwoFactor
+	twoFactor, err := repository.Search(userID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if twoFactor.Secret == "" || twoFactor.Enabled() {
+		responses.Error(w, http.StatusConflict, errors.New("There is no two-factor authentication setup to confirm"))
+		return
+	}
+
+	step, valid := totp.Validate(twoFactor.Secret, request.Code, time.Now())
+	if !valid {
+		responses.Error(w, http.StatusBadRequest, errInvalidTwoFactorCode)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	enabled, err := repository.Enable(userID, step, hashes)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !enabled {
+		responses.Error(w, http.StatusConflict, errors.New("There is no two-factor authentication setup to confirm"))
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, models.RecoveryCodes{RecoveryCodes: codes})
+}
+
+// @Summary Disable two-factor authentication
+// @Description Stop asking the authenticated user for a code when they log in. The password and a code from the authenticator app or a recovery code are required
+// @Tags authentication
+// @Accept json
+// @Security Bearer
+// @Param request body models.DisableTwoFactorRequest true "Password and code"
+// @Success 204 {object} object
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 409 {object} responses.Problem "Conflict"
+// @Failure 422 {object} responses.Problem "Unprocessable Entity"
+// @Failure 429 {object} responses.Problem "Too Many Requests"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /auth/2fa/disable [post]
+func (controller *Controller) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	requestBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var request models.DisableTwoFactorRequest
+	if err = json.Unmarshal(requestBody, &request); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	savedPassword, err := controller.Users.SearchPassword(userID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err = security.VerifyPassword(savedPassword, request.Password); err != nil {
+		responses.Error(w, http.StatusUnauthorized, errors.New("The password is incorrect"))
+		return
+	}
+
+	repository := controller.TwoFactor
+	twoFactor, err := repository.Search(userID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !twoFactor.Enabled() {
+		responses.Error(w, http.StatusConflict, errors.New("Two-factor authentication is not enabled"))
+		return
+	}
+
+	verified, err := controller.verifySecondFactor(twoFactor, request.Code)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !verified {
+		responses.Error(w, http.StatusUnauthorized, errInvalidTwoFactorCode)
+		return
+	}
+
+	if err = repository.Disable(userID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+// @Summary Complete a login with the second factor
+// @Description Exchange the challenge token returned by /login and a code from the authenticator app or a recovery code for the session tokens. A challenge works for 5 minutes and 5 codes
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorLoginRequest true "Challenge token and code"
+// @Success 200 {object} models.AuthTokens
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 422 {object} responses.Problem "Unprocessable Entity"
+// @Failure 429 {object} responses.Problem "Too Many Requests"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
+// @Router /login/2fa [post]
+func (controller *Controller) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	requestBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var request models.TwoFactorLoginRequest
+	if err = json.Unmarshal(requestBody, &request); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	repository := controller.TwoFactor
+	tokenHash := security.HashToken(request.ChallengeToken)
+	userID, attempted, err := repository.AttemptChallenge(tokenHash, maxChallengeAttempts)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !attempted {
+		responses.Error(w, http.StatusUnauthorized, errInvalidLoginChallenge)
+		return
+	}
+
+	user, err := controller.Users.SearchByID(userID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	attempt := models.LoginAttempt{
+		UserID:    &user.ID,
+		Email:     strings.ToLower(user.Email),
+		IP:        middlewares.ClientIP(r),
+		UserAgent: truncate(r.UserAgent(), 255),
+	}
+
+	if user.SuspendedAt != nil {
+		attempt.Reason = models.LoginSuspended
+		controller.recordLoginAttempt(attempt)
+		responses.Error(w, http.StatusForbidden, errors.New("this account is suspended"))
+		return
+	}
+
+	twoFactor, err := repository.Search(userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		responses.Error(w, http.StatusUnauthorized, errInvalidLoginChallenge)
+		return
+	}
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	verified, err := controller.verifySecondFactor(twoFactor, request.Code)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !verified {
+		attempt.Reason = models.LoginInvalidTwoFactorCode
+		controller.recordLoginAttempt(attempt)
+		responses.Error(w, http.StatusUnauthorized, errInvalidTwoFactorCode)
+		return
+	}
+
+	consumed, err := repository.ConsumeChallenge(tokenHash)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !consumed {
+		responses.Error(w, http.StatusUnauthorized, errInvalidLoginChallenge)
+		return
+	}
+
+	tokens, err := controller.startSession(user)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	attempt.Succeeded, attempt.Reason = true, models.LoginSucceeded
+	controller.recordLoginAttempt(attempt)
+
+	responses.JSON(w, http.StatusOK, tokens)
+}
+
+// startLoginChallenge creates the challenge a login with the right password has to
+// answer with a second factor before it gets a session
+func (controller *Controller) startLoginChallenge(userID uint64) (models.TwoFactorChallenge, error) {
+	token, err := security.RandomToken(32)
+	if err != nil {
+		return models.TwoFactorChallenge{}, err
+	}
+
+	if err = controller.TwoFactor.CreateChallenge(models.LoginChallenge{
+		TokenHash: security.HashToken(token),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(loginChallengeDuration),
+	}); err != nil {
+		return models.TwoFactorChallenge{}, err
+	}
+
+	return models.TwoFactorChallenge{
+		ChallengeToken: token,
+		ExpiresIn:      int64(loginChallengeDuration.Seconds()),
+	}, nil
+}
+
+// verifySecondFactor checks a code from the authenticator app, which cannot be used twice, or
+// else spends one of the recovery codes
+func (controller *Controller) verifySecondFactor(twoFactor models.TwoFactor, code string) (bool, error) {
+	repository := controller.TwoFactor
+	if step, valid := totp.Validate(twoFactor.Secret, code, time.Now()); valid {
+		return repository.UseStep(twoFactor.UserID, step)
+	}
+
+	return repository.UseRecoveryCode(twoFactor.UserID, security.HashToken(normalizeRecoveryCode(code)))
+}
+
+// newRecoveryCodes returns the recovery codes to show to the user and the hashes to store
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		buffer := make([]byte, 5)
+		if _, err := rand.Read(buffer); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(buffer))
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, security.HashToken(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode accepts the recovery codes in any case and with or without the dash
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -2,9 +2,9 @@ package controllers
 
 import (
 	"api/src/authentication"
-	"api/src/database"
+	"api/src/events"
 	"api/src/models"
-	"api/src/repositories"
+	"api/src/pagination"
 	"api/src/responses"
 	"api/src/security"
 	"encoding/json"
@@ -18,17 +18,19 @@ import (
 )
 
 // @Summary Create a new user
-// @Description Create a new user with the provided data
+// @Description Create a new user with the provided data, a link to confirm the email address is sent to it. Unconfirmed accounts cannot publish anything
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security Bearer
 // @Param user body models.User true "New user data"
 // @Success 201 {object} models.User
-// @Failure 400 {object} object "Bad Request"
-// @Failure 500 {object} object "Internal Server Error"
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 409 {object} responses.Problem "Conflict"
+// @Failure 429 {object} responses.Problem "Too Many Requests"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /users [post]
-func CreateUser(w http.ResponseWriter, r *http.Request) {
+func (controller *Controller) CreateUser(w http.ResponseWriter, r *http.Request) {
 	requestBody, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		responses.Error(w, http.StatusUnprocessableEntity, err)
@@ -46,50 +48,54 @@ func CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := database.Connect()
-	if err != nil {
-		responses.Error(w, http.StatusInternalServerError, err)
-		return
-	}
-	defer db.Close()
-
-	repository := repositories.NewUsersRepository(db)
+	repository := controller.Users
 	user.ID, err = repository.Create(user)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	controller.trySendEmailVerification(r.Context(), user)
 	responses.JSON(w, http.StatusCreated, user)
 }
 
 // @Summary Get all users
-// @Description Retrieve all users, optionally filtered by name or nickname
+// @Description Retrieve all users, optionally filtered by name or nickname. The users who blocked the authenticated user or were blocked by them are left out
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security Bearer
-// @Success 200 {array} models.User
-// @Failure 500 {object} object "Internal Server Error"
+// @Param user query string false "Name or nick to filter by"
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.User]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /users [get]
-func GetUsers(w http.ResponseWriter, r *http.Request) {
+func (controller *Controller) GetUsers(w http.ResponseWriter, r *http.Request) {
+	viewerID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
 	nameOrNick := strings.ToLower(r.URL.Query().Get("user"))
 
-	db, err := database.Connect()
+	page, err := pagination.FromRequest(r)
 	if err != nil {
-		responses.Error(w, http.StatusInternalServerError, err)
+		responses.Error(w, http.StatusBadRequest, err)
 		return
 	}
-	defer db.Close()
 
-	repository := repositories.NewUsersRepository(db)
-	users, err := repository.Search(nameOrNick)
+	repository := controller.Users
+	users, err := repository.Search(nameOrNick, viewerID, page)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, users)
+	responses.JSON(w, http.StatusOK, pagination.NewPage(users, page, userCursorID))
 }
 
 // @Summary Get user by ID
@@ -100,10 +106,18 @@ func GetUsers(w http.ResponseWriter, r *http.Request) {
 // @Security Bearer
 // @Param userID path int true "User ID"
 // @Success 200 {object} models.User
-// @Failure 400 {object} object "Bad Request"
-// @Failure 500 {object} object "Internal Server Error"
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /users/{userID} [get]
-func GetUser(w http.ResponseWriter, r *http.Request) {
+func (controller *Controller) GetUser(w http.ResponseWriter, r *http.Request) {
+	viewerID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
 	params := mux.Vars(r)
 	userId, err := strconv.ParseUint(params["userID"], 10, 64)
 	if err != nil {
@@ -111,14 +125,11 @@ func GetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := database.Connect()
-	if err != nil {
-		responses.Error(w, http.StatusInternalServerError, err)
+	if !controller.checkNotBlocked(w, viewerID, userId) {
 		return
 	}
-	defer db.Close()
 
-	repository := repositories.NewUsersRepository(db)
+	repository := controller.Users
 	user, err := repository.SearchByID(userId)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
@@ -136,11 +147,11 @@ func GetUser(w http.ResponseWriter, r *http.Request) {
 // @Security Bearer
 // @Param userID path int true "User ID"
 // @Success 204 {object} object
-// @Failure 400 {object} object "Bad Request"
-// @Failure 403 {object} object "Forbidden"
-// @Failure 500 {object} object "Internal Server Error"
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /users/{userID} [delete]
-func DeleteUser(w http.ResponseWriter, r *http.Request) {
+func (controller *Controller) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	userID, err := strconv.ParseUint(params["userID"], 10, 64)
 	if err != nil {
@@ -159,35 +170,38 @@ func DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := database.Connect()
+	// The rows of the attachments go away with the posts, the stored images have to be removed by hand
+	attachments, err := controller.Attachments.SearchByUser(userID)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
-	defer db.Close()
 
-	repository := repositories.NewUsersRepository(db)
+	repository := controller.Users
 	if err := repository.Delete(userID); err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	controller.deleteFiles(attachments)
+
 	responses.JSON(w, http.StatusNoContent, nil)
 }
 
 // @Summary Update user by ID
-// @Description Update a user by their ID
+// @Description Update a user by their ID, a new email address has to be confirmed again before publishing. Personal access tokens cannot change the email address
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security Bearer
 // @Param userID path int true "User ID"
 // @Success 204 {object} object
-// @Failure 400 {object} object "Bad Request"
-// @Failure 403 {object} object "Forbidden"
-// @Failure 500 {object} object "Internal Server Error"
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 409 {object} responses.Problem "Conflict"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /users/{userID} [put]
-func UpdateUser(w http.ResponseWriter, r *http.Request) {
+func (controller *Controller) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	userID, err := strconv.ParseUint(params["userID"], 10, 64)
 	if err != nil {
@@ -195,13 +209,13 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userIDToken, err := authentication.ExtractUserID(r)
+	principal, err := authentication.ExtractPrincipal(r)
 	if err != nil {
 		responses.Error(w, http.StatusUnauthorized, err)
 		return
 	}
 
-	if userID != userIDToken {
+	if userID != principal.UserID {
 		responses.Error(w, http.StatusForbidden, errors.New("It is not possible to update a user other than yours"))
 		return
 	}
@@ -224,34 +238,48 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := database.Connect()
+	repository := controller.Users
+	saved, err := repository.SearchByID(userID)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
-	defer db.Close()
 
-	repository := repositories.NewUsersRepository(db)
+	// The email address receives the password reset links, a token must not be able to redirect them
+	if user.Email != saved.Email && principal.AccessTokenID != 0 {
+		responses.Error(w, http.StatusForbidden, errors.New("The email address can only be changed from a logged in session"))
+		return
+	}
+
 	if err = repository.Update(userID, user); err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	// A new address has to be confirmed again
+	if user.Email != saved.Email {
+		user.ID = userID
+		controller.trySendEmailVerification(r.Context(), user)
+	}
+
 	responses.JSON(w, http.StatusNoContent, nil)
 }
 
 // @Summary Follow user by ID
-// @Description Follow a user by their ID
+// @Description Follow a user by their ID, following a private account sends a follow request instead and answers 202
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security Bearer
 // @Param userID path int true "User ID"
 // @Success 204 {object} object
-// @Failure 401 {object} object "Unauthorized"
-// @Failure 500 {object} object "Internal Server Error"
+// @Success 202 {object} object
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /users/{userID}/follow [post]
-func FollowUser(w http.ResponseWriter, r *http.Request) {
+func (controller *Controller) FollowUser(w http.ResponseWriter, r *http.Request) {
 	followerID, err := authentication.ExtractUserID(r)
 	if err != nil {
 		responses.Error(w, http.StatusUnauthorized, err)
@@ -270,34 +298,63 @@ func FollowUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := database.Connect()
+	blocked, err := controller.Blocks.IsBlocked(userID, followerID)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
-	defer db.Close()
 
-	repository := repositories.NewUsersRepository(db)
+	if blocked {
+		responses.Error(w, http.StatusForbidden, errBlocked)
+		return
+	}
+
+	repository := controller.Users
+	user, err := repository.SearchByID(userID)
+	if err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if user.Private {
+		following, err := repository.IsFollowing(userID, followerID)
+		if err != nil {
+			responses.Error(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !following {
+			controller.requestToFollow(w, userID, followerID)
+			return
+		}
+	}
+
 	if err = repository.Follow(userID, followerID); err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	controller.notify(userID, followerID, models.NotificationFollow, nil)
+	controller.Events.Publish(events.Event{
+		Type:       events.UserFollowed,
+		Recipients: []uint64{userID},
+		Data:       events.Follow{FollowerID: followerID},
+	})
 	responses.JSON(w, http.StatusNoContent, nil)
 }
 
 // @Summary Unfollow user by ID
-// @Description Unfollow a user by their ID
+// @Description Unfollow a user by their ID, a pending follow request is withdrawn
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security Bearer
 // @Param userID path int true "User ID"
 // @Success 204 {object} object
-// @Failure 401 {object} object "Unauthorized"
-// @Failure 500 {object} object "Internal Server Error"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /users/{userID}/unfollow [post]
-func UnfollowUser(w http.ResponseWriter, r *http.Request) {
+func (controller *Controller) UnfollowUser(w http.ResponseWriter, r *http.Request) {
 	followerID, err := authentication.ExtractUserID(r)
 	if err != nil {
 		responses.Error(w, http.StatusUnauthorized, err)
@@ -305,7 +362,7 @@ func UnfollowUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	parameters := mux.Vars(r)
-	userID, err := strconv.ParseUint(parameters["userId"], 10, 64)
+	userID, err := strconv.ParseUint(parameters["userID"], 10, 64)
 
 	if err != nil {
 		responses.Error(w, http.StatusBadRequest, err)
@@ -317,15 +374,13 @@ func UnfollowUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := database.Connect()
-	if err != nil {
+	repository := controller.Users
+	if err = repository.Unfollow(userID, followerID); err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
-	defer db.Close()
 
-	repository := repositories.NewUsersRepository(db)
-	if err = repository.Unfollow(userID, followerID); err != nil {
+	if err = controller.FollowRequests.Cancel(userID, followerID); err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
@@ -340,33 +395,47 @@ func UnfollowUser(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Security Bearer
 // @Param userID path int true "User ID"
-// @Success 200 {array} models.User
-// @Failure 400 {object} object "Bad Request"
-// @Failure 500 {object} object "Internal Server Error"
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.User]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /users/{userID}/followers [get]
-func SearchFollowers(w http.ResponseWriter, r *http.Request) {
-	parameters := mux.Vars(r)
+func (controller *Controller) SearchFollowers(w http.ResponseWriter, r *http.Request) {
+	viewerID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
 
+	parameters := mux.Vars(r)
 	userID, err := strconv.ParseUint(parameters["userID"], 10, 64)
 	if err != nil {
 		responses.Error(w, http.StatusBadRequest, err)
 		return
 	}
 
-	db, err := database.Connect()
+	if !controller.checkCanSee(w, viewerID, userID) {
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
 	if err != nil {
-		responses.Error(w, http.StatusInternalServerError, err)
+		responses.Error(w, http.StatusBadRequest, err)
+		return
 	}
-	defer db.Close()
 
-	repository := repositories.NewUsersRepository(db)
-	followers, err := repository.SearchFollowers(userID)
+	repository := controller.Users
+	followers, err := repository.SearchFollowers(userID, page)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, followers)
+	responses.JSON(w, http.StatusOK, pagination.NewPage(followers, page, userCursorID))
 }
 
 // @Summary Search following users of user
@@ -376,37 +445,51 @@ func SearchFollowers(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Security Bearer
 // @Param userID path int true "User ID"
-// @Success 200 {array} models.User
-// @Failure 400 {object} object "Bad Request"
-// @Failure 500 {object} object "Internal Server Error"
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Success 200 {object} pagination.Page[models.User]
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 403 {object} responses.Problem "Forbidden"
+// @Failure 404 {object} responses.Problem "Not Found"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /users/{userID}/following [get]
-func SearchFollowing(w http.ResponseWriter, r *http.Request) {
-	parameters := mux.Vars(r)
+func (controller *Controller) SearchFollowing(w http.ResponseWriter, r *http.Request) {
+	viewerID, err := authentication.ExtractUserID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
 
+	parameters := mux.Vars(r)
 	userID, err := strconv.ParseUint(parameters["userID"], 10, 64)
 	if err != nil {
 		responses.Error(w, http.StatusBadRequest, err)
 		return
 	}
 
-	db, err := database.Connect()
+	if !controller.checkCanSee(w, viewerID, userID) {
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
 	if err != nil {
-		responses.Error(w, http.StatusInternalServerError, err)
+		responses.Error(w, http.StatusBadRequest, err)
+		return
 	}
-	defer db.Close()
 
-	repository := repositories.NewUsersRepository(db)
-	users, err := repository.SearchFollowing(userID)
+	repository := controller.Users
+	users, err := repository.SearchFollowing(userID, page)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, users)
+	responses.JSON(w, http.StatusOK, pagination.NewPage(users, page, userCursorID))
 }
 
 // @Summary Update user password
-// @Description Update the password of a user by their ID
+// @Description Update the password of a user by their ID and end all of their other sessions
 // @Tags users
 // @Accept json
 // @Produce json
@@ -414,17 +497,24 @@ func SearchFollowing(w http.ResponseWriter, r *http.Request) {
 // @Param userID path int true "User ID"
 // @Param password body models.Password true "New password" example({"new": "string", "current": "string"})
 // @Success 204 {object} object
-// @Failure 400 {object} object "Bad Request"
-// @Failure 401 {object} object "Unauthorized"
-// @Failure 500 {object} object "Internal Server Error"
+// @Failure 400 {object} responses.Problem "Bad Request"
+// @Failure 401 {object} responses.Problem "Unauthorized"
+// @Failure 422 {object} responses.Problem "Unprocessable Entity"
+// @Failure 500 {object} responses.Problem "Internal Server Error"
 // @Router /users/{userID}/update-password [post]
-func UpdatePassword(w http.ResponseWriter, r *http.Request) {
+func (controller *Controller) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 	userIDToken, err := authentication.ExtractUserID(r)
 	if err != nil {
 		responses.Error(w, http.StatusUnauthorized, err)
 		return
 	}
 
+	sessionID, err := authentication.ExtractSessionID(r)
+	if err != nil {
+		responses.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
 	parameters := mux.Vars(r)
 	userID, err := strconv.ParseUint(parameters["userID"], 10, 64)
 	if err != nil {
@@ -438,6 +528,10 @@ func UpdatePassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	requestBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, http.StatusUnprocessableEntity, err)
+		return
+	}
 
 	var password models.Password
 
@@ -446,13 +540,7 @@ func UpdatePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := database.Connect()
-	if err != nil {
-		responses.Error(w, http.StatusInternalServerError, err)
-	}
-	defer db.Close()
-
-	repository := repositories.NewUsersRepository(db)
+	repository := controller.Users
 	savedPassword, err := repository.SearchPassword(userID)
 	if err != nil {
 		responses.Error(w, http.StatusInternalServerError, err)
@@ -467,6 +555,7 @@ func UpdatePassword(w http.ResponseWriter, r *http.Request) {
 	hashedPassword, err := security.Hash(password.New)
 	if err != nil {
 		responses.Error(w, http.StatusBadRequest, err)
+		return
 	}
 
 	if err = repository.UpdatePassword(userID, string(hashedPassword)); err != nil {
@@ -474,5 +563,15 @@ func UpdatePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	sessions := controller.Sessions
+	if err = sessions.RevokeAllExcept(userID, sessionID); err != nil {
+		responses.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	responses.JSON(w, http.StatusNoContent, nil)
 }
+
+func userCursorID(user models.User) uint64 {
+	return user.ID
+}
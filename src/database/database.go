@@ -8,7 +8,7 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 )
 
-// Open the connection with the database
+// Open the connection pool with the database, it is shared by every request
 func Connect() (*sql.DB, error) {
 	db, err := sql.Open("mysql", config.ConnectionString)
 
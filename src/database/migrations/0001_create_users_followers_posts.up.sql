@@ -1,11 +1,3 @@
-usersCREATE DATABASE IF NOT EXISTS devbook;
-
-USE devbook;
-
-DROP TABLE IF EXISTS posts;
-DROP TABLE IF EXISTS followers;
-DROP TABLE IF EXISTS users;
-
 CREATE TABLE users(
     id int auto_increment primary key,
     name varchar(50) not null,
@@ -39,4 +31,4 @@ CREATE TABLE posts(
     ON DELETE CASCADE,
     likes int default 0,
     createdAt timestamp default current_timestamp
-) ENGINE=INNODB;
\ No newline at end of file
+) ENGINE=INNODB;
@@ -0,0 +1,5 @@
+ALTER TABLE posts ADD COLUMN likes int default 0 AFTER authorId;
+
+UPDATE posts p SET likes = (SELECT count(*) FROM post_likes l WHERE l.post_id = p.id);
+
+DROP TABLE IF EXISTS post_likes;
@@ -0,0 +1,17 @@
+CREATE TABLE post_likes(
+    post_id int not null,
+    FOREIGN KEY (post_id)
+    REFERENCES posts(id)
+    ON DELETE CASCADE,
+
+    user_id int not null,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    created_at timestamp default current_timestamp,
+
+    PRIMARY KEY(post_id, user_id)
+) ENGINE=INNODB;
+
+ALTER TABLE posts DROP COLUMN likes;
@@ -0,0 +1,21 @@
+CREATE TABLE comments(
+    id int auto_increment primary key,
+    post_id int not null,
+    FOREIGN KEY (post_id)
+    REFERENCES posts(id)
+    ON DELETE CASCADE,
+
+    author_id int not null,
+    FOREIGN KEY (author_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    parent_comment_id int null,
+    FOREIGN KEY (parent_comment_id)
+    REFERENCES comments(id)
+    ON DELETE CASCADE,
+
+    content varchar(300) not null,
+    created_at timestamp default current_timestamp,
+    updated_at timestamp null
+) ENGINE=INNODB;
@@ -0,0 +1,12 @@
+CREATE TABLE sessions(
+    id char(32) primary key,
+    user_id int not null,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    refresh_token_hash char(64) not null unique,
+    expires_at timestamp not null,
+    revoked_at timestamp null,
+    created_at timestamp default current_timestamp
+) ENGINE=INNODB;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN role varchar(20) not null default 'user' AFTER password,
+    ADD COLUMN suspended_at timestamp null AFTER role;
@@ -0,0 +1,23 @@
+CREATE TABLE notifications(
+    id int auto_increment primary key,
+    user_id int not null,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    actor_id int not null,
+    FOREIGN KEY (actor_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    type varchar(20) not null,
+    post_id int null,
+    FOREIGN KEY (post_id)
+    REFERENCES posts(id)
+    ON DELETE CASCADE,
+
+    read_at timestamp null,
+    created_at timestamp default current_timestamp,
+
+    INDEX (user_id, read_at)
+) ENGINE=INNODB;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS conversation_participants;
+DROP TABLE IF EXISTS conversations;
+
+ALTER TABLE users
+    DROP COLUMN messages_from_anyone;
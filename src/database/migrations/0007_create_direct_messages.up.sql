@@ -0,0 +1,55 @@
+ALTER TABLE users
+    ADD COLUMN messages_from_anyone boolean not null default false AFTER suspended_at;
+
+CREATE TABLE conversations(
+    id int auto_increment primary key,
+    user_one_id int not null,
+    FOREIGN KEY (user_one_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    user_two_id int not null,
+    FOREIGN KEY (user_two_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    last_message_id int null,
+    created_at timestamp default current_timestamp,
+
+    UNIQUE (user_one_id, user_two_id)
+) ENGINE=INNODB;
+
+CREATE TABLE conversation_participants(
+    conversation_id int not null,
+    FOREIGN KEY (conversation_id)
+    REFERENCES conversations(id)
+    ON DELETE CASCADE,
+
+    user_id int not null,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    last_read_message_id int null,
+    muted boolean not null default false,
+
+    PRIMARY KEY(conversation_id, user_id)
+) ENGINE=INNODB;
+
+CREATE TABLE messages(
+    id int auto_increment primary key,
+    conversation_id int not null,
+    FOREIGN KEY (conversation_id)
+    REFERENCES conversations(id)
+    ON DELETE CASCADE,
+
+    sender_id int not null,
+    FOREIGN KEY (sender_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    content varchar(1000) not null,
+    created_at timestamp default current_timestamp,
+
+    INDEX (conversation_id, id)
+) ENGINE=INNODB;
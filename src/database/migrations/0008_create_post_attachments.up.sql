@@ -0,0 +1,16 @@
+CREATE TABLE post_attachments(
+    id int auto_increment primary key,
+    post_id int not null,
+    FOREIGN KEY (post_id)
+    REFERENCES posts(id)
+    ON DELETE CASCADE,
+
+    storage_key varchar(255) not null,
+    thumbnail_key varchar(255) not null,
+    content_type varchar(50) not null,
+    width int not null,
+    height int not null,
+    size int not null,
+    alt_text varchar(500) not null default '',
+    created_at timestamp default current_timestamp
+) ENGINE=INNODB;
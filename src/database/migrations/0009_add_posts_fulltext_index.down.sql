@@ -0,0 +1,2 @@
+ALTER TABLE posts
+    DROP INDEX posts_fulltext;
@@ -0,0 +1,13 @@
+CREATE TABLE post_hashtags(
+    post_id int not null,
+    FOREIGN KEY (post_id)
+    REFERENCES posts(id)
+    ON DELETE CASCADE,
+
+    tag varchar(100) not null,
+    created_at timestamp default current_timestamp,
+
+    primary key (post_id, tag),
+    index post_hashtags_tag (tag, post_id),
+    index post_hashtags_created_at (created_at)
+) ENGINE=INNODB;
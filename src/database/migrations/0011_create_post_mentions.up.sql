@@ -0,0 +1,17 @@
+CREATE TABLE post_mentions(
+    post_id int not null,
+    FOREIGN KEY (post_id)
+    REFERENCES posts(id)
+    ON DELETE CASCADE,
+
+    user_id int not null,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    start_offset int not null,
+    end_offset int not null,
+
+    primary key (post_id, start_offset),
+    index post_mentions_user (user_id, post_id)
+) ENGINE=INNODB;
@@ -0,0 +1,19 @@
+ALTER TABLE users
+    ADD COLUMN private boolean not null default false AFTER messages_from_anyone;
+
+CREATE TABLE follow_requests(
+    id int auto_increment primary key,
+    user_id int not null,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    follower_id int not null,
+    FOREIGN KEY (follower_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    created_at timestamp default current_timestamp,
+
+    unique (user_id, follower_id)
+) ENGINE=INNODB;
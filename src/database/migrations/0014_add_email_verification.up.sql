@@ -0,0 +1,18 @@
+ALTER TABLE users
+    ADD COLUMN email_verified_at timestamp null AFTER email;
+
+-- The accounts created before verification existed keep working
+UPDATE users SET email_verified_at = createdAt;
+
+CREATE TABLE email_verifications(
+    token_hash char(64) primary key,
+    user_id int not null,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    email varchar(50) not null,
+    expires_at timestamp not null,
+    used_at timestamp null,
+    created_at timestamp default current_timestamp
+) ENGINE=INNODB;
@@ -0,0 +1,12 @@
+CREATE TABLE password_resets(
+    token_hash char(64) primary key,
+    user_id int not null,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    email varchar(50) not null,
+    expires_at timestamp not null,
+    used_at timestamp null,
+    created_at timestamp default current_timestamp
+) ENGINE=INNODB;
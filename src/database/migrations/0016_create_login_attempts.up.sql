@@ -0,0 +1,17 @@
+CREATE TABLE login_attempts(
+    id int auto_increment primary key,
+    user_id int null,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE SET NULL,
+
+    email varchar(50) not null,
+    ip varchar(45) not null,
+    user_agent varchar(255) not null default '',
+    succeeded boolean not null,
+    reason varchar(20) not null,
+    created_at timestamp default current_timestamp,
+
+    index login_attempts_email (email, created_at),
+    index login_attempts_ip (ip, created_at)
+) ENGINE=INNODB;
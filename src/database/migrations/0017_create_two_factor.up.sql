@@ -0,0 +1,37 @@
+CREATE TABLE two_factor(
+    user_id int primary key,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    secret varchar(64) not null,
+    enabled_at timestamp null,
+    last_step bigint not null default 0,
+    created_at timestamp default current_timestamp
+) ENGINE=INNODB;
+
+CREATE TABLE recovery_codes(
+    id int auto_increment primary key,
+    user_id int not null,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    code_hash char(64) not null,
+    used_at timestamp null,
+
+    unique key recovery_codes_user_code (user_id, code_hash)
+) ENGINE=INNODB;
+
+CREATE TABLE login_challenges(
+    token_hash char(64) primary key,
+    user_id int not null,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    attempts int not null default 0,
+    expires_at timestamp not null,
+    used_at timestamp null,
+    created_at timestamp default current_timestamp
+) ENGINE=INNODB;
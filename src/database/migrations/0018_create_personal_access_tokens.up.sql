@@ -0,0 +1,14 @@
+CREATE TABLE personal_access_tokens(
+    id int auto_increment primary key,
+    user_id int not null,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+
+    name varchar(50) not null,
+    token_hash char(64) not null unique,
+    scopes varchar(255) not null,
+    expires_at timestamp null,
+    last_used_at timestamp null,
+    created_at timestamp default current_timestamp
+) ENGINE=INNODB;
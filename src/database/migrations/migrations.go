@@ -0,0 +1,381 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+//go:embed seeds/*.sql
+var seeds embed.FS
+
+// lockName is the MySQL named lock that keeps two instances from migrating at the same time
+const lockName = "schema_migrations"
+
+// legacySchema finds, for each migration that replaced the old sql/sql.sql script, whether
+// its changes are already in a database that script created, in the order they were made
+var legacySchema = []struct {
+	version uint64
+	query   string
+}{
+	{1, "select count(*) from information_schema.tables where table_schema = database() and table_name = 'users'"},
+	{2, "select count(*) from information_schema.tables where table_schema = database() and table_name = 'post_likes'"},
+	{3, "select count(*) from information_schema.tables where table_schema = database() and table_name = 'comments'"},
+	{4, "select count(*) from information_schema.tables where table_schema = database() and table_name = 'sessions'"},
+	{5, "select count(*) from information_schema.columns where table_schema = database() and table_name = 'users' and column_name = 'role'"},
+}
+
+// Migration represents one numbered change of the schema
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// State represents a migration and whether it was applied to the database
+type State struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Load reads the embedded migrations ordered by version, files are named
+// <version>_<name>.up.sql and <version>_<name>.down.sql
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint64]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+
+		base, direction := "", ""
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			base, direction = strings.TrimSuffix(name, ".up.sql"), "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			base, direction = strings.TrimSuffix(name, ".down.sql"), "down"
+		default:
+			continue
+		}
+
+		prefix, description, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("migration %s must be named <version>_<name>", name)
+		}
+
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has an invalid version: %w", name, err)
+		}
+
+		content, err := fs.ReadFile(files, name)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: description}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != description {
+			return nil, fmt.Errorf("migration version %d is used by %s and %s", version, migration.Name, description)
+		}
+
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	var migrations []Migration
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every pending migration and returns the ones it applied
+func Up(db *sql.DB) ([]Migration, error) {
+	var applied []Migration
+
+	err := withLock(db, func(conn *sql.Conn) error {
+		states, err := status(conn)
+		if err != nil {
+			return err
+		}
+
+		if states, err = adoptLegacySchema(conn, states); err != nil {
+			return err
+		}
+
+		for _, state := range states {
+			if state.AppliedAt != nil {
+				continue
+			}
+
+			if err = execScript(conn, state.Up); err != nil {
+				return fmt.Errorf("applying migration %d_%s: %w", state.Version, state.Name, err)
+			}
+
+			if _, err = conn.ExecContext(context.Background(),
+				"insert into schema_migrations (version, name) values (?, ?)", state.Version, state.Name,
+			); err != nil {
+				return err
+			}
+
+			applied = append(applied, state.Migration)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations and returns the ones it reverted
+func Down(db *sql.DB, steps int) ([]Migration, error) {
+	var reverted []Migration
+
+	err := withLock(db, func(conn *sql.Conn) error {
+		states, err := status(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(states) - 1; i >= 0 && len(reverted) < steps; i-- {
+			state := states[i]
+			if state.AppliedAt == nil {
+				continue
+			}
+
+			if err = execScript(conn, state.Down); err != nil {
+				return fmt.Errorf("reverting migration %d_%s: %w", state.Version, state.Name, err)
+			}
+
+			if _, err = conn.ExecContext(context.Background(),
+				"delete from schema_migrations where version = ?", state.Version,
+			); err != nil {
+				return err
+			}
+
+			reverted = append(reverted, state.Migration)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status returns every known migration and when it was applied
+func Status(db *sql.DB) ([]State, error) {
+	var states []State
+
+	err := withLock(db, func(conn *sql.Conn) error {
+		var err error
+		states, err = status(conn)
+		return err
+	})
+
+	return states, err
+}
+
+// Seed inserts the sample data, running it more than once does not duplicate rows
+func Seed(db *sql.DB) error {
+	entries, err := fs.ReadDir(seeds, "seeds")
+	if err != nil {
+		return err
+	}
+
+	return withLock(db, func(conn *sql.Conn) error {
+		for _, entry := range entries {
+			content, err := fs.ReadFile(seeds, path.Join("seeds", entry.Name()))
+			if err != nil {
+				return err
+			}
+
+			if err = execScript(conn, string(content)); err != nil {
+				return fmt.Errorf("running seed %s: %w", entry.Name(), err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// adoptLegacySchema records as applied the migrations a database created by the old
+// sql/sql.sql script already has, it only looks at databases without any applied migration
+func adoptLegacySchema(conn *sql.Conn, states []State) ([]State, error) {
+	ctx := context.Background()
+
+	for _, state := range states {
+		if state.AppliedAt != nil {
+			return states, nil
+		}
+	}
+
+	adopted := 0
+	for _, legacy := range legacySchema {
+		var found int
+		if err := conn.QueryRowContext(ctx, legacy.query).Scan(&found); err != nil {
+			return nil, err
+		}
+
+		if found == 0 {
+			break
+		}
+
+		for _, state := range states {
+			if state.Version != legacy.version {
+				continue
+			}
+
+			if _, err := conn.ExecContext(ctx,
+				"insert into schema_migrations (version, name) values (?, ?)", state.Version, state.Name,
+			); err != nil {
+				return nil, err
+			}
+			adopted++
+		}
+	}
+
+	if adopted == 0 {
+		return states, nil
+	}
+
+	return status(conn)
+}
+
+func status(conn *sql.Conn) ([]State, error) {
+	ctx := context.Background()
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations(
+    version bigint unsigned primary key,
+    name varchar(255) not null,
+    applied_at timestamp default current_timestamp
+) ENGINE=INNODB`); err != nil {
+		return nil, err
+	}
+
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, "select version, applied_at from schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[uint64]time.Time{}
+	for rows.Next() {
+		var version uint64
+		var appliedAt time.Time
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = appliedAt
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	states := make([]State, 0, len(migrations))
+	for _, migration := range migrations {
+		state := State{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			state.AppliedAt = &appliedAt
+		}
+
+		states = append(states, state)
+	}
+
+	return states, nil
+}
+
+// withLock runs the function on a single connection holding the migrations lock
+func withLock(db *sql.DB, function func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err = conn.QueryRowContext(ctx, "select get_lock(?, 60)", lockName).Scan(&locked); err != nil {
+		return err
+	}
+
+	if locked.Int64 != 1 {
+		return errors.New("timed out waiting for another instance to finish migrating")
+	}
+	defer conn.ExecContext(ctx, "select release_lock(?)", lockName)
+
+	return function(conn)
+}
+
+// execScript runs every statement of the script, statements end with a semicolon at the end of a line
+func execScript(conn *sql.Conn, script string) error {
+	for _, statement := range splitStatements(script) {
+		if _, err := conn.ExecContext(context.Background(), statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(script, "\n") {
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			statement := strings.TrimSuffix(strings.TrimSpace(current.String()), ";")
+			if statement != "" {
+				statements = append(statements, statement)
+			}
+			current.Reset()
+		}
+	}
+
+	if statement := strings.TrimSpace(current.String()); statement != "" {
+		statements = append(statements, statement)
+	}
+
+	return statements
+}
@@ -0,0 +1,15 @@
+INSERT IGNORE INTO users (name, nick, email, email_verified_at, password)
+VALUES
+("user 1", "user_1", "user1@gmail.com", now(), "$2a$10$BpvIAF83paKczAQjjwBH8Om1h/0Wrk3arODnlQTHm8lly./IqRaIa"),
+("user 2", "user_2", "user2@gmail.com", now(), "$2a$10$BpvIAF83paKczAQjjwBH8Om1h/0Wrk3arODnlQTHm8lly./IqRaIa"),
+("user 3", "user_3", "user3@gmail.com", now(), "$2a$10$BpvIAF83paKczAQjjwBH8Om1h/0Wrk3arODnlQTHm8lly./IqRaIa");
+
+INSERT IGNORE INTO followers (user_id, follower_id)
+SELECT u.id, f.id FROM users u, users f
+WHERE (u.nick, f.nick) IN (("user_1", "user_2"), ("user_3", "user_1"), ("user_1", "user_3"));
+
+INSERT INTO posts (title, content, authorId)
+SELECT CONCAT("User ", SUBSTRING(u.nick, 6), " post"), CONCAT("This is the post of the user ", SUBSTRING(u.nick, 6), "!"), u.id
+FROM users u
+WHERE u.nick IN ("user_1", "user_2", "user_3")
+AND NOT EXISTS (SELECT 1 FROM posts p WHERE p.authorId = u.id);
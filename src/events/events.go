@@ -0,0 +1,60 @@
+// Package events delivers real-time events to the connected clients of the users they are addressed to
+package events
+
+// Types of the events pushed to clients
+const (
+	PostCreated     = "post.created"
+	PostLiked       = "post.liked"
+	UserFollowed    = "user.followed"
+	MessageReceived = "message.received"
+)
+
+// Event represents something that happened and the users who should hear about it
+type Event struct {
+	// ID is assigned by the broker, clients send the last one they saw when they reconnect
+	ID         uint64
+	Type       string
+	Recipients []uint64
+	Data       any
+}
+
+// Broker fans events out to the subscriptions of their recipients, the
+// in-process Hub is enough for a single instance and a broker backed by a
+// message queue can take its place when the API runs on several instances
+type Broker interface {
+	Publish(event Event)
+	// Subscribe starts delivering the events addressed to the user, the ones
+	// published after lastEventID that are still retained are replayed first
+	Subscribe(userID, lastEventID uint64) *Subscription
+	// Close ends every subscription, it is called when the server shuts down
+	Close()
+}
+
+// Subscription represents a connected client
+type Subscription struct {
+	// Replay holds the events the client missed while it was disconnected
+	Replay []Event
+	// Events is closed when the client falls too far behind or the broker closes,
+	// the client is expected to reconnect with the ID of the last event it received
+	Events <-chan Event
+
+	userID uint64
+	events chan Event
+	cancel func(subscription *Subscription)
+}
+
+// Close stops the delivery of events to the subscription
+func (subscription *Subscription) Close() {
+	subscription.cancel(subscription)
+}
+
+// PostLike is the data of a PostLiked event
+type PostLike struct {
+	PostID uint64 `json:"postId"`
+	UserID uint64 `json:"userId"`
+}
+
+// Follow is the data of a UserFollowed event
+type Follow struct {
+	FollowerID uint64 `json:"followerId"`
+}
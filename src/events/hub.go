@@ -0,0 +1,130 @@
+package events
+
+import (
+	"slices"
+	"sync"
+)
+
+const (
+	// DefaultHistory is how many events the hub keeps to replay to reconnecting clients
+	DefaultHistory = 1000
+	// subscriptionBuffer is how many events a client can fall behind before it is disconnected
+	subscriptionBuffer = 64
+)
+
+// Hub is the in-process Broker, it keeps the most recent events in memory so
+// that clients reconnecting to the same instance do not miss any
+type Hub struct {
+	mu            sync.Mutex
+	lastID        uint64
+	history       []Event
+	historySize   int
+	subscriptions map[uint64]map[*Subscription]struct{}
+	closed        bool
+}
+
+var _ Broker = (*Hub)(nil)
+
+// NewHub creates a hub that retains the last historySize events
+func NewHub(historySize int) *Hub {
+	return &Hub{
+		historySize:   historySize,
+		subscriptions: map[uint64]map[*Subscription]struct{}{},
+	}
+}
+
+// Publish assigns the next ID to the event and sends it to the subscriptions of
+// its recipients, subscriptions that cannot keep up are closed instead of blocking
+func (hub *Hub) Publish(event Event) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	if hub.closed || len(event.Recipients) == 0 {
+		return
+	}
+
+	hub.lastID++
+	event.ID = hub.lastID
+
+	hub.history = append(hub.history, event)
+	if len(hub.history) > hub.historySize {
+		hub.history = hub.history[len(hub.history)-hub.historySize:]
+	}
+
+	for _, userID := range event.Recipients {
+		for subscription := range hub.subscriptions[userID] {
+			select {
+			case subscription.events <- event:
+			default:
+				hub.remove(subscription)
+			}
+		}
+	}
+}
+
+func (hub *Hub) Subscribe(userID, lastEventID uint64) *Subscription {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	events := make(chan Event, subscriptionBuffer)
+	subscription := &Subscription{
+		Events: events,
+		userID: userID,
+		events: events,
+		cancel: hub.unsubscribe,
+	}
+
+	if hub.closed {
+		close(events)
+		return subscription
+	}
+
+	if lastEventID > 0 {
+		for _, event := range hub.history {
+			if event.ID > lastEventID && slices.Contains(event.Recipients, userID) {
+				subscription.Replay = append(subscription.Replay, event)
+			}
+		}
+	}
+
+	if hub.subscriptions[userID] == nil {
+		hub.subscriptions[userID] = map[*Subscription]struct{}{}
+	}
+	hub.subscriptions[userID][subscription] = struct{}{}
+
+	return subscription
+}
+
+func (hub *Hub) Close() {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.closed = true
+	for _, subscriptions := range hub.subscriptions {
+		for subscription := range subscriptions {
+			hub.remove(subscription)
+		}
+	}
+}
+
+func (hub *Hub) unsubscribe(subscription *Subscription) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.remove(subscription)
+}
+
+// remove closes the subscription if it is still registered, the lock must be held
+func (hub *Hub) remove(subscription *Subscription) {
+	subscriptions := hub.subscriptions[subscription.userID]
+	if _, ok := subscriptions[subscription]; !ok {
+		return
+	}
+
+	delete(subscriptions, subscription)
+	if len(subscriptions) == 0 {
+		delete(hub.subscriptions, subscription.userID)
+	}
+
+	close(subscription.events)
+}
@@ -0,0 +1,78 @@
+package events
+
+import "testing"
+
+func TestHubDeliversToRecipients(t *testing.T) {
+	hub := NewHub(DefaultHistory)
+	alice := hub.Subscribe(1, 0)
+	bob := hub.Subscribe(2, 0)
+	defer alice.Close()
+	defer bob.Close()
+
+	hub.Publish(Event{Type: UserFollowed, Recipients: []uint64{1}})
+
+	if event := <-alice.Events; event.ID != 1 || event.Type != UserFollowed {
+		t.Fatalf("alice received %+v", event)
+	}
+
+	select {
+	case event := <-bob.Events:
+		t.Fatalf("bob received %+v", event)
+	default:
+	}
+}
+
+func TestHubReplaysMissedEvents(t *testing.T) {
+	hub := NewHub(2)
+	for i := 0; i < 3; i++ {
+		hub.Publish(Event{Type: PostCreated, Recipients: []uint64{1}})
+	}
+	hub.Publish(Event{Type: PostCreated, Recipients: []uint64{2}})
+
+	subscription := hub.Subscribe(1, 1)
+	defer subscription.Close()
+
+	// The first event of the user fell out of the history and the last one is not theirs
+	if len(subscription.Replay) != 1 || subscription.Replay[0].ID != 3 {
+		t.Fatalf("replayed %+v", subscription.Replay)
+	}
+
+	if fresh := hub.Subscribe(1, 0); len(fresh.Replay) != 0 {
+		t.Fatalf("a first connection replayed %+v", fresh.Replay)
+	}
+}
+
+func TestHubDropsSlowSubscriptions(t *testing.T) {
+	hub := NewHub(DefaultHistory)
+	subscription := hub.Subscribe(1, 0)
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		hub.Publish(Event{Type: PostCreated, Recipients: []uint64{1}})
+	}
+
+	received := 0
+	for range subscription.Events {
+		received++
+	}
+
+	if received != subscriptionBuffer {
+		t.Fatalf("received %d events before being dropped, want %d", received, subscriptionBuffer)
+	}
+
+	// Closing a dropped subscription must not close its channel twice
+	subscription.Close()
+}
+
+func TestHubCloseEndsSubscriptions(t *testing.T) {
+	hub := NewHub(DefaultHistory)
+	subscription := hub.Subscribe(1, 0)
+	hub.Close()
+
+	if _, open := <-subscription.Events; open {
+		t.Fatal("the subscription is still open")
+	}
+
+	if _, open := <-hub.Subscribe(1, 0).Events; open {
+		t.Fatal("a closed hub accepted a subscription")
+	}
+}
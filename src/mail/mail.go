@@ -0,0 +1,56 @@
+// Package mail sends the emails of the API, like the address confirmations
+package mail
+
+import (
+	"api/src/config"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Message represents a plain text email to a single recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer is implemented by the backends that deliver emails
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// FromConfig creates the backend chosen by the MAIL_BACKEND setting
+func FromConfig() (Mailer, error) {
+	switch config.MailBackend {
+	case "outbox":
+		return NewOutbox(config.MailOutboxDir), nil
+
+	case "smtp":
+		if config.SMTPHost == "" || config.MailFrom == "" {
+			return nil, errors.New("SMTP_HOST and MAIL_FROM are required by the smtp mail backend")
+		}
+
+		return NewSMTP(SMTPConfig{
+			Host:     config.SMTPHost,
+			Port:     config.SMTPPort,
+			Username: config.SMTPUsername,
+			Password: config.SMTPPassword,
+			From:     config.MailFrom,
+		}), nil
+	}
+
+	return nil, fmt.Errorf("unknown mail backend %q", config.MailBackend)
+}
+
+// checkHeaders rejects the values that would let a recipient or subject inject more headers
+func checkHeaders(values ...string) error {
+	for _, value := range values {
+		if strings.ContainsAny(value, "\r\n") {
+			return errors.New("email headers cannot contain line breaks")
+		}
+	}
+
+	return nil
+}
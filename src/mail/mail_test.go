@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompose(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	message := compose("api@example.com", Message{
+		To:      "alice@example.com",
+		Subject: "Confirmação",
+		Body:    "Hello\nWorld",
+	}, date)
+
+	want := "From: api@example.com\r\n" +
+		"To: alice@example.com\r\n" +
+		"Subject: =?utf-8?q?Confirma=C3=A7=C3=A3o?=\r\n" +
+		"Date: Tue, 02 Jan 2024 03:04:05 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"Content-Transfer-Encoding: 8bit\r\n\r\n" +
+		"Hello\r\nWorld"
+	if string(message) != want {
+		t.Fatalf("composed\n%q\nwant\n%q", message, want)
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	dir := t.TempDir()
+	outbox := NewOutbox(dir)
+
+	if err := outbox.Send(context.Background(), Message{To: "alice@example.com", Subject: "Hi", Body: "Hello"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := outbox.Send(context.Background(), Message{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "Hi"})
+	if err == nil {
+		t.Fatal("a recipient with a line break was accepted")
+	}
+
+	if messages := outbox.Messages(); len(messages) != 1 || messages[0].Body != "Hello" {
+		t.Fatalf("outbox has %+v", messages)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), ".eml") {
+		t.Fatalf("outbox directory has %v (%v)", entries, err)
+	}
+}
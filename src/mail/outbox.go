@@ -0,0 +1,58 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Outbox keeps the emails instead of delivering them, each one is written to
+// a file of its directory. It is meant for tests and local runs
+type Outbox struct {
+	dir string
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+var _ Mailer = (*Outbox)(nil)
+
+// NewOutbox creates an outbox that writes the emails to dir, nothing is written when dir is empty
+func NewOutbox(dir string) *Outbox {
+	return &Outbox{dir: dir}
+}
+
+func (outbox *Outbox) Send(ctx context.Context, message Message) error {
+	if err := checkHeaders(message.To, message.Subject); err != nil {
+		return err
+	}
+
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+
+	if outbox.dir != "" {
+		if err := os.MkdirAll(outbox.dir, 0o755); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		name := fmt.Sprintf("%s-%03d.eml", now.Format("20060102T150405.000000000"), len(outbox.messages)+1)
+		if err := os.WriteFile(filepath.Join(outbox.dir, name), compose("outbox", message, now), 0o644); err != nil {
+			return err
+		}
+	}
+
+	outbox.messages = append(outbox.messages, message)
+	return nil
+}
+
+// Messages returns the emails sent so far, oldest first
+func (outbox *Outbox) Messages() []Message {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+
+	return append([]Message(nil), outbox.messages...)
+}
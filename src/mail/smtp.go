@@ -0,0 +1,112 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPConfig holds the settings of the SMTP server the emails are relayed through
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SMTP delivers the emails through an SMTP server, upgrading the connection
+// with STARTTLS whenever the server offers it
+type SMTP struct {
+	config SMTPConfig
+}
+
+var _ Mailer = (*SMTP)(nil)
+
+// NewSMTP creates an SMTP backend
+func NewSMTP(config SMTPConfig) *SMTP {
+	return &SMTP{config}
+}
+
+func (mailer *SMTP) Send(ctx context.Context, message Message) error {
+	if err := checkHeaders(message.To, message.Subject); err != nil {
+		return err
+	}
+
+	address := net.JoinHostPort(mailer.config.Host, strconv.Itoa(mailer.config.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, mailer.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: mailer.config.Host}); err != nil {
+			return err
+		}
+	}
+
+	if mailer.config.Username != "" {
+		auth := smtp.PlainAuth("", mailer.config.Username, mailer.config.Password, mailer.config.Host)
+		if err = client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(mailer.config.From); err != nil {
+		return err
+	}
+
+	if err = client.Rcpt(message.To); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err = writer.Write(compose(mailer.config.From, message, time.Now())); err != nil {
+		return err
+	}
+
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// compose builds the message in the internet message format, with CRLF line endings
+func compose(from string, message Message, date time.Time) []byte {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "From: %s\r\n", from)
+	fmt.Fprintf(&buffer, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buffer, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buffer, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buffer.WriteString("MIME-Version: 1.0\r\n")
+	buffer.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buffer.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+
+	body := strings.ReplaceAll(message.Body, "\r\n", "\n")
+	buffer.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return buffer.Bytes()
+}
@@ -0,0 +1,81 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var errInvalidGIF = errors.New("invalid GIF")
+
+// gifFramePixels adds up the areas of the frames of the GIF file by walking its blocks without
+// decompressing them, gif.DecodeAll allocates every frame so they all count towards MaxPixels
+func gifFramePixels(data []byte) (int, error) {
+	// Header and logical screen descriptor
+	if len(data) < 13 {
+		return 0, errInvalidGIF
+	}
+
+	offset := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		offset += 3 << (flags&0x07 + 1)
+	}
+
+	var pixels int
+	for offset < len(data) {
+		switch data[offset] {
+		case 0x21: // Extension: label, then sub-blocks
+			var err error
+			if offset, err = skipSubBlocks(data, offset+2); err != nil {
+				return 0, err
+			}
+
+		case 0x2C: // Image descriptor: position, size, flags, then the LZW code size and sub-blocks
+			if offset+10 > len(data) {
+				return 0, errInvalidGIF
+			}
+
+			width := int(binary.LittleEndian.Uint16(data[offset+5:]))
+			height := int(binary.LittleEndian.Uint16(data[offset+7:]))
+			pixels += width * height
+			if pixels > MaxPixels {
+				return pixels, nil
+			}
+
+			flags := data[offset+9]
+			offset += 10
+			if flags&0x80 != 0 {
+				offset += 3 << (flags&0x07 + 1)
+			}
+
+			var err error
+			if offset, err = skipSubBlocks(data, offset+1); err != nil {
+				return 0, err
+			}
+
+		case 0x3B: // Trailer
+			return pixels, nil
+
+		default:
+			return 0, errInvalidGIF
+		}
+	}
+
+	return pixels, nil
+}
+
+// skipSubBlocks returns the offset after the sub-blocks starting at offset and their terminator
+func skipSubBlocks(data []byte, offset int) (int, error) {
+	for {
+		if offset >= len(data) {
+			return 0, errInvalidGIF
+		}
+
+		size := int(data[offset])
+		offset++
+		if size == 0 {
+			return offset, nil
+		}
+
+		offset += size
+	}
+}
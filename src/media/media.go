@@ -0,0 +1,190 @@
+// Package media validates the images users upload and prepares them to be
+// stored: metadata is dropped by re-encoding and thumbnails are generated
+package media
+
+import (
+	"api/src/models"
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	// MaxSize is the largest image accepted, in bytes
+	MaxSize = 5 << 20
+	// MaxPixels bounds the decoded size so small files cannot expand into huge images
+	MaxPixels = 40_000_000
+	// ThumbnailSize is the longest side of the thumbnails, in pixels
+	ThumbnailSize = 320
+)
+
+// ErrTooLarge is returned for images bigger than MaxSize
+var ErrTooLarge = errors.New("The image cannot be larger than 5 MB")
+
+// Image represents a processed upload, ready to be stored
+type Image struct {
+	ContentType string
+	Extension   string
+	Data        []byte
+	Width       int
+	Height      int
+
+	ThumbnailContentType string
+	ThumbnailExtension   string
+	Thumbnail            []byte
+}
+
+// Process validates the upload, drops its metadata, such as the EXIF block of
+// photos, by decoding and encoding it again and generates its thumbnail
+func Process(data []byte) (Image, error) {
+	if len(data) > MaxSize {
+		return Image{}, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if contentType != "image/jpeg" && contentType != "image/png" && contentType != "image/gif" {
+		return Image{}, invalidImage("The image must be a JPEG, PNG or GIF file")
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, invalidImage("The image could not be read")
+	}
+
+	if config.Width*config.Height > MaxPixels {
+		return Image{}, invalidImage("The image cannot have more than 40 megapixels")
+	}
+
+	processed := Image{ContentType: contentType}
+	var picture image.Image
+	var encoded bytes.Buffer
+
+	switch contentType {
+	case "image/jpeg":
+		if picture, err = jpeg.Decode(bytes.NewReader(data)); err != nil {
+			return Image{}, invalidImage("The image could not be read")
+		}
+
+		// The orientation lives in the EXIF block that is about to be dropped
+		picture = orient(picture, jpegOrientation(data))
+		err = jpeg.Encode(&encoded, picture, &jpeg.Options{Quality: 90})
+		processed.Extension = ".jpg"
+
+	case "image/png":
+		if picture, err = png.Decode(bytes.NewReader(data)); err != nil {
+			return Image{}, invalidImage("The image could not be read")
+		}
+
+		err = png.Encode(&encoded, picture)
+		processed.Extension = ".png"
+
+	case "image/gif":
+		pixels, scanErr := gifFramePixels(data)
+		if scanErr != nil {
+			return Image{}, invalidImage("The image could not be read")
+		}
+
+		if pixels > MaxPixels {
+			return Image{}, invalidImage("The frames of the animation cannot add up to more than 40 megapixels")
+		}
+
+		animation, decodeErr := gif.DecodeAll(bytes.NewReader(data))
+		if decodeErr != nil {
+			return Image{}, invalidImage("The image could not be read")
+		}
+
+		picture = firstFrame(animation)
+		err = gif.EncodeAll(&encoded, animation)
+		processed.Extension = ".gif"
+	}
+	if err != nil {
+		return Image{}, err
+	}
+
+	processed.Data = encoded.Bytes()
+	processed.Width = picture.Bounds().Dx()
+	processed.Height = picture.Bounds().Dy()
+
+	thumbnail := Thumbnail(picture, ThumbnailSize)
+	encoded = bytes.Buffer{}
+
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&encoded, thumbnail, &jpeg.Options{Quality: 80})
+		processed.ThumbnailContentType, processed.ThumbnailExtension = "image/jpeg", ".jpg"
+	} else {
+		err = png.Encode(&encoded, thumbnail)
+		processed.ThumbnailContentType, processed.ThumbnailExtension = "image/png", ".png"
+	}
+	if err != nil {
+		return Image{}, err
+	}
+
+	processed.Thumbnail = encoded.Bytes()
+	return processed, nil
+}
+
+// Thumbnail scales the picture down so that its longest side is at most size
+// pixels, each pixel is the average of the area of the source it covers
+func Thumbnail(picture image.Image, size int) image.Image {
+	bounds := picture.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	targetWidth, targetHeight := width, height
+	if width > size || height > size {
+		if width >= height {
+			targetWidth, targetHeight = size, max(1, height*size/width)
+		} else {
+			targetWidth, targetHeight = max(1, width*size/height), size
+		}
+	}
+
+	source := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(source, source.Bounds(), picture, bounds.Min, draw.Src)
+
+	thumbnail := image.NewRGBA(image.Rect(0, 0, targetWidth, targetHeight))
+	for y := 0; y < targetHeight; y++ {
+		top, bottom := y*height/targetHeight, max((y+1)*height/targetHeight, y*height/targetHeight+1)
+
+		for x := 0; x < targetWidth; x++ {
+			left, right := x*width/targetWidth, max((x+1)*width/targetWidth, x*width/targetWidth+1)
+
+			var r, g, b, a, count int
+			for sy := top; sy < bottom; sy++ {
+				row := source.Pix[sy*source.Stride:]
+				for sx := left; sx < right; sx++ {
+					pixel := row[sx*4 : sx*4+4]
+					r, g, b, a = r+int(pixel[0]), g+int(pixel[1]), b+int(pixel[2]), a+int(pixel[3])
+					count++
+				}
+			}
+
+			offset := y*thumbnail.Stride + x*4
+			thumbnail.Pix[offset] = uint8(r / count)
+			thumbnail.Pix[offset+1] = uint8(g / count)
+			thumbnail.Pix[offset+2] = uint8(b / count)
+			thumbnail.Pix[offset+3] = uint8(a / count)
+		}
+	}
+
+	return thumbnail
+}
+
+// firstFrame draws the first frame of the animation on a canvas of its full size
+func firstFrame(animation *gif.GIF) image.Image {
+	canvas := image.NewRGBA(image.Rect(0, 0, animation.Config.Width, animation.Config.Height))
+	if len(animation.Image) > 0 {
+		frame := animation.Image[0]
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+	}
+
+	return canvas
+}
+
+func invalidImage(message string) error {
+	return &models.FieldError{Field: "image", Message: message}
+}
@@ -0,0 +1,180 @@
+package media
+
+import (
+	"api/src/models"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encode(t *testing.T, picture image.Image, format string) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	var err error
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buffer, picture, nil)
+	case "png":
+		err = png.Encode(&buffer, picture)
+	case "gif":
+		err = gif.Encode(&buffer, picture, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+}
+
+// withExif inserts an EXIF segment holding the orientation and a fake GPS note right after the start of the JPEG
+func withExif(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, exifOrientationTag)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	tiff = append(tiff, "GPS 22.9068 S 43.1729 W"...)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	header := binary.BigEndian.AppendUint16([]byte{0xFF, 0xE1}, uint16(len(segment)+2))
+
+	result := append([]byte{}, data[:2]...)
+	result = append(result, header...)
+	result = append(result, segment...)
+	return append(result, data[2:]...)
+}
+
+func TestProcessStripsExifAndKeepsOrientation(t *testing.T) {
+	picture := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	data := withExif(encode(t, picture, "jpeg"), 6)
+
+	if jpegOrientation(data) != 6 {
+		t.Fatalf("read orientation %d", jpegOrientation(data))
+	}
+
+	processed, err := Process(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(processed.Data, []byte("Exif")) || bytes.Contains(processed.Data, []byte("GPS")) {
+		t.Fatal("the metadata survived")
+	}
+
+	if processed.ContentType != "image/jpeg" || processed.Width != 20 || processed.Height != 40 {
+		t.Fatalf("got a %s of %dx%d", processed.ContentType, processed.Width, processed.Height)
+	}
+}
+
+func TestProcessGeneratesThumbnails(t *testing.T) {
+	picture := image.NewNRGBA(image.Rect(0, 0, 1000, 500))
+	for y := 0; y < 500; y++ {
+		for x := 0; x < 1000; x++ {
+			picture.Set(x, y, color.NRGBA{R: 200, A: 255})
+		}
+	}
+
+	processed, err := Process(encode(t, picture, "png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	thumbnail, err := png.Decode(bytes.NewReader(processed.Thumbnail))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := thumbnail.Bounds().Size(); size.X != ThumbnailSize || size.Y != ThumbnailSize/2 {
+		t.Fatalf("the thumbnail is %v", size)
+	}
+
+	if r, _, _, a := thumbnail.At(10, 10).RGBA(); r>>8 != 200 || a>>8 != 255 {
+		t.Fatalf("the thumbnail color is %v", thumbnail.At(10, 10))
+	}
+
+	small, err := Process(encode(t, image.NewGray(image.Rect(0, 0, 30, 10)), "gif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if small.Extension != ".gif" || small.ThumbnailContentType != "image/png" {
+		t.Fatalf("a GIF was processed as %+v", small)
+	}
+}
+
+func TestProcessRejectsInvalidUploads(t *testing.T) {
+	var fieldError *models.FieldError
+	if _, err := Process([]byte("<svg></svg>")); !errors.As(err, &fieldError) || fieldError.Field != "image" {
+		t.Fatalf("an SVG was not rejected: %v", err)
+	}
+
+	if _, err := Process(make([]byte, MaxSize+1)); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("a large upload was not rejected: %v", err)
+	}
+
+	truncated := encode(t, image.NewRGBA(image.Rect(0, 0, 10, 10)), "png")[:40]
+	if _, err := Process(truncated); !errors.As(err, &fieldError) {
+		t.Fatalf("a truncated image was not rejected: %v", err)
+	}
+}
+
+func TestProcessRejectsGIFFrameBombs(t *testing.T) {
+	// Each frame is a quarter of the limit and compresses to a few kilobytes
+	palette := color.Palette{color.Black, color.White}
+	frame := image.NewPaletted(image.Rect(0, 0, 5000, 2000), palette)
+
+	animation := &gif.GIF{}
+	for i := 0; i < 5; i++ {
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, 10)
+	}
+
+	var buffer bytes.Buffer
+	if err := gif.EncodeAll(&buffer, animation); err != nil {
+		t.Fatal(err)
+	}
+
+	var fieldError *models.FieldError
+	if _, err := Process(buffer.Bytes()); !errors.As(err, &fieldError) || fieldError.Field != "image" {
+		t.Fatalf("a %d bytes GIF of 50 megapixels was not rejected: %v", buffer.Len(), err)
+	}
+
+	pixels, err := gifFramePixels(buffer.Bytes())
+	if err != nil || pixels <= MaxPixels {
+		t.Fatalf("counted %d pixels: %v", pixels, err)
+	}
+
+	animation.Image, animation.Delay = animation.Image[:2], animation.Delay[:2]
+	buffer.Reset()
+	if err = gif.EncodeAll(&buffer, animation); err != nil {
+		t.Fatal(err)
+	}
+
+	if pixels, err = gifFramePixels(buffer.Bytes()); err != nil || pixels != 20_000_000 {
+		t.Fatalf("counted %d pixels in two frames: %v", pixels, err)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	picture := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	picture.Set(0, 0, color.RGBA{R: 255, A: 255})
+
+	// Where the top left pixel ends up for each orientation
+	for orientation, want := range map[int]image.Point{
+		2: {2, 0}, 3: {2, 1}, 4: {0, 1}, 5: {0, 0}, 6: {1, 0}, 7: {1, 2}, 8: {0, 2},
+	} {
+		upright := orient(picture, orientation)
+		if r, _, _, _ := upright.At(want.X, want.Y).RGBA(); r == 0 {
+			t.Errorf("orientation %d did not move the top left pixel to %v", orientation, want)
+		}
+	}
+}
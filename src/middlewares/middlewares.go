@@ -2,11 +2,35 @@ package middlewares
 
 import (
 	"api/src/authentication"
+	"api/src/config"
+	"api/src/models"
+	"api/src/ratelimit"
 	"api/src/responses"
+	"api/src/security"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
+// SessionStore tells whether the session a token was issued for can still be used
+type SessionStore interface {
+	IsActive(sessionID string) (bool, error)
+}
+
+// AccessTokenStore finds the personal access token a request was sent with
+type AccessTokenStore interface {
+	Use(tokenHash string) (models.AccessToken, bool, error)
+}
+
+// VerificationStore tells whether a user confirmed their email address
+type VerificationStore interface {
+	IsEmailVerified(userID uint64) (bool, error)
+}
+
 // Logger writes request information to the terminal
 func Logger(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -16,12 +40,166 @@ func Logger(next http.HandlerFunc) http.HandlerFunc {
 }
 
 // Authenticate checks whether the user making the request is authenticated
-func Authenticate(next http.HandlerFunc) http.HandlerFunc {
+// and that the session behind the token was not revoked, the principal is
+// then stored in the request context for the handlers. Personal access tokens
+// are accepted as well when they carry the scopes of the route
+func Authenticate(next http.HandlerFunc, sessions SessionStore, tokens AccessTokenStore, scopes []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token := authentication.ExtractAccessToken(r); token != "" {
+			authenticateAccessToken(next, tokens, scopes, token)(w, r)
+			return
+		}
+
+		principal, err := authentication.ParseToken(r)
+		if err != nil {
+			responses.Error(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		active, err := sessions.IsActive(principal.SessionID)
+		if err != nil {
+			responses.Error(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !active {
+			responses.Error(w, http.StatusUnauthorized, errors.New("the session has ended, log in again"))
+			return
+		}
+
+		next(w, r.WithContext(authentication.WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// authenticateAccessToken authenticates the request with a personal access token, which acts as its
+// owner but only on the routes that need no more than its scopes
+func authenticateAccessToken(next http.HandlerFunc, tokens AccessTokenStore, scopes []string, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken, valid, err := tokens.Use(security.HashToken(token))
+		if err != nil {
+			responses.Error(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !valid {
+			responses.Error(w, http.StatusUnauthorized, errors.New("the access token is invalid or has expired"))
+			return
+		}
+
+		principal := authentication.Principal{
+			UserID:        accessToken.UserID,
+			Role:          accessToken.UserRole,
+			AccessTokenID: accessToken.ID,
+			Scopes:        accessToken.Scopes,
+		}
+
+		if !principal.HasScopes(scopes...) {
+			if len(scopes) == 0 {
+				responses.Error(w, http.StatusForbidden, errors.New("this resource cannot be used with an access token"))
+				return
+			}
+
+			responses.Error(w, http.StatusForbidden, fmt.Errorf("the access token needs the scopes %s", strings.Join(scopes, ", ")))
+			return
+		}
+
+		next(w, r.WithContext(authentication.WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// Authorize checks whether the authenticated user has one of the roles the route requires,
+// it must run after Authenticate
+func Authorize(next http.HandlerFunc, roles []string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if err := authentication.ValidateToken(r); err != nil {
+		principal, err := authentication.ExtractPrincipal(r)
+		if err != nil {
 			responses.Error(w, http.StatusUnauthorized, err)
 			return
 		}
+
+		if !principal.HasRole(roles...) {
+			responses.Error(w, http.StatusForbidden, errors.New("you do not have permission to access this resource"))
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// RequireVerifiedEmail keeps the users who did not confirm their email address
+// out of the route, it must run after Authenticate
+func RequireVerifiedEmail(next http.HandlerFunc, users VerificationStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := authentication.ExtractUserID(r)
+		if err != nil {
+			responses.Error(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		verified, err := users.IsEmailVerified(userID)
+		if err != nil {
+			responses.Error(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !verified {
+			responses.Error(w, http.StatusForbidden, errors.New("confirm your email address before doing this"))
+			return
+		}
+
 		next(w, r)
 	}
 }
+
+// RateLimit throttles the route with a token bucket per client, authenticated
+// requests are counted per user and anonymous ones per IP address. It must run
+// after Authenticate on the routes that require authentication
+func RateLimit(next http.HandlerFunc, store ratelimit.Store, route string, limit ratelimit.Limit) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := "ip:" + ClientIP(r)
+		if principal, ok := authentication.PrincipalFromContext(r.Context()); ok {
+			client = fmt.Sprintf("user:%d", principal.UserID)
+		}
+
+		result, err := store.Take(r.Context(), route+" "+client, limit)
+		if err != nil {
+			// An unavailable store must not take the API down with it
+			log.Printf("Error checking the rate limit of %s: %v", route, err)
+			next(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Set("RateLimit-Policy", limit.Policy())
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ratelimit.Seconds(result.Reset)))
+
+		if !result.Allowed {
+			retryAfter := ratelimit.Seconds(result.RetryAfter)
+			header.Set("Retry-After", strconv.Itoa(retryAfter))
+			responses.Error(w, http.StatusTooManyRequests, fmt.Errorf("Too many requests, try again in %d seconds", retryAfter))
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// ClientIP returns the address of the client, behind a trusted proxy it is
+// the last address the proxy appended to X-Forwarded-For
+func ClientIP(r *http.Request) string {
+	if config.TrustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			addresses := strings.Split(forwarded, ",")
+			return strings.TrimSpace(addresses[len(addresses)-1])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
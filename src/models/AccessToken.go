@@ -0,0 +1,93 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scopes of the personal access tokens, each one opens a group of routes
+const (
+	ScopeUsersRead          = "users:read"
+	ScopeUsersWrite         = "users:write"
+	ScopeFollowsRead        = "follows:read"
+	ScopeFollowsWrite       = "follows:write"
+	ScopePostsRead          = "posts:read"
+	ScopePostsWrite         = "posts:write"
+	ScopeMessagesRead       = "messages:read"
+	ScopeMessagesWrite      = "messages:write"
+	ScopeNotificationsRead  = "notifications:read"
+	ScopeNotificationsWrite = "notifications:write"
+)
+
+// Scopes lists every scope a personal access token can be given
+var Scopes = []string{
+	ScopeUsersRead, ScopeUsersWrite,
+	ScopeFollowsRead, ScopeFollowsWrite,
+	ScopePostsRead, ScopePostsWrite,
+	ScopeMessagesRead, ScopeMessagesWrite,
+	ScopeNotificationsRead, ScopeNotificationsWrite,
+}
+
+// AccessTokenPrefix starts every personal access token, it tells them apart from the JWTs of the sessions
+const AccessTokenPrefix = "pat_"
+
+// AccessToken represents a personal access token a user created for a script or an integration,
+// only the hash of the token is kept
+type AccessToken struct {
+	ID         uint64     `json:"id,omitempty"`
+	UserID     uint64     `json:"userId,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	TokenHash  string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt,omitempty"`
+	// UserRole is the current role of the owner, the token acts with it
+	UserRole string `json:"-"`
+}
+
+// NewAccessToken represents a personal access token that was just created, the token is only shown once
+type NewAccessToken struct {
+	AccessToken
+	Token string `json:"token"`
+}
+
+// AccessTokenRequest represents the format of the request that creates a personal access token
+type AccessTokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Validate checks the name, the scopes and the expiry of the token
+func (request *AccessTokenRequest) Validate() error {
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		return newFieldError("name", "The name is mandatory and cannot be blank")
+	}
+
+	if len(request.Name) > 50 {
+		return newFieldError("name", "The name cannot be longer than 50 characters")
+	}
+
+	if len(request.Scopes) == 0 {
+		return newFieldError("scopes", "The token needs at least one scope")
+	}
+
+	for _, scope := range request.Scopes {
+		if !slices.Contains(Scopes, scope) {
+			return newFieldError("scopes", fmt.Sprintf("The scope %q does not exist", scope))
+		}
+	}
+
+	slices.Sort(request.Scopes)
+	request.Scopes = slices.Compact(request.Scopes)
+
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return newFieldError("expiresAt", "The expiry must be in the future")
+	}
+
+	return nil
+}
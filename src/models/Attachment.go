@@ -0,0 +1,35 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// MaxAttachments is how many images a post can carry
+const MaxAttachments = 4
+
+// Attachment represents an image attached to a post, the URLs point to the storage backend
+type Attachment struct {
+	ID           uint64    `json:"id,omitempty"`
+	PostID       uint64    `json:"postId,omitempty"`
+	Key          string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	URL          string    `json:"url,omitempty"`
+	ThumbnailURL string    `json:"thumbnailUrl,omitempty"`
+	ContentType  string    `json:"contentType,omitempty"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Size         int       `json:"size"`
+	AltText      string    `json:"altText"`
+	CreatedAt    time.Time `json:"createdAt,omitempty"`
+}
+
+func (attachment *Attachment) Prepare() error {
+	attachment.AltText = strings.TrimSpace(attachment.AltText)
+
+	if len(attachment.AltText) > 500 {
+		return newFieldError("altText", "The alt text cannot be longer than 500 characters")
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Comment represents a comment on a post, replies point to the comment they answer
+type Comment struct {
+	ID              uint64     `json:"id,omitempty"`
+	PostID          uint64     `json:"postId,omitempty"`
+	ParentCommentID *uint64    `json:"parentCommentId,omitempty"`
+	AuthorID        uint64     `json:"authorId,omitempty"`
+	AuthorNick      string     `json:"authorNick,omitempty"`
+	Content         string     `json:"content,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt,omitempty"`
+	UpdatedAt       *time.Time `json:"updatedAt,omitempty"`
+}
+
+func (comment *Comment) Prepare() error {
+	if err := comment.validate(); err != nil {
+		return err
+	}
+
+	comment.format()
+	return nil
+}
+
+func (comment *Comment) validate() error {
+	if strings.TrimSpace(comment.Content) == "" {
+		return newFieldError("content", "The content is mandatory and cannot be blank")
+	}
+
+	if utf8.RuneCountInString(strings.TrimSpace(comment.Content)) > 300 {
+		return newFieldError("content", "The content cannot be longer than 300 characters")
+	}
+
+	return nil
+}
+
+func (comment *Comment) format() {
+	comment.Content = strings.TrimSpace(comment.Content)
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// EmailVerification represents a token sent to confirm that the user owns an email address,
+// it only confirms the address it was sent to
+type EmailVerification struct {
+	TokenHash string     `json:"-"`
+	UserID    uint64     `json:"userId,omitempty"`
+	Email     string     `json:"email,omitempty"`
+	ExpiresAt time.Time  `json:"expiresAt,omitempty"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt,omitempty"`
+}
+
+// VerifyEmailRequest represents the format of the email confirmation request
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
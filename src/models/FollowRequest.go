@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// FollowRequest represents a user asking to follow a private account
+type FollowRequest struct {
+	ID     uint64 `json:"id,omitempty"`
+	UserID uint64 `json:"-"`
+	// Follower is the user who asked to follow
+	Follower  User      `json:"follower"`
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+}
+
+// PrivacySettings represents whether the account of the user is private
+type PrivacySettings struct {
+	// Private accounts approve their followers, only followers see their posts and follower lists
+	Private bool `json:"private"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxHashtagLength is the longest tag kept, longer ones are cut
+const MaxHashtagLength = 100
+
+// TrendingHashtag represents a tag and how much it was used recently
+type TrendingHashtag struct {
+	Tag string `json:"tag"`
+	// Posts is how many posts used the tag inside the window
+	Posts uint64 `json:"posts"`
+	// Score weighs every use by how recent it is, it halves every half-life
+	Score float64 `json:"score"`
+}
+
+// ParseHashtags returns the distinct #tags of the text, lowercase and in the order they first appear.
+// A tag starts after a # that does not follow a letter or digit and must have at least one letter
+func ParseHashtags(text string) []string {
+	var tags []string
+	seen := map[string]bool{}
+
+	previous := ' '
+	for i, r := range text {
+		if r == '#' && !isHashtagRune(previous) {
+			end := i + 1
+			for end < len(text) {
+				next, size := utf8.DecodeRuneInString(text[end:])
+				if !isHashtagRune(next) {
+					break
+				}
+				end += size
+			}
+
+			if tag, ok := NormalizeHashtag(text[i+1 : end]); ok && !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+		previous = r
+	}
+
+	return tags
+}
+
+// NormalizeHashtag returns the tag as it is stored, lowercase and without
+// the leading #, ok is false when it is not a valid tag
+func NormalizeHashtag(tag string) (normalized string, ok bool) {
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+
+	hasLetter := false
+	for _, r := range tag {
+		if !isHashtagRune(r) {
+			return "", false
+		}
+		hasLetter = hasLetter || unicode.IsLetter(r)
+	}
+
+	if !hasLetter {
+		return "", false
+	}
+
+	if runes := []rune(tag); len(runes) > MaxHashtagLength {
+		tag = string(runes[:MaxHashtagLength])
+	}
+
+	return tag, true
+}
+
+func isHashtagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Outcomes of a login attempt, only the invalid credentials and codes count towards a lockout
+const (
+	LoginSucceeded            = "succeeded"
+	LoginInvalidCredentials   = "invalid_credentials"
+	LoginThrottled            = "throttled"
+	LoginSuspended            = "suspended"
+	LoginTwoFactorRequired    = "two_factor_required"
+	LoginInvalidTwoFactorCode = "invalid_code"
+)
+
+// LoginAttempt represents an entry of the audit trail of the logins, the email is
+// kept even when it does not belong to any account
+type LoginAttempt struct {
+	ID        uint64    `json:"id,omitempty"`
+	UserID    *uint64   `json:"userId,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"userAgent,omitempty"`
+	Succeeded bool      `json:"succeeded"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+}
+
+// Failed reports whether the attempt counts towards a lockout
+func (attempt LoginAttempt) Failed() bool {
+	return attempt.Reason == LoginInvalidCredentials || attempt.Reason == LoginInvalidTwoFactorCode
+}
+
+// LoginFailures summarizes the recent failed logins of an account or an IP address
+type LoginFailures struct {
+	Count int
+	Last  time.Time
+}
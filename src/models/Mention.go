@@ -0,0 +1,51 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Mention represents a user referenced as @nick in the content of a post,
+// offsets count Unicode characters and End is exclusive
+type Mention struct {
+	PostID uint64 `json:"-"`
+	UserID uint64 `json:"userId"`
+	Nick   string `json:"nick"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+}
+
+// ParseMentions returns the @nick tokens of the text, they are not resolved
+// to users yet. A mention starts after an @ that does not follow a letter or
+// digit, so email addresses are not mentions
+func ParseMentions(text string) []Mention {
+	var mentions []Mention
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '@' || (i > 0 && isNickRune(runes[i-1])) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isNickRune(runes[end]) {
+			end++
+		}
+
+		// A sentence can end right after a mention
+		for end > i+1 && strings.ContainsRune(".-", runes[end-1]) {
+			end--
+		}
+
+		if end > i+1 {
+			mentions = append(mentions, Mention{Nick: string(runes[i+1 : end]), Start: i, End: end})
+		}
+		i = end - 1
+	}
+
+	return mentions
+}
+
+func isNickRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Message represents a direct message sent in a conversation
+type Message struct {
+	ID             uint64    `json:"id,omitempty"`
+	ConversationID uint64    `json:"conversationId,omitempty"`
+	SenderID       uint64    `json:"senderId,omitempty"`
+	Content        string    `json:"content,omitempty"`
+	CreatedAt      time.Time `json:"createdAt,omitempty"`
+}
+
+// Conversation represents a one-to-one conversation as seen by one of its participants
+type Conversation struct {
+	ID uint64 `json:"id,omitempty"`
+	// Participant is the other user of the conversation
+	Participant User     `json:"participant"`
+	LastMessage *Message `json:"lastMessage,omitempty"`
+	UnreadCount uint64   `json:"unreadCount"`
+	// LastReadMessageID is the read marker of the user, ParticipantLastReadMessageID the one of the other user
+	LastReadMessageID            uint64    `json:"lastReadMessageId"`
+	ParticipantLastReadMessageID uint64    `json:"participantLastReadMessageId"`
+	Muted                        bool      `json:"muted"`
+	CreatedAt                    time.Time `json:"createdAt,omitempty"`
+}
+
+// ReadMarkerRequest represents the format of the request that marks a conversation as read
+type ReadMarkerRequest struct {
+	// MessageID is the last message read, the latest message of the conversation when it is zero
+	MessageID uint64 `json:"messageId"`
+}
+
+// MessageSettings represents who can start a conversation with the user
+type MessageSettings struct {
+	MessagesFromAnyone bool `json:"messagesFromAnyone"`
+}
+
+func (message *Message) Prepare() error {
+	message.Content = strings.TrimSpace(message.Content)
+
+	if message.Content == "" {
+		return newFieldError("content", "The content is mandatory and cannot be blank")
+	}
+
+	if utf8.RuneCountInString(message.Content) > 1000 {
+		return newFieldError("content", "The content cannot be longer than 1000 characters")
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Kinds of events a user is notified about
+const (
+	NotificationFollow  = "follow"
+	NotificationLike    = "like"
+	NotificationComment = "comment"
+	NotificationMention = "mention"
+	// NotificationFollowRequest tells a private account someone asked to follow it
+	NotificationFollowRequest = "follow_request"
+	// NotificationFollowAccepted tells a user their request to follow a private account was approved
+	NotificationFollowAccepted = "follow_accepted"
+)
+
+// Notification represents the events of the same kind on the same post, or
+// the new followers, grouped together while they are unread
+type Notification struct {
+	// ID is the ID of the most recent event of the group
+	ID         uint64    `json:"id,omitempty"`
+	UserID     uint64    `json:"-"`
+	Type       string    `json:"type,omitempty"`
+	PostID     *uint64   `json:"postId,omitempty"`
+	ActorID    uint64    `json:"actorId,omitempty"`
+	ActorNick  string    `json:"actorNick,omitempty"`
+	ActorCount uint64    `json:"actorCount"`
+	Message    string    `json:"message,omitempty"`
+	Read       bool      `json:"read"`
+	CreatedAt  time.Time `json:"createdAt,omitempty"`
+}
+
+// NotificationsReadRequest represents the format of the request that marks notifications as read
+type NotificationsReadRequest struct {
+	// IDs are the notifications to mark, all of them are marked when it is empty
+	IDs []uint64 `json:"ids"`
+}
+
+// UnreadCount represents the number of unread notifications
+type UnreadCount struct {
+	Unread uint64 `json:"unread"`
+}
+
+// Describe fills in the message shown to the user, naming the most recent actor
+func (notification *Notification) Describe() {
+	actors := notification.ActorNick
+	switch {
+	case notification.ActorCount == 2:
+		actors += " and 1 other"
+	case notification.ActorCount > 2:
+		actors = fmt.Sprintf("%s and %d others", notification.ActorNick, notification.ActorCount-1)
+	}
+
+	switch notification.Type {
+	case NotificationFollow:
+		notification.Message = actors + " started following you"
+	case NotificationLike:
+		notification.Message = actors + " liked your post"
+	case NotificationComment:
+		notification.Message = actors + " commented on your post"
+	case NotificationMention:
+		notification.Message = actors + " mentioned you in a post"
+	case NotificationFollowRequest:
+		notification.Message = actors + " asked to follow you"
+	case NotificationFollowAccepted:
+		notification.Message = actors + " accepted your follow request"
+	}
+}
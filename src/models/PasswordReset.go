@@ -0,0 +1,42 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// PasswordReset represents a token sent to the email address of a user who forgot their password,
+// it only works while the account keeps that address
+type PasswordReset struct {
+	TokenHash string     `json:"-"`
+	UserID    uint64     `json:"userId,omitempty"`
+	Email     string     `json:"email,omitempty"`
+	ExpiresAt time.Time  `json:"expiresAt,omitempty"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt,omitempty"`
+}
+
+// ForgotPasswordRequest represents the format of the request that asks for a reset link
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest represents the format of the request that chooses a new password
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// Validate checks that the request has a token and a new password
+func (request *ResetPasswordRequest) Validate() error {
+	request.Token = strings.TrimSpace(request.Token)
+	if request.Token == "" {
+		return newFieldError("token", "The token is mandatory and cannot be blank")
+	}
+
+	if request.Password == "" {
+		return newFieldError("password", "The password is mandatory and cannot be blank")
+	}
+
+	return nil
+}
@@ -1,19 +1,23 @@
 package models
 
 import (
-	"errors"
 	"strings"
 	"time"
 )
 
 type Post struct {
-	ID         uint64    `json:"id,omitempty"`
-	Title      string    `json:"title,omitempty"`
-	Content    string    `json:"content,omitempty"`
-	AuthorID   uint64    `json:"authorId,omitempty"`
-	AuthorNick string    `json:"authorNick,omitempty"`
-	Likes      uint64    `json:"likes"`
-	CreatedAt  time.Time `json:"createdAt,omitempty"`
+	ID           uint64       `json:"id,omitempty"`
+	Title        string       `json:"title,omitempty"`
+	Content      string       `json:"content,omitempty"`
+	AuthorID     uint64       `json:"authorId,omitempty"`
+	AuthorNick   string       `json:"authorNick,omitempty"`
+	Likes        uint64       `json:"likes"`
+	LikedByMe    bool         `json:"likedByMe"`
+	CommentCount uint64       `json:"commentCount"`
+	Attachments  []Attachment `json:"attachments"`
+	Mentions     []Mention    `json:"mentions"`
+	Hashtags     []string     `json:"-"`
+	CreatedAt    time.Time    `json:"createdAt,omitempty"`
 }
 
 func (post *Post) Prepare() error {
@@ -27,11 +31,11 @@ func (post *Post) Prepare() error {
 
 func (post *Post) validate() error {
 	if post.Title == "" {
-		return errors.New("The title is mandatory and cannot be blank")
+		return newFieldError("title", "The title is mandatory and cannot be blank")
 	}
 
 	if post.Content == "" {
-		return errors.New("The content is mandatory and cannot be blank")
+		return newFieldError("content", "The content is mandatory and cannot be blank")
 	}
 
 	return nil
@@ -40,4 +44,5 @@ func (post *Post) validate() error {
 func (post *Post) format() {
 	post.Title = strings.TrimSpace(post.Title)
 	post.Content = strings.TrimSpace(post.Content)
+	post.Hashtags = ParseHashtags(post.Content)
 }
@@ -0,0 +1,25 @@
+package models
+
+// PostSearchResult represents a post found by a full-text search
+type PostSearchResult struct {
+	Post Post `json:"post"`
+	// Rank is the position of the result, the first result has rank 1
+	Rank  uint64  `json:"rank"`
+	Score float64 `json:"score"`
+	// Title is the whole title with the matches highlighted
+	Title Snippet `json:"title"`
+	// Snippet is the fragment of the content around the best matches
+	Snippet Snippet `json:"snippet"`
+}
+
+// Snippet represents a piece of text with the words that matched the search
+type Snippet struct {
+	Text       string      `json:"text"`
+	Highlights []Highlight `json:"highlights"`
+}
+
+// Highlight marks a match inside a snippet, offsets count Unicode characters and End is exclusive
+type Highlight struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Session represents a login of a user, it lives as long as its refresh token keeps being rotated
+type Session struct {
+	ID               string     `json:"id,omitempty"`
+	UserID           uint64     `json:"userId,omitempty"`
+	RefreshTokenHash string     `json:"-"`
+	ExpiresAt        time.Time  `json:"expiresAt,omitempty"`
+	RevokedAt        *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt,omitempty"`
+}
+
+// AuthTokens represents the tokens returned to the client when a session starts or is refreshed
+type AuthTokens struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int64  `json:"expiresIn"`
+}
+
+// RefreshRequest represents the format of the token refresh request
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// TwoFactor represents the TOTP settings of a user, the secret is pending until a first code confirms it
+type TwoFactor struct {
+	UserID            uint64     `json:"-"`
+	Secret            string     `json:"-"`
+	EnabledAt         *time.Time `json:"enabledAt,omitempty"`
+	LastStep          int64      `json:"-"`
+	RecoveryCodesLeft int        `json:"recoveryCodesLeft"`
+}
+
+// Enabled reports whether the logins of the user ask for a second factor
+func (twoFactor TwoFactor) Enabled() bool {
+	return twoFactor.EnabledAt != nil
+}
+
+// TwoFactorSetup represents the secret to add to an authenticator app, either typed or read from the URI
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// RecoveryCodes represents the single use codes that replace the authenticator app when it is lost,
+// they are only shown once
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// LoginChallenge represents a login that checked the password and waits for the second factor
+type LoginChallenge struct {
+	TokenHash string     `json:"-"`
+	UserID    uint64     `json:"-"`
+	Attempts  int        `json:"-"`
+	ExpiresAt time.Time  `json:"-"`
+	UsedAt    *time.Time `json:"-"`
+}
+
+// TwoFactorChallenge represents the answer of a login that needs a second factor
+type TwoFactorChallenge struct {
+	ChallengeToken string `json:"challengeToken"`
+	ExpiresIn      int64  `json:"expiresIn"`
+}
+
+// TwoFactorCodeRequest represents the format of the request that confirms the setup with a code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// Validate checks that the request has a code
+func (request *TwoFactorCodeRequest) Validate() error {
+	request.Code = strings.TrimSpace(request.Code)
+	if request.Code == "" {
+		return newFieldError("code", "The code is mandatory and cannot be blank")
+	}
+
+	return nil
+}
+
+// TwoFactorLoginRequest represents the format of the second step of a login, the code comes
+// from the authenticator app or is one of the recovery codes
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code"`
+}
+
+// Validate checks that the request has a challenge token and a code
+func (request *TwoFactorLoginRequest) Validate() error {
+	request.ChallengeToken = strings.TrimSpace(request.ChallengeToken)
+	if request.ChallengeToken == "" {
+		return newFieldError("challengeToken", "The challenge token is mandatory and cannot be blank")
+	}
+
+	request.Code = strings.TrimSpace(request.Code)
+	if request.Code == "" {
+		return newFieldError("code", "The code is mandatory and cannot be blank")
+	}
+
+	return nil
+}
+
+// DisableTwoFactorRequest represents the format of the request that turns two-factor authentication off,
+// the user proves it is them again with their password and a code
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// Validate checks that the request has a password and a code
+func (request *DisableTwoFactorRequest) Validate() error {
+	if request.Password == "" {
+		return newFieldError("password", "The password is mandatory and cannot be blank")
+	}
+
+	request.Code = strings.TrimSpace(request.Code)
+	if request.Code == "" {
+		return newFieldError("code", "The code is mandatory and cannot be blank")
+	}
+
+	return nil
+}
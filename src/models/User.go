@@ -2,21 +2,42 @@ package models
 
 import (
 	"api/src/security"
-	"errors"
 	"strings"
 	"time"
 
 	"github.com/badoux/checkmail"
 )
 
+// Roles a user can have, each one can do everything the previous one can
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 // User represents a user using the social media
 type User struct {
-	ID        uint64    `json:"id,omitempty"`
-	Name      string    `json:"name,omitempty"`
-	Nick      string    `json:"nick,omitempty"`
-	Email     string    `json:"email,omitempty"`
-	Password  string    `json:"password,omitempty"`
-	CreatedAt time.Time `json:"CreatedAt,omitempty"`
+	ID                 uint64     `json:"id,omitempty"`
+	Name               string     `json:"name,omitempty"`
+	Nick               string     `json:"nick,omitempty"`
+	Email              string     `json:"email,omitempty"`
+	EmailVerifiedAt    *time.Time `json:"emailVerifiedAt,omitempty"`
+	Password           string     `json:"password,omitempty"`
+	Role               string     `json:"role,omitempty"`
+	SuspendedAt        *time.Time `json:"suspendedAt,omitempty"`
+	MessagesFromAnyone bool       `json:"messagesFromAnyone,omitempty"`
+	Private            bool       `json:"private,omitempty"`
+	CreatedAt          time.Time  `json:"CreatedAt,omitempty"`
+}
+
+// RoleRequest represents the format of the role update request
+type RoleRequest struct {
+	Role string `json:"role"`
+}
+
+// ValidRole reports whether the role is one of the known roles
+func ValidRole(role string) bool {
+	return role == RoleUser || role == RoleModerator || role == RoleAdmin
 }
 
 type UserRequest struct {
@@ -37,23 +58,23 @@ func (user *User) Prepare(step string) error {
 
 func (user *User) validate(step string) error {
 	if user.Name == "" {
-		return errors.New("The name is mandatory and cannot be blank")
+		return newFieldError("name", "The name is mandatory and cannot be blank")
 	}
 
 	if user.Nick == "" {
-		return errors.New("The nick is mandatory and cannot be blank")
+		return newFieldError("nick", "The nick is mandatory and cannot be blank")
 	}
 
 	if user.Email == "" {
-		return errors.New("The email is mandatory and cannot be blank")
+		return newFieldError("email", "The email is mandatory and cannot be blank")
 	}
 
 	if err := checkmail.ValidateFormat(user.Email); err != nil {
-		return errors.New("Invalid email")
+		return newFieldError("email", "Invalid email")
 	}
 
 	if step == "register" && user.Password == "" {
-		return errors.New("The password is mandatory and cannot be blank")
+		return newFieldError("password", "The password is mandatory and cannot be blank")
 	}
 
 	return nil
@@ -62,7 +83,7 @@ func (user *User) validate(step string) error {
 func (user *User) format(step string) error {
 	user.Name = strings.TrimSpace(user.Name)
 	user.Nick = strings.TrimSpace(user.Nick)
-	user.Email = strings.TrimSpace(user.Email)
+	user.Email = strings.ToLower(strings.TrimSpace(user.Email))
 
 	if step == "register" {
 		hashedPassword, err := security.Hash(user.Password)
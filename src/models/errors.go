@@ -0,0 +1,25 @@
+package models
+
+import "errors"
+
+// Errors the stores return so callers can tell what went wrong without looking
+// at driver errors, they are mapped to HTTP statuses by responses.Error
+var (
+	ErrNotFound  = errors.New("the resource was not found")
+	ErrConflict  = errors.New("the resource conflicts with an existing one")
+	ErrForbidden = errors.New("the operation is not allowed")
+)
+
+// FieldError represents a validation error of a single field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (err *FieldError) Error() string {
+	return err.Message
+}
+
+func newFieldError(field, message string) error {
+	return &FieldError{Field: field, Message: message}
+}
@@ -0,0 +1,89 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// DefaultLimit is the page size used when the request does not ask for one
+	DefaultLimit = 20
+	// MaxLimit is the largest page size a request can ask for
+	MaxLimit = 100
+)
+
+// Params represents the page requested by the client
+type Params struct {
+	Limit int
+	// After is the ID of the last item of the previous page, zero on the first page
+	After uint64
+}
+
+// Page represents one page of results and the cursor to fetch the next one
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// FromRequest reads the limit and cursor query parameters of the request
+func FromRequest(r *http.Request) (Params, error) {
+	params := Params{Limit: DefaultLimit}
+	query := r.URL.Query()
+
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 {
+			return Params{}, errors.New("The limit must be a positive number")
+		}
+
+		params.Limit = min(value, MaxLimit)
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return Params{}, errors.New("Invalid cursor")
+		}
+
+		params.After = after
+	}
+
+	return params, nil
+}
+
+// Fetch returns how many rows the repository must read, one more than the
+// limit so that NewPage can tell whether there is a next page
+func (params Params) Fetch() int {
+	return params.Limit + 1
+}
+
+// NewPage builds the page from the rows read by the repository, the cursor
+// points at the ID of the last item returned
+func NewPage[T any](items []T, params Params, id func(T) uint64) Page[T] {
+	page := Page[T]{Data: items}
+	if page.Data == nil {
+		page.Data = []T{}
+	}
+
+	if len(items) > params.Limit {
+		page.Data = items[:params.Limit]
+		page.NextCursor = encodeCursor(id(page.Data[params.Limit-1]))
+	}
+
+	return page
+}
+
+func encodeCursor(id uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(id, 10)))
+}
+
+func decodeCursor(cursor string) (uint64, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(string(decoded), 10, 64)
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store forgets the buckets that refilled completely
+const sweepInterval = time.Minute
+
+// Memory keeps the buckets in the memory of the process, every instance of
+// the API enforces its own limits
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// bucket stores the tokens left at the time of the last update, the refill is computed lazily
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+var _ Store = (*Memory)(nil)
+
+// NewMemory creates an empty in-memory store that reads the time from now,
+// a nil clock means time.Now
+func NewMemory(now func() time.Time) *Memory {
+	if now == nil {
+		now = time.Now
+	}
+	return &Memory{buckets: map[string]bucket{}, now: now}
+}
+
+func (store *Memory) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := store.now()
+	store.sweep(now)
+
+	current, ok := store.buckets[key]
+	if !ok {
+		current = bucket{tokens: float64(limit.Requests), updated: now, limit: limit}
+	}
+	current.refill(now)
+	current.limit = limit
+
+	result := Result{Limit: limit.Requests}
+	if current.tokens >= 1 {
+		current.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - current.tokens) * float64(limit.interval()))
+	}
+
+	store.buckets[key] = current
+
+	result.Remaining = int(current.tokens)
+	result.Reset = time.Duration((float64(limit.Requests) - current.tokens) * float64(limit.interval()))
+	return result, nil
+}
+
+// refill adds the tokens earned since the last update, up to the size of the bucket
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated)
+	if elapsed > 0 {
+		b.tokens = min(float64(b.limit.Requests), b.tokens+float64(elapsed)/float64(b.limit.interval()))
+		b.updated = now
+	}
+}
+
+// sweep removes the buckets that are full again, they behave like missing ones, the lock must be held
+func (store *Memory) sweep(now time.Time) {
+	if now.Sub(store.lastSweep) < sweepInterval {
+		return
+	}
+	store.lastSweep = now
+
+	for key, b := range store.buckets {
+		if now.Sub(b.updated) >= b.limit.Window {
+			delete(store.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryTake(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemory(func() time.Time { return now })
+
+	limit := Limit{Requests: 3, Window: 30 * time.Second}
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take(context.Background(), "login", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Allowed || result.Remaining != i {
+			t.Fatalf("request %d: %+v", 3-i, result)
+		}
+	}
+
+	result, _ := store.Take(context.Background(), "login", limit)
+	if result.Allowed || result.RetryAfter != 10*time.Second || result.Reset != 30*time.Second {
+		t.Fatalf("fourth request: %+v", result)
+	}
+
+	// Other keys have their own buckets
+	if result, _ = store.Take(context.Background(), "other", limit); !result.Allowed {
+		t.Fatalf("other key: %+v", result)
+	}
+
+	now = now.Add(10 * time.Second)
+	if result, _ = store.Take(context.Background(), "login", limit); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("after a refill: %+v", result)
+	}
+
+	now = now.Add(time.Hour)
+	if result, _ = store.Take(context.Background(), "login", limit); !result.Allowed || result.Remaining != 2 {
+		t.Fatalf("after a full refill: %+v", result)
+	}
+}
+
+func TestMemorySweep(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemory(func() time.Time { return now })
+
+	store.Take(context.Background(), "idle", Limit{Requests: 5, Window: time.Second})
+	now = now.Add(2 * sweepInterval)
+	store.Take(context.Background(), "active", Limit{Requests: 5, Window: time.Hour})
+
+	if _, ok := store.buckets["idle"]; ok {
+		t.Fatal("the full bucket was kept")
+	}
+	if _, ok := store.buckets["active"]; !ok {
+		t.Fatal("the bucket in use was removed")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	if policy := (Limit{Requests: 10, Window: time.Minute}).Policy(); policy != "10;w=60" {
+		t.Fatalf("policy %q", policy)
+	}
+}
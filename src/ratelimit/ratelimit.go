@@ -0,0 +1,58 @@
+// Package ratelimit throttles clients with token buckets, a bucket holds up
+// to the number of requests of its limit and refills steadily over the window
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Default is the limit of the routes that do not set their own
+var Default = Limit{Requests: 300, Window: time.Minute}
+
+// Limit allows bursts of Requests and refills them over Window, the zero value means Default
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// IsZero reports whether the limit was not set
+func (limit Limit) IsZero() bool {
+	return limit.Requests == 0 && limit.Window == 0
+}
+
+// Policy returns the limit in the format of the RateLimit-Policy header
+func (limit Limit) Policy() string {
+	return fmt.Sprintf("%d;w=%d", limit.Requests, int(math.Ceil(limit.Window.Seconds())))
+}
+
+// interval returns how long a single request takes to refill
+func (limit Limit) interval() time.Duration {
+	return limit.Window / time.Duration(limit.Requests)
+}
+
+// Result describes the state of a bucket after a request took, or failed to take, a token from it
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining is how many more requests the bucket allows right now
+	Remaining int
+	// Reset is how long the bucket takes to be full again
+	Reset time.Duration
+	// RetryAfter is how long a rejected request must wait for the next token
+	RetryAfter time.Duration
+}
+
+// Store is implemented by the backends that keep the buckets, a shared store
+// lets several API instances enforce the same limits
+type Store interface {
+	// Take removes a token from the bucket of the key, creating a full bucket when there is none
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Seconds rounds the duration up to whole seconds, the unit of the rate limit headers
+func Seconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
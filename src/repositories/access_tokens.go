@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// Represent a personal access tokens repository
+type AccessTokens struct {
+	db *sql.DB
+}
+
+// Create a personal access tokens repository
+func NewAccessTokensRepository(db *sql.DB) *AccessTokens {
+	return &AccessTokens{db}
+}
+
+// Inserts a personal access token into the database, the scopes are kept separated by spaces
+func (repository AccessTokens) Create(token models.AccessToken) (uint64, error) {
+	statement, err := repository.db.Prepare(
+		"insert into personal_access_tokens (user_id, name, token_hash, scopes, expires_at) values (?, ?, ?, ?, ?)",
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer statement.Close()
+
+	result, err := statement.Exec(token.UserID, token.Name, token.TokenHash, strings.Join(token.Scopes, " "), token.ExpiresAt)
+	if err != nil {
+		return 0, err
+	}
+
+	lastInsertedID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(lastInsertedID), nil
+}
+
+// SearchByUser returns the personal access tokens of the user, most recent first
+func (repository AccessTokens) SearchByUser(userID uint64, page pagination.Params) ([]models.AccessToken, error) {
+	rows, err := repository.db.Query(`select id, user_id, name, scopes, expires_at, last_used_at, created_at
+	from personal_access_tokens where user_id = ?
+	and (? = 0 or id < ?) order by id desc limit ?`,
+		userID, page.After, page.After, page.Fetch())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.AccessToken
+	for rows.Next() {
+		var token models.AccessToken
+		var scopes string
+
+		if err = rows.Scan(
+			&token.ID,
+			&token.UserID,
+			&token.Name,
+			&scopes,
+			&token.ExpiresAt,
+			&token.LastUsedAt,
+			&token.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		token.Scopes = strings.Fields(scopes)
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+// Delete revokes the personal access token of the user
+func (repository AccessTokens) Delete(tokenID, userID uint64) error {
+	statement, err := repository.db.Prepare("delete from personal_access_tokens where id = ? and user_id = ?")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	result, err := statement.Exec(tokenID, userID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Use returns the valid personal access token with the hash and the role of its owner, the last use
+// is only written once a minute so busy scripts do not write on every request
+func (repository AccessTokens) Use(tokenHash string) (models.AccessToken, bool, error) {
+	var token models.AccessToken
+	var scopes string
+
+	err := repository.db.QueryRow(`select t.id, t.user_id, t.name, t.scopes, t.expires_at, t.last_used_at, t.created_at, u.role
+	from personal_access_tokens t join users u on u.id = t.user_id
+	where t.token_hash = ? and (t.expires_at is null or t.expires_at > current_timestamp) and u.suspended_at is null`,
+		tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&scopes,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.CreatedAt,
+		&token.UserRole,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.AccessToken{}, false, nil
+	}
+	if err != nil {
+		return models.AccessToken{}, false, err
+	}
+
+	token.Scopes = strings.Fields(scopes)
+
+	if _, err = repository.db.Exec(`update personal_access_tokens set last_used_at = current_timestamp
+	where id = ? and (last_used_at is null or last_used_at < current_timestamp - interval 1 minute)`, token.ID); err != nil {
+		return models.AccessToken{}, false, err
+	}
+
+	return token, true, nil
+}
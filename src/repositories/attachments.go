@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"api/src/models"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// Represent a post attachments repository
+type Attachments struct {
+	db *sql.DB
+}
+
+// Create a post attachments repository
+func NewAttachmentsRepository(db *sql.DB) *Attachments {
+	return &Attachments{db}
+}
+
+const attachmentColumns = `a.id, a.post_id, a.storage_key, a.thumbnail_key,
+	a.content_type, a.width, a.height, a.size, a.alt_text, a.created_at`
+
+// Inserts an attachment into the database, the post row stays locked while its
+// images are counted so parallel uploads cannot go over the limit
+func (repository Attachments) Create(attachment models.Attachment) (uint64, error) {
+	tx, err := repository.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var postID uint64
+	err = tx.QueryRow("select id from posts where id = ? for update", attachment.PostID).Scan(&postID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	if err = tx.QueryRow("select count(*) from post_attachments where post_id = ?", attachment.PostID).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	if count >= models.MaxAttachments {
+		return 0, ErrConflict
+	}
+
+	result, err := tx.Exec(`insert into post_attachments
+	(post_id, storage_key, thumbnail_key, content_type, width, height, size, alt_text)
+	values (?, ?, ?, ?, ?, ?, ?, ?)`,
+		attachment.PostID,
+		attachment.Key,
+		attachment.ThumbnailKey,
+		attachment.ContentType,
+		attachment.Width,
+		attachment.Height,
+		attachment.Size,
+		attachment.AltText,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	lastInsertedID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(lastInsertedID), tx.Commit()
+}
+
+func (repository Attachments) SearchByID(attachmentID uint64) (models.Attachment, error) {
+	attachments, err := repository.search("where a.id = ?", attachmentID)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+
+	if len(attachments) == 0 {
+		return models.Attachment{}, ErrNotFound
+	}
+
+	return attachments[0], nil
+}
+
+// SearchByPosts returns the attachments of the posts in the order they were uploaded
+func (repository Attachments) SearchByPosts(postIDs []uint64) ([]models.Attachment, error) {
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+
+	arguments := make([]any, 0, len(postIDs))
+	for _, postID := range postIDs {
+		arguments = append(arguments, postID)
+	}
+
+	return repository.search(
+		"where a.post_id in (?"+strings.Repeat(", ?", len(postIDs)-1)+") order by a.id", arguments...,
+	)
+}
+
+// SearchByUser returns the attachments of every post of the user
+func (repository Attachments) SearchByUser(userID uint64) ([]models.Attachment, error) {
+	return repository.search("join posts p on p.id = a.post_id where p.authorId = ? order by a.id", userID)
+}
+
+func (repository Attachments) Delete(attachmentID uint64) error {
+	statement, err := repository.db.Prepare("delete from post_attachments where id = ?")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(attachmentID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository Attachments) search(condition string, arguments ...any) ([]models.Attachment, error) {
+	rows, err := repository.db.Query("select "+attachmentColumns+" from post_attachments a "+condition, arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []models.Attachment
+
+	for rows.Next() {
+		var attachment models.Attachment
+
+		if err = rows.Scan(
+			&attachment.ID,
+			&attachment.PostID,
+			&attachment.Key,
+			&attachment.ThumbnailKey,
+			&attachment.ContentType,
+			&attachment.Width,
+			&attachment.Height,
+			&attachment.Size,
+			&attachment.AltText,
+			&attachment.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, nil
+}
@@ -0,0 +1,160 @@
+package repositories
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"database/sql"
+)
+
+// Represent a repository of the blocks and mutes between users
+type Blocks struct {
+	db *sql.DB
+}
+
+// Create a blocks repository
+func NewBlocksRepository(db *sql.DB) *Blocks {
+	return &Blocks{db}
+}
+
+// Block records that the user blocked the other one and removes the follows
+// and follow requests between them, in both directions
+func (repository Blocks) Block(userID, blockedID uint64) error {
+	tx, err := repository.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("insert ignore into user_blocks (user_id, blocked_id) values (?, ?)", userID, blockedID); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(`delete from followers
+	where (user_id = ? and follower_id = ?) or (user_id = ? and follower_id = ?)`,
+		userID, blockedID, blockedID, userID); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(`delete from follow_requests
+	where (user_id = ? and follower_id = ?) or (user_id = ? and follower_id = ?)`,
+		userID, blockedID, blockedID, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repository Blocks) Unblock(userID, blockedID uint64) error {
+	statement, err := repository.db.Prepare("delete from user_blocks where user_id = ? and blocked_id = ?")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(userID, blockedID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IsBlocked reports whether either of the users blocked the other
+func (repository Blocks) IsBlocked(userID, otherID uint64) (bool, error) {
+	var blocked bool
+	err := repository.db.QueryRow(`select exists(select 1 from user_blocks
+	where (user_id = ? and blocked_id = ?) or (user_id = ? and blocked_id = ?))`,
+		userID, otherID, otherID, userID).Scan(&blocked)
+
+	return blocked, err
+}
+
+// SearchBlockedIDs returns the users the user blocked and the ones who blocked the user
+func (repository Blocks) SearchBlockedIDs(userID uint64) ([]uint64, error) {
+	rows, err := repository.db.Query(`select blocked_id from user_blocks where user_id = ?
+	union select user_id from user_blocks where blocked_id = ?`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uint64
+	for rows.Next() {
+		var id uint64
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// SearchBlocked returns the users the user blocked
+func (repository Blocks) SearchBlocked(userID uint64, page pagination.Params) ([]models.User, error) {
+	return repository.searchUsers(`select u.id, u.name, u.nick, u.email, u.createdAt
+	from users u, user_blocks b where u.id = b.blocked_id and b.user_id = ?
+	and u.id > ? order by u.id limit ?`, userID, page)
+}
+
+// Mute hides the posts of the muted user from the timeline of the user
+func (repository Blocks) Mute(userID, mutedID uint64) error {
+	statement, err := repository.db.Prepare("insert ignore into user_mutes (user_id, muted_id) values (?, ?)")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(userID, mutedID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository Blocks) Unmute(userID, mutedID uint64) error {
+	statement, err := repository.db.Prepare("delete from user_mutes where user_id = ? and muted_id = ?")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(userID, mutedID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SearchMuted returns the users the user muted
+func (repository Blocks) SearchMuted(userID uint64, page pagination.Params) ([]models.User, error) {
+	return repository.searchUsers(`select u.id, u.name, u.nick, u.email, u.createdAt
+	from users u, user_mutes m where u.id = m.muted_id and m.user_id = ?
+	and u.id > ? order by u.id limit ?`, userID, page)
+}
+
+func (repository Blocks) searchUsers(query string, userID uint64, page pagination.Params) ([]models.User, error) {
+	rows, err := repository.db.Query(query, userID, page.After, page.Fetch())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err = rows.Scan(
+			&user.ID,
+			&user.Name,
+			&user.Nick,
+			&user.Email,
+			&user.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	return users, nil
+}
@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"database/sql"
+)
+
+// Represent a comments repository
+type Comments struct {
+	db *sql.DB
+}
+
+// Create a comments repository
+func NewCommentsRepository(db *sql.DB) *Comments {
+	return &Comments{db}
+}
+
+// Inserts a comment into the database
+func (repository Comments) Create(comment models.Comment) (uint64, error) {
+	statement, err := repository.db.Prepare(
+		"insert into comments (post_id, author_id, parent_comment_id, content) values (?, ?, ?, ?)",
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer statement.Close()
+
+	result, err := statement.Exec(comment.PostID, comment.AuthorID, comment.ParentCommentID, comment.Content)
+	if err != nil {
+		return 0, err
+	}
+
+	lastInsertedID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(lastInsertedID), nil
+}
+
+func (repository Comments) SearchByID(commentID uint64) (models.Comment, error) {
+	rows, err := repository.db.Query(`
+	select c.id, c.post_id, c.parent_comment_id, c.author_id, u.nick, c.content, c.created_at, c.updated_at
+	from comments c, users u where u.id = c.author_id and c.id = ?`, commentID,
+	)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return models.Comment{}, ErrNotFound
+	}
+
+	var comment models.Comment
+	if err = rows.Scan(
+		&comment.ID,
+		&comment.PostID,
+		&comment.ParentCommentID,
+		&comment.AuthorID,
+		&comment.AuthorNick,
+		&comment.Content,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+	); err != nil {
+		return models.Comment{}, err
+	}
+
+	return comment, nil
+}
+
+// SearchByPost returns the comments of a post in the order they were written,
+// replies carry the ID of their parent so clients can rebuild the threads
+func (repository Comments) SearchByPost(postID uint64, page pagination.Params) ([]models.Comment, error) {
+	rows, err := repository.db.Query(`
+	select c.id, c.post_id, c.parent_comment_id, c.author_id, u.nick, c.content, c.created_at, c.updated_at
+	from comments c, users u where u.id = c.author_id and c.post_id = ?
+	and c.id > ? order by c.id limit ?`, postID, page.After, page.Fetch(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []models.Comment
+
+	for rows.Next() {
+		var comment models.Comment
+
+		if err = rows.Scan(
+			&comment.ID,
+			&comment.PostID,
+			&comment.ParentCommentID,
+			&comment.AuthorID,
+			&comment.AuthorNick,
+			&comment.Content,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		comments = append(comments, comment)
+	}
+
+	return comments, nil
+}
+
+func (repository Comments) Update(commentID uint64, comment models.Comment) error {
+	statement, err := repository.db.Prepare(
+		"update comments set content = ?, updated_at = current_timestamp where id = ?",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(comment.Content, commentID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Delete removes the comment and, through the foreign key, all of its replies
+func (repository Comments) Delete(commentID uint64) error {
+	statement, err := repository.db.Prepare("delete from comments where id = ?")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(commentID); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"api/src/models"
+	"database/sql"
+	"errors"
+)
+
+// Represent an email verifications repository
+type EmailVerifications struct {
+	db *sql.DB
+}
+
+// Create an email verifications repository
+func NewEmailVerificationsRepository(db *sql.DB) *EmailVerifications {
+	return &EmailVerifications{db}
+}
+
+// Inserts an email verification into the database
+func (repository EmailVerifications) Create(verification models.EmailVerification) error {
+	statement, err := repository.db.Prepare(
+		"insert into email_verifications (token_hash, user_id, email, expires_at) values (?, ?, ?, ?)",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(verification.TokenHash, verification.UserID, verification.Email, verification.ExpiresAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SearchByToken returns the verification of the hashed token
+func (repository EmailVerifications) SearchByToken(tokenHash string) (models.EmailVerification, error) {
+	rows, err := repository.db.Query(
+		"select token_hash, user_id, email, expires_at, used_at, created_at from email_verifications where token_hash = ?",
+		tokenHash,
+	)
+	if err != nil {
+		return models.EmailVerification{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return models.EmailVerification{}, ErrNotFound
+	}
+
+	var verification models.EmailVerification
+	if err = rows.Scan(
+		&verification.TokenHash,
+		&verification.UserID,
+		&verification.Email,
+		&verification.ExpiresAt,
+		&verification.UsedAt,
+		&verification.CreatedAt,
+	); err != nil {
+		return models.EmailVerification{}, err
+	}
+
+	return verification, nil
+}
+
+// Confirm uses the token and marks the email address it was sent to as verified, it reports
+// false when the token was already used, expired or the user changed their address since
+func (repository EmailVerifications) Confirm(tokenHash string) (bool, error) {
+	tx, err := repository.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var userID uint64
+	err = tx.QueryRow(`select v.user_id from email_verifications v join users u on u.id = v.user_id
+	where v.token_hash = ? and v.used_at is null and v.expires_at > current_timestamp and u.email = v.email
+	for update`, tokenHash).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if _, err = tx.Exec("update users set email_verified_at = current_timestamp where id = ?", userID); err != nil {
+		return false, err
+	}
+
+	// The other tokens of the user cannot be used anymore either
+	if _, err = tx.Exec(
+		"update email_verifications set used_at = current_timestamp where user_id = ? and used_at is null", userID,
+	); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
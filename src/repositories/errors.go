@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"api/src/models"
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Errors the repositories return, they are the ones of the models so the
+// callers match them without depending on this package
+var (
+	ErrNotFound  = models.ErrNotFound
+	ErrConflict  = models.ErrConflict
+	ErrForbidden = models.ErrForbidden
+)
+
+// mysqlDuplicateEntry is the MySQL error number of unique key violations
+const mysqlDuplicateEntry = 1062
+
+// translateError turns known driver errors into the sentinel errors
+func translateError(err error, conflictMessage string) error {
+	var mysqlError *mysql.MySQLError
+	if errors.As(err, &mysqlError) && mysqlError.Number == mysqlDuplicateEntry {
+		return fmt.Errorf("%w: %s", ErrConflict, conflictMessage)
+	}
+
+	return err
+}
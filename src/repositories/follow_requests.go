@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"database/sql"
+)
+
+// Represent a follow requests repository
+type FollowRequests struct {
+	db *sql.DB
+}
+
+// Create a follow requests repository
+func NewFollowRequestsRepository(db *sql.DB) *FollowRequests {
+	return &FollowRequests{db}
+}
+
+// Create records that the follower asked to follow the user, asking twice has no effect
+func (repository FollowRequests) Create(userID, followerID uint64) error {
+	statement, err := repository.db.Prepare(
+		"insert ignore into follow_requests (user_id, follower_id) values (?, ?)")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(userID, followerID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository FollowRequests) SearchByID(requestID uint64) (models.FollowRequest, error) {
+	rows, err := repository.db.Query(`select r.id, r.user_id, r.created_at, u.id, u.name, u.nick, u.email, u.createdAt
+	from follow_requests r join users u on u.id = r.follower_id where r.id = ?`, requestID)
+	if err != nil {
+		return models.FollowRequest{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return models.FollowRequest{}, ErrNotFound
+	}
+
+	return scanFollowRequest(rows)
+}
+
+// Search returns the pending requests to follow the user, oldest first
+func (repository FollowRequests) Search(userID uint64, page pagination.Params) ([]models.FollowRequest, error) {
+	rows, err := repository.db.Query(`select r.id, r.user_id, r.created_at, u.id, u.name, u.nick, u.email, u.createdAt
+	from follow_requests r join users u on u.id = r.follower_id
+	where r.user_id = ? and r.id > ? order by r.id limit ?`, userID, page.After, page.Fetch())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []models.FollowRequest
+
+	for rows.Next() {
+		request, err := scanFollowRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		requests = append(requests, request)
+	}
+
+	return requests, nil
+}
+
+// Approve turns the request into a follow
+func (repository FollowRequests) Approve(requestID uint64) error {
+	tx, err := repository.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(`insert ignore into followers (user_id, follower_id)
+	select user_id, follower_id from follow_requests where id = ?`, requestID); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec("delete from follow_requests where id = ?", requestID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes the request without creating the follow
+func (repository FollowRequests) Delete(requestID uint64) error {
+	statement, err := repository.db.Prepare("delete from follow_requests where id = ?")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(requestID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Cancel removes the pending request of the follower to follow the user, if there is one
+func (repository FollowRequests) Cancel(userID, followerID uint64) error {
+	statement, err := repository.db.Prepare("delete from follow_requests where user_id = ? and follower_id = ?")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(userID, followerID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func scanFollowRequest(rows *sql.Rows) (models.FollowRequest, error) {
+	var request models.FollowRequest
+
+	if err := rows.Scan(
+		&request.ID,
+		&request.UserID,
+		&request.CreatedAt,
+		&request.Follower.ID,
+		&request.Follower.Name,
+		&request.Follower.Nick,
+		&request.Follower.Email,
+		&request.Follower.CreatedAt,
+	); err != nil {
+		return models.FollowRequest{}, err
+	}
+
+	return request, nil
+}
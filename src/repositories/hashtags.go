@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"api/src/models"
+	"database/sql"
+	"time"
+)
+
+// Represent a hashtags repository
+type Hashtags struct {
+	db *sql.DB
+}
+
+// Create a hashtags repository
+func NewHashtagsRepository(db *sql.DB) *Hashtags {
+	return &Hashtags{db}
+}
+
+// Trending returns the tags used in the window, every use weighs half as much
+// each halfLife that passed since it happened
+func (repository Hashtags) Trending(window, halfLife time.Duration, limit int) ([]models.TrendingHashtag, error) {
+	rows, err := repository.db.Query(`
+	select tag, count(*), sum(pow(0.5, timestampdiff(second, created_at, now()) / ?)) as score
+	from post_hashtags
+	where created_at >= now() - interval ? second
+	group by tag
+	order by score desc, tag
+	limit ?`,
+		halfLife.Seconds(), int64(window.Seconds()), limit)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashtags []models.TrendingHashtag
+
+	for rows.Next() {
+		var hashtag models.TrendingHashtag
+
+		if err = rows.Scan(&hashtag.Tag, &hashtag.Posts, &hashtag.Score); err != nil {
+			return nil, err
+		}
+
+		hashtags = append(hashtags, hashtag)
+	}
+
+	return hashtags, nil
+}
@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"database/sql"
+	"time"
+)
+
+// Represent a login attempts repository
+type LoginAttempts struct {
+	db *sql.DB
+}
+
+// Create a login attempts repository
+func NewLoginAttemptsRepository(db *sql.DB) *LoginAttempts {
+	return &LoginAttempts{db}
+}
+
+// Inserts a login attempt into the database
+func (repository LoginAttempts) Create(attempt models.LoginAttempt) error {
+	statement, err := repository.db.Prepare(
+		"insert into login_attempts (user_id, email, ip, user_agent, succeeded, reason, created_at) values (?, ?, ?, ?, ?, ?, ?)",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(
+		attempt.UserID,
+		attempt.Email,
+		attempt.IP,
+		attempt.UserAgent,
+		attempt.Succeeded,
+		attempt.Reason,
+		attempt.CreatedAt,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SearchFailures returns the failed logins since the given time of the email and of the IP address,
+// a successful login clears the failures of the email but not the ones of the address
+func (repository LoginAttempts) SearchFailures(email, ip string, since time.Time) (models.LoginFailures, models.LoginFailures, error) {
+	account, err := repository.searchFailures(`select count(*), max(a.created_at) from login_attempts a
+	where a.email = ? and a.reason in (?, ?) and a.created_at > ?
+	and not exists(select 1 from login_attempts s where s.email = a.email and s.succeeded and s.id > a.id)`,
+		email, models.LoginInvalidCredentials, models.LoginInvalidTwoFactorCode, since)
+	if err != nil {
+		return models.LoginFailures{}, models.LoginFailures{}, err
+	}
+
+	address, err := repository.searchFailures(
+		"select count(*), max(created_at) from login_attempts where ip = ? and reason in (?, ?) and created_at > ?",
+		ip, models.LoginInvalidCredentials, models.LoginInvalidTwoFactorCode, since)
+	if err != nil {
+		return models.LoginFailures{}, models.LoginFailures{}, err
+	}
+
+	return account, address, nil
+}
+
+func (repository LoginAttempts) searchFailures(query string, arguments ...any) (models.LoginFailures, error) {
+	var failures models.LoginFailures
+	var last sql.NullTime
+	if err := repository.db.QueryRow(query, arguments...).Scan(&failures.Count, &last); err != nil {
+		return models.LoginFailures{}, err
+	}
+
+	failures.Last = last.Time
+	return failures, nil
+}
+
+// SearchByUser returns the login attempts of the user, most recent first
+func (repository LoginAttempts) SearchByUser(userID uint64, page pagination.Params) ([]models.LoginAttempt, error) {
+	rows, err := repository.db.Query(`select id, user_id, email, ip, user_agent, succeeded, reason, created_at
+	from login_attempts where user_id = ?
+	and (? = 0 or id < ?) order by id desc limit ?`,
+		userID, page.After, page.After, page.Fetch())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []models.LoginAttempt
+	for rows.Next() {
+		var attempt models.LoginAttempt
+		if err = rows.Scan(
+			&attempt.ID,
+			&attempt.UserID,
+			&attempt.Email,
+			&attempt.IP,
+			&attempt.UserAgent,
+			&attempt.Succeeded,
+			&attempt.Reason,
+			&attempt.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, nil
+}
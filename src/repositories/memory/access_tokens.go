@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/repositories"
+	"slices"
+	"time"
+)
+
+// AccessTokens is the in-memory implementation of repositories.AccessTokenStore
+type AccessTokens struct {
+	db *Database
+}
+
+var _ repositories.AccessTokenStore = (*AccessTokens)(nil)
+
+func (store AccessTokens) Create(token models.AccessToken) (uint64, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	store.db.nextAccessTokenID++
+	token.ID = store.db.nextAccessTokenID
+	token.Scopes = slices.Clone(token.Scopes)
+	token.LastUsedAt = nil
+	token.CreatedAt = time.Now()
+	store.db.accessTokens[token.ID] = token
+
+	return token.ID, nil
+}
+
+func (store AccessTokens) SearchByUser(userID uint64, page pagination.Params) ([]models.AccessToken, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	var tokens []models.AccessToken
+	for _, id := range sortedIDs(store.db.accessTokens) {
+		if token := store.db.accessTokens[id]; token.UserID == userID {
+			token.TokenHash = ""
+			tokens = append(tokens, token)
+		}
+	}
+
+	return pageDescending(tokens, page, accessTokenKey), nil
+}
+
+func (store AccessTokens) Delete(tokenID, userID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	if token, ok := store.db.accessTokens[tokenID]; !ok || token.UserID != userID {
+		return repositories.ErrNotFound
+	}
+
+	delete(store.db.accessTokens, tokenID)
+	return nil
+}
+
+func (store AccessTokens) Use(tokenHash string) (models.AccessToken, bool, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	now := time.Now()
+	for id, token := range store.db.accessTokens {
+		if token.TokenHash != tokenHash {
+			continue
+		}
+
+		user, ok := store.db.users[token.UserID]
+		if !ok || user.SuspendedAt != nil || (token.ExpiresAt != nil && !now.Before(*token.ExpiresAt)) {
+			return models.AccessToken{}, false, nil
+		}
+
+		token.LastUsedAt = &now
+		store.db.accessTokens[id] = token
+
+		token.TokenHash = ""
+		token.UserRole = user.Role
+		return token, true, nil
+	}
+
+	return models.AccessToken{}, false, nil
+}
+
+func accessTokenKey(token models.AccessToken) uint64 {
+	return token.ID
+}
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"api/src/models"
+	"api/src/repositories"
+	"time"
+)
+
+// Attachments is the in-memory implementation of repositories.AttachmentStore
+type Attachments struct {
+	db *Database
+}
+
+var _ repositories.AttachmentStore = (*Attachments)(nil)
+
+func (store Attachments) Create(attachment models.Attachment) (uint64, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	if _, found := store.db.posts[attachment.PostID]; !found {
+		return 0, repositories.ErrNotFound
+	}
+
+	count := 0
+	for _, saved := range store.db.attachments {
+		if saved.PostID == attachment.PostID {
+			count++
+		}
+	}
+
+	if count >= models.MaxAttachments {
+		return 0, repositories.ErrConflict
+	}
+
+	store.db.nextAttachmentID++
+	attachment.ID = store.db.nextAttachmentID
+	attachment.URL, attachment.ThumbnailURL = "", ""
+	attachment.CreatedAt = time.Now()
+	store.db.attachments[attachment.ID] = attachment
+
+	return attachment.ID, nil
+}
+
+func (store Attachments) SearchByID(attachmentID uint64) (models.Attachment, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	attachment, found := store.db.attachments[attachmentID]
+	if !found {
+		return models.Attachment{}, repositories.ErrNotFound
+	}
+
+	return attachment, nil
+}
+
+func (store Attachments) SearchByPosts(postIDs []uint64) ([]models.Attachment, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	wanted := make(map[uint64]bool, len(postIDs))
+	for _, postID := range postIDs {
+		wanted[postID] = true
+	}
+
+	return store.filter(func(attachment models.Attachment) bool {
+		return wanted[attachment.PostID]
+	}), nil
+}
+
+func (store Attachments) SearchByUser(userID uint64) ([]models.Attachment, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	return store.filter(func(attachment models.Attachment) bool {
+		return store.db.posts[attachment.PostID].AuthorID == userID
+	}), nil
+}
+
+func (store Attachments) Delete(attachmentID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	delete(store.db.attachments, attachmentID)
+	return nil
+}
+
+// filter returns the attachments that match in upload order, the lock must be held
+func (store Attachments) filter(keep func(attachment models.Attachment) bool) []models.Attachment {
+	var attachments []models.Attachment
+	for _, id := range sortedIDs(store.db.attachments) {
+		if attachment := store.db.attachments[id]; keep(attachment) {
+			attachments = append(attachments, attachment)
+		}
+	}
+
+	return attachments
+}
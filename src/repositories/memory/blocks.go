@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/repositories"
+	"time"
+)
+
+// Blocks is the in-memory implementation of repositories.BlockStore
+type Blocks struct {
+	db *Database
+}
+
+var _ repositories.BlockStore = (*Blocks)(nil)
+
+func (store Blocks) Block(userID, blockedID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	key := relation{userID, blockedID}
+	if _, ok := store.db.blocks[key]; !ok {
+		store.db.blocks[key] = time.Now()
+	}
+
+	delete(store.db.followers, follow{userID, blockedID})
+	delete(store.db.followers, follow{blockedID, userID})
+
+	for id, request := range store.db.followRequests {
+		if request.userID == userID && request.followerID == blockedID ||
+			request.userID == blockedID && request.followerID == userID {
+			delete(store.db.followRequests, id)
+		}
+	}
+
+	return nil
+}
+
+func (store Blocks) Unblock(userID, blockedID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	delete(store.db.blocks, relation{userID, blockedID})
+	return nil
+}
+
+func (store Blocks) IsBlocked(userID, otherID uint64) (bool, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	return store.db.blocked(userID, otherID), nil
+}
+
+func (store Blocks) SearchBlockedIDs(userID uint64) ([]uint64, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	var ids []uint64
+	for key := range store.db.blocks {
+		switch userID {
+		case key.userID:
+			ids = append(ids, key.otherID)
+		case key.otherID:
+			ids = append(ids, key.userID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (store Blocks) SearchBlocked(userID uint64, page pagination.Params) ([]models.User, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	return store.search(store.db.blocks, userID, page), nil
+}
+
+func (store Blocks) Mute(userID, mutedID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	key := relation{userID, mutedID}
+	if _, ok := store.db.mutes[key]; !ok {
+		store.db.mutes[key] = time.Now()
+	}
+
+	return nil
+}
+
+func (store Blocks) Unmute(userID, mutedID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	delete(store.db.mutes, relation{userID, mutedID})
+	return nil
+}
+
+func (store Blocks) SearchMuted(userID uint64, page pagination.Params) ([]models.User, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	return store.search(store.db.mutes, userID, page), nil
+}
+
+// search returns the users related to the user, the read lock must be held
+func (store Blocks) search(relations map[relation]time.Time, userID uint64, page pagination.Params) []models.User {
+	var users []models.User
+	for _, id := range sortedIDs(store.db.users) {
+		if _, ok := relations[relation{userID, id}]; ok {
+			users = append(users, publicUser(store.db.users[id]))
+		}
+	}
+
+	return pageAscending(users, page, userKey)
+}
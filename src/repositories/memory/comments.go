@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/repositories"
+	"time"
+)
+
+// Comments is the in-memory implementation of repositories.CommentStore
+type Comments struct {
+	db *Database
+}
+
+var _ repositories.CommentStore = (*Comments)(nil)
+
+func (store Comments) Create(comment models.Comment) (uint64, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	store.db.nextCommentID++
+	comment.ID = store.db.nextCommentID
+	comment.AuthorNick = ""
+	comment.CreatedAt = time.Now()
+	comment.UpdatedAt = nil
+	store.db.comments[comment.ID] = comment
+
+	return comment.ID, nil
+}
+
+func (store Comments) SearchByID(commentID uint64) (models.Comment, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	comment, ok := store.db.comments[commentID]
+	if !ok {
+		return models.Comment{}, repositories.ErrNotFound
+	}
+
+	return store.view(comment), nil
+}
+
+func (store Comments) SearchByPost(postID uint64, page pagination.Params) ([]models.Comment, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	var comments []models.Comment
+	for _, id := range sortedIDs(store.db.comments) {
+		if comment := store.db.comments[id]; comment.PostID == postID {
+			comments = append(comments, store.view(comment))
+		}
+	}
+
+	return pageAscending(comments, page, commentKey), nil
+}
+
+func (store Comments) Update(commentID uint64, comment models.Comment) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	saved, ok := store.db.comments[commentID]
+	if !ok {
+		return nil
+	}
+
+	now := time.Now()
+	saved.Content = comment.Content
+	saved.UpdatedAt = &now
+	store.db.comments[commentID] = saved
+	return nil
+}
+
+func (store Comments) Delete(commentID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	store.db.deleteComment(commentID)
+	return nil
+}
+
+// view fills in the author nick, the read lock must be held
+func (store Comments) view(comment models.Comment) models.Comment {
+	comment.AuthorNick = store.db.users[comment.AuthorID].Nick
+	return comment
+}
@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"api/src/models"
+	"api/src/repositories"
+	"time"
+)
+
+// EmailVerifications is the in-memory implementation of repositories.EmailVerificationStore
+type EmailVerifications struct {
+	db *Database
+}
+
+var _ repositories.EmailVerificationStore = (*EmailVerifications)(nil)
+
+func (store EmailVerifications) Create(verification models.EmailVerification) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	verification.UsedAt = nil
+	verification.CreatedAt = time.Now()
+	store.db.emailVerifications[verification.TokenHash] = verification
+	return nil
+}
+
+func (store EmailVerifications) SearchByToken(tokenHash string) (models.EmailVerification, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	verification, ok := store.db.emailVerifications[tokenHash]
+	if !ok {
+		return models.EmailVerification{}, repositories.ErrNotFound
+	}
+
+	return verification, nil
+}
+
+func (store EmailVerifications) Confirm(tokenHash string) (bool, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	now := time.Now()
+	verification, ok := store.db.emailVerifications[tokenHash]
+	user, exists := store.db.users[verification.UserID]
+	if !ok || !exists || verification.UsedAt != nil || !now.Before(verification.ExpiresAt) || user.Email != verification.Email {
+		return false, nil
+	}
+
+	user.EmailVerifiedAt = &now
+	store.db.users[user.ID] = user
+
+	for hash, other := range store.db.emailVerifications {
+		if other.UserID == user.ID && other.UsedAt == nil {
+			other.UsedAt = &now
+			store.db.emailVerifications[hash] = other
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/repositories"
+	"time"
+)
+
+// FollowRequests is the in-memory implementation of repositories.FollowRequestStore
+type FollowRequests struct {
+	db *Database
+}
+
+var _ repositories.FollowRequestStore = (*FollowRequests)(nil)
+
+func (store FollowRequests) Create(userID, followerID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	for _, request := range store.db.followRequests {
+		if request.userID == userID && request.followerID == followerID {
+			return nil
+		}
+	}
+
+	store.db.nextFollowRequestID++
+	store.db.followRequests[store.db.nextFollowRequestID] = followRequest{userID, followerID, time.Now()}
+	return nil
+}
+
+func (store FollowRequests) SearchByID(requestID uint64) (models.FollowRequest, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	request, ok := store.db.followRequests[requestID]
+	if !ok {
+		return models.FollowRequest{}, repositories.ErrNotFound
+	}
+
+	return store.view(requestID, request), nil
+}
+
+func (store FollowRequests) Search(userID uint64, page pagination.Params) ([]models.FollowRequest, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	var requests []models.FollowRequest
+	for _, id := range sortedIDs(store.db.followRequests) {
+		if request := store.db.followRequests[id]; request.userID == userID {
+			requests = append(requests, store.view(id, request))
+		}
+	}
+
+	return pageAscending(requests, page, followRequestKey), nil
+}
+
+func (store FollowRequests) Approve(requestID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	request, ok := store.db.followRequests[requestID]
+	if !ok {
+		return nil
+	}
+
+	store.db.followers[follow{request.userID, request.followerID}] = struct{}{}
+	delete(store.db.followRequests, requestID)
+	return nil
+}
+
+func (store FollowRequests) Delete(requestID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	delete(store.db.followRequests, requestID)
+	return nil
+}
+
+func (store FollowRequests) Cancel(userID, followerID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	for id, request := range store.db.followRequests {
+		if request.userID == userID && request.followerID == followerID {
+			delete(store.db.followRequests, id)
+		}
+	}
+
+	return nil
+}
+
+// view builds the request as the SQL repository returns it, the read lock must be held
+func (store FollowRequests) view(requestID uint64, request followRequest) models.FollowRequest {
+	return models.FollowRequest{
+		ID:        requestID,
+		UserID:    request.userID,
+		Follower:  publicUser(store.db.users[request.followerID]),
+		CreatedAt: request.createdAt,
+	}
+}
+
+func followRequestKey(request models.FollowRequest) uint64 {
+	return request.ID
+}
@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"api/src/models"
+	"api/src/repositories"
+	"math"
+	"sort"
+	"time"
+)
+
+// Hashtags is the in-memory implementation of repositories.HashtagStore
+type Hashtags struct {
+	db *Database
+}
+
+var _ repositories.HashtagStore = (*Hashtags)(nil)
+
+func (store Hashtags) Trending(window, halfLife time.Duration, limit int) ([]models.TrendingHashtag, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	now := time.Now()
+	byTag := map[string]*models.TrendingHashtag{}
+	for key, usedAt := range store.db.hashtags {
+		age := now.Sub(usedAt)
+		if age > window {
+			continue
+		}
+
+		trending, found := byTag[key.tag]
+		if !found {
+			trending = &models.TrendingHashtag{Tag: key.tag}
+			byTag[key.tag] = trending
+		}
+
+		trending.Posts++
+		trending.Score += math.Pow(0.5, age.Seconds()/halfLife.Seconds())
+	}
+
+	hashtags := make([]models.TrendingHashtag, 0, len(byTag))
+	for _, trending := range byTag {
+		hashtags = append(hashtags, *trending)
+	}
+
+	sort.Slice(hashtags, func(i, j int) bool {
+		if hashtags[i].Score != hashtags[j].Score {
+			return hashtags[i].Score > hashtags[j].Score
+		}
+		return hashtags[i].Tag < hashtags[j].Tag
+	})
+
+	return hashtags[:min(len(hashtags), limit)], nil
+}
@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/repositories"
+	"time"
+)
+
+// LoginAttempts is the in-memory implementation of repositories.LoginAttemptStore
+type LoginAttempts struct {
+	db *Database
+}
+
+var _ repositories.LoginAttemptStore = (*LoginAttempts)(nil)
+
+func (store LoginAttempts) Create(attempt models.LoginAttempt) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	store.db.nextLoginAttemptID++
+	attempt.ID = store.db.nextLoginAttemptID
+	if attempt.CreatedAt.IsZero() {
+		attempt.CreatedAt = time.Now()
+	}
+	store.db.loginAttempts = append(store.db.loginAttempts, attempt)
+	return nil
+}
+
+func (store LoginAttempts) SearchFailures(email, ip string, since time.Time) (models.LoginFailures, models.LoginFailures, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	var account, address models.LoginFailures
+	for _, attempt := range store.db.loginAttempts {
+		if attempt.Email == email && attempt.Succeeded {
+			account = models.LoginFailures{}
+		}
+
+		if !attempt.Failed() || !attempt.CreatedAt.After(since) {
+			continue
+		}
+
+		if attempt.Email == email {
+			account.Count++
+			account.Last = attempt.CreatedAt
+		}
+
+		if attempt.IP == ip {
+			address.Count++
+			address.Last = attempt.CreatedAt
+		}
+	}
+
+	return account, address, nil
+}
+
+func (store LoginAttempts) SearchByUser(userID uint64, page pagination.Params) ([]models.LoginAttempt, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	var attempts []models.LoginAttempt
+	for _, attempt := range store.db.loginAttempts {
+		if attempt.UserID != nil && *attempt.UserID == userID {
+			attempts = append(attempts, attempt)
+		}
+	}
+
+	return pageDescending(attempts, page, loginAttemptKey), nil
+}
+
+func loginAttemptKey(attempt models.LoginAttempt) uint64 {
+	return attempt.ID
+}
@@ -0,0 +1,378 @@
+// Package memory implements the repository stores in memory, it is meant for
+// tests and local runs that should not depend on MySQL
+package memory
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Database holds the state shared by the in-memory stores, every store
+// created from the same database sees the same rows
+type Database struct {
+	mu sync.RWMutex
+
+	users      map[uint64]models.User
+	nextUserID uint64
+	followers  map[follow]struct{}
+
+	followRequests      map[uint64]followRequest
+	nextFollowRequestID uint64
+
+	blocks map[relation]time.Time
+	mutes  map[relation]time.Time
+
+	posts      map[uint64]models.Post
+	nextPostID uint64
+	likes      map[like]time.Time
+	hashtags   map[hashtag]time.Time
+	mentions   map[uint64][]models.Mention
+
+	attachments      map[uint64]models.Attachment
+	nextAttachmentID uint64
+
+	comments      map[uint64]models.Comment
+	nextCommentID uint64
+
+	sessions map[string]models.Session
+
+	emailVerifications map[string]models.EmailVerification
+	passwordResets     map[string]models.PasswordReset
+
+	loginAttempts      []models.LoginAttempt
+	nextLoginAttemptID uint64
+
+	twoFactor       map[uint64]models.TwoFactor
+	recoveryCodes   map[uint64]map[string]bool
+	loginChallenges map[string]models.LoginChallenge
+
+	accessTokens      map[uint64]models.AccessToken
+	nextAccessTokenID uint64
+
+	notifications      map[uint64]models.Notification
+	nextNotificationID uint64
+
+	conversations      map[uint64]conversation
+	nextConversationID uint64
+	participants       map[participant]participantState
+	messages           map[uint64]models.Message
+	nextMessageID      uint64
+}
+
+type follow struct {
+	userID, followerID uint64
+}
+
+type followRequest struct {
+	userID, followerID uint64
+	createdAt          time.Time
+}
+
+// relation is a block or a mute of the other user by the user
+type relation struct {
+	userID, otherID uint64
+}
+
+type like struct {
+	postID, userID uint64
+}
+
+type hashtag struct {
+	postID uint64
+	tag    string
+}
+
+// New creates an empty in-memory database
+func New() *Database {
+	return &Database{
+		users:     map[uint64]models.User{},
+		followers: map[follow]struct{}{},
+		posts:     map[uint64]models.Post{},
+		likes:     map[like]time.Time{},
+		hashtags:  map[hashtag]time.Time{},
+		mentions:  map[uint64][]models.Mention{},
+		comments:  map[uint64]models.Comment{},
+		sessions:  map[string]models.Session{},
+
+		emailVerifications: map[string]models.EmailVerification{},
+		passwordResets:     map[string]models.PasswordReset{},
+
+		twoFactor:       map[uint64]models.TwoFactor{},
+		recoveryCodes:   map[uint64]map[string]bool{},
+		loginChallenges: map[string]models.LoginChallenge{},
+
+		accessTokens: map[uint64]models.AccessToken{},
+
+		attachments: map[uint64]models.Attachment{},
+
+		followRequests: map[uint64]followRequest{},
+		blocks:         map[relation]time.Time{},
+		mutes:          map[relation]time.Time{},
+		notifications:  map[uint64]models.Notification{},
+
+		conversations: map[uint64]conversation{},
+		participants:  map[participant]participantState{},
+		messages:      map[uint64]models.Message{},
+	}
+}
+
+// Users returns the store of users backed by the database
+func (db *Database) Users() *Users {
+	return &Users{db}
+}
+
+// Posts returns the store of posts backed by the database
+func (db *Database) Posts() *Posts {
+	return &Posts{db}
+}
+
+// Comments returns the store of comments backed by the database
+func (db *Database) Comments() *Comments {
+	return &Comments{db}
+}
+
+// Sessions returns the store of sessions backed by the database
+func (db *Database) Sessions() *Sessions {
+	return &Sessions{db}
+}
+
+// EmailVerifications returns the store of email confirmation tokens backed by the database
+func (db *Database) EmailVerifications() *EmailVerifications {
+	return &EmailVerifications{db}
+}
+
+// PasswordResets returns the store of password reset tokens backed by the database
+func (db *Database) PasswordResets() *PasswordResets {
+	return &PasswordResets{db}
+}
+
+// LoginAttempts returns the audit trail of the logins backed by the database
+func (db *Database) LoginAttempts() *LoginAttempts {
+	return &LoginAttempts{db}
+}
+
+// TwoFactor returns the store of two-factor authentication settings backed by the database
+func (db *Database) TwoFactor() *TwoFactor {
+	return &TwoFactor{db}
+}
+
+// AccessTokens returns the store of personal access tokens backed by the database
+func (db *Database) AccessTokens() *AccessTokens {
+	return &AccessTokens{db}
+}
+
+// Notifications returns the store of notifications backed by the database
+func (db *Database) Notifications() *Notifications {
+	return &Notifications{db}
+}
+
+// Messages returns the store of direct messages backed by the database
+func (db *Database) Messages() *Messages {
+	return &Messages{db}
+}
+
+// FollowRequests returns the store of follow requests backed by the database
+func (db *Database) FollowRequests() *FollowRequests {
+	return &FollowRequests{db}
+}
+
+// Blocks returns the store of blocks and mutes backed by the database
+func (db *Database) Blocks() *Blocks {
+	return &Blocks{db}
+}
+
+// Hashtags returns the store of hashtags backed by the database
+func (db *Database) Hashtags() *Hashtags {
+	return &Hashtags{db}
+}
+
+// Attachments returns the store of post attachments backed by the database
+func (db *Database) Attachments() *Attachments {
+	return &Attachments{db}
+}
+
+// blocked reports whether either of the users blocked the other, the lock must be held
+func (db *Database) blocked(userID, otherID uint64) bool {
+	_, blocked := db.blocks[relation{userID, otherID}]
+	_, blockedBy := db.blocks[relation{otherID, userID}]
+	return blocked || blockedBy
+}
+
+// sortedIDs returns the keys of the map in ascending order
+func sortedIDs[T any](rows map[uint64]T) []uint64 {
+	ids := make([]uint64, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// sortByID orders the items by ascending ID
+func sortByID[T any](items []T, id func(T) uint64) {
+	sort.Slice(items, func(i, j int) bool { return id(items[i]) < id(items[j]) })
+}
+
+// pageAscending keeps the items after the cursor, in ascending ID order
+func pageAscending[T any](items []T, page pagination.Params, id func(T) uint64) []T {
+	var result []T
+	for _, item := range items {
+		if id(item) > page.After {
+			result = append(result, item)
+		}
+
+		if len(result) == page.Fetch() {
+			break
+		}
+	}
+
+	return result
+}
+
+// pageDescending keeps the items before the cursor, in descending ID order
+func pageDescending[T any](items []T, page pagination.Params, id func(T) uint64) []T {
+	var result []T
+	for i := len(items) - 1; i >= 0; i-- {
+		if page.After == 0 || id(items[i]) < page.After {
+			result = append(result, items[i])
+		}
+
+		if len(result) == page.Fetch() {
+			break
+		}
+	}
+
+	return result
+}
+
+func userKey(user models.User) uint64 {
+	return user.ID
+}
+
+func postKey(post models.Post) uint64 {
+	return post.ID
+}
+
+func commentKey(comment models.Comment) uint64 {
+	return comment.ID
+}
+
+func messageKey(message models.Message) uint64 {
+	return message.ID
+}
+
+func notificationKey(notification models.Notification) uint64 {
+	return notification.ID
+}
+
+// publicUser returns the columns the SQL repositories select when listing users
+func publicUser(user models.User) models.User {
+	return models.User{
+		ID:        user.ID,
+		Name:      user.Name,
+		Nick:      user.Nick,
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+// deletePost removes the post with its likes, comments and attachments, the lock must be held
+func (db *Database) deletePost(postID uint64) {
+	delete(db.posts, postID)
+
+	for id, attachment := range db.attachments {
+		if attachment.PostID == postID {
+			delete(db.attachments, id)
+		}
+	}
+
+	for key := range db.hashtags {
+		if key.postID == postID {
+			delete(db.hashtags, key)
+		}
+	}
+
+	delete(db.mentions, postID)
+
+	for key := range db.likes {
+		if key.postID == postID {
+			delete(db.likes, key)
+		}
+	}
+
+	for id, comment := range db.comments {
+		if comment.PostID == postID {
+			delete(db.comments, id)
+		}
+	}
+
+	for id, notification := range db.notifications {
+		if notification.PostID != nil && *notification.PostID == postID {
+			delete(db.notifications, id)
+		}
+	}
+}
+
+// syncHashtags makes the tags of the post match the given ones, the lock must be held
+func (db *Database) syncHashtags(postID uint64, tags []string) {
+	keep := map[string]bool{}
+	for _, tag := range tags {
+		keep[tag] = true
+		if _, found := db.hashtags[hashtag{postID, tag}]; !found {
+			db.hashtags[hashtag{postID, tag}] = time.Now()
+		}
+	}
+
+	for key := range db.hashtags {
+		if key.postID == postID && !keep[key.tag] {
+			delete(db.hashtags, key)
+		}
+	}
+}
+
+// syncMentions replaces the mentions of the post, the lock must be held
+func (db *Database) syncMentions(postID uint64, mentions []models.Mention) {
+	if len(mentions) == 0 {
+		delete(db.mentions, postID)
+		return
+	}
+
+	saved := make([]models.Mention, 0, len(mentions))
+	for _, mention := range mentions {
+		saved = append(saved, models.Mention{PostID: postID, UserID: mention.UserID, Start: mention.Start, End: mention.End})
+	}
+
+	db.mentions[postID] = saved
+}
+
+// deleteComment removes the comment and its replies, the lock must be held
+func (db *Database) deleteComment(commentID uint64) {
+	delete(db.comments, commentID)
+
+	for id, comment := range db.comments {
+		if comment.ParentCommentID != nil && *comment.ParentCommentID == commentID {
+			db.deleteComment(id)
+		}
+	}
+}
+
+// deleteConversation removes the conversation with its participants and messages, the lock must be held
+func (db *Database) deleteConversation(conversationID uint64) {
+	delete(db.conversations, conversationID)
+
+	for key := range db.participants {
+		if key.conversationID == conversationID {
+			delete(db.participants, key)
+		}
+	}
+
+	for id, message := range db.messages {
+		if message.ConversationID == conversationID {
+			delete(db.messages, id)
+		}
+	}
+}
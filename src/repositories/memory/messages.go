@@ -0,0 +1,217 @@
+package memory
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/repositories"
+	"time"
+)
+
+// Messages is the in-memory implementation of repositories.MessageStore
+type Messages struct {
+	db *Database
+}
+
+var _ repositories.MessageStore = (*Messages)(nil)
+
+type conversation struct {
+	id               uint64
+	userOne, userTwo uint64
+	lastMessageID    uint64
+	createdAt        time.Time
+}
+
+type participant struct {
+	conversationID, userID uint64
+}
+
+type participantState struct {
+	lastReadMessageID uint64
+	muted             bool
+}
+
+func (store Messages) StartConversation(message models.Message, recipientID uint64) (uint64, uint64, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	conversationID, ok := store.between(message.SenderID, recipientID)
+	if !ok {
+		store.db.nextConversationID++
+		conversationID = store.db.nextConversationID
+		store.db.conversations[conversationID] = conversation{
+			id:        conversationID,
+			userOne:   min(message.SenderID, recipientID),
+			userTwo:   max(message.SenderID, recipientID),
+			createdAt: time.Now(),
+		}
+		store.db.participants[participant{conversationID, message.SenderID}] = participantState{}
+		store.db.participants[participant{conversationID, recipientID}] = participantState{}
+	}
+
+	message.ConversationID = conversationID
+	return conversationID, store.insert(message), nil
+}
+
+func (store Messages) SearchConversationBetween(userID, otherUserID uint64) (uint64, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	conversationID, ok := store.between(userID, otherUserID)
+	if !ok {
+		return 0, repositories.ErrNotFound
+	}
+
+	return conversationID, nil
+}
+
+func (store Messages) SearchConversation(conversationID, userID uint64) (models.Conversation, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	saved, ok := store.db.conversations[conversationID]
+	if !ok || (saved.userOne != userID && saved.userTwo != userID) {
+		return models.Conversation{}, repositories.ErrNotFound
+	}
+
+	return store.view(saved, userID), nil
+}
+
+func (store Messages) SearchConversations(userID uint64, page pagination.Params) ([]models.Conversation, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	var conversations []models.Conversation
+	for _, saved := range store.db.conversations {
+		if saved.lastMessageID != 0 && (saved.userOne == userID || saved.userTwo == userID) {
+			conversations = append(conversations, store.view(saved, userID))
+		}
+	}
+
+	sortByID(conversations, conversationKey)
+	return pageDescending(conversations, page, conversationKey), nil
+}
+
+func (store Messages) Create(message models.Message) (uint64, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	if _, ok := store.db.conversations[message.ConversationID]; !ok {
+		return 0, nil
+	}
+
+	return store.insert(message), nil
+}
+
+// insert saves the message as the last one of its conversation, read by its sender, the lock must be held
+func (store Messages) insert(message models.Message) uint64 {
+	saved := store.db.conversations[message.ConversationID]
+
+	store.db.nextMessageID++
+	message.ID = store.db.nextMessageID
+	message.CreatedAt = time.Now()
+	store.db.messages[message.ID] = message
+
+	saved.lastMessageID = message.ID
+	store.db.conversations[saved.id] = saved
+
+	key := participant{saved.id, message.SenderID}
+	state := store.db.participants[key]
+	state.lastReadMessageID = message.ID
+	store.db.participants[key] = state
+
+	return message.ID
+}
+
+func (store Messages) Search(conversationID uint64, page pagination.Params) ([]models.Message, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	var messages []models.Message
+	for _, id := range sortedIDs(store.db.messages) {
+		if message := store.db.messages[id]; message.ConversationID == conversationID {
+			messages = append(messages, message)
+		}
+	}
+
+	return pageDescending(messages, page, messageKey), nil
+}
+
+func (store Messages) MarkRead(conversationID, userID, messageID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	key := participant{conversationID, userID}
+	if state, ok := store.db.participants[key]; ok {
+		state.lastReadMessageID = max(state.lastReadMessageID, messageID)
+		store.db.participants[key] = state
+	}
+
+	return nil
+}
+
+func (store Messages) SetMuted(conversationID, userID uint64, muted bool) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	key := participant{conversationID, userID}
+	if state, ok := store.db.participants[key]; ok {
+		state.muted = muted
+		store.db.participants[key] = state
+	}
+
+	return nil
+}
+
+// between finds the conversation of the two users, the read lock must be held
+func (store Messages) between(userID, otherUserID uint64) (uint64, bool) {
+	userOne, userTwo := min(userID, otherUserID), max(userID, otherUserID)
+	for id, saved := range store.db.conversations {
+		if saved.userOne == userOne && saved.userTwo == userTwo {
+			return id, true
+		}
+	}
+
+	return 0, false
+}
+
+// view builds the conversation as seen by the user, the read lock must be held
+func (store Messages) view(saved conversation, userID uint64) models.Conversation {
+	otherUserID := saved.userOne
+	if otherUserID == userID {
+		otherUserID = saved.userTwo
+	}
+
+	other := store.db.users[otherUserID]
+	mine := store.db.participants[participant{saved.id, userID}]
+	theirs := store.db.participants[participant{saved.id, otherUserID}]
+
+	result := models.Conversation{
+		ID:                           saved.id,
+		Participant:                  models.User{ID: other.ID, Name: other.Name, Nick: other.Nick},
+		LastReadMessageID:            mine.lastReadMessageID,
+		ParticipantLastReadMessageID: theirs.lastReadMessageID,
+		Muted:                        mine.muted,
+		CreatedAt:                    saved.createdAt,
+	}
+
+	if message, ok := store.db.messages[saved.lastMessageID]; ok {
+		result.LastMessage = &message
+	}
+
+	for _, message := range store.db.messages {
+		if message.ConversationID == saved.id && message.SenderID != userID && message.ID > mine.lastReadMessageID {
+			result.UnreadCount++
+		}
+	}
+
+	return result
+}
+
+// conversationKey orders conversations by their latest message, like the cursor of the SQL repository
+func conversationKey(conversation models.Conversation) uint64 {
+	if conversation.LastMessage == nil {
+		return 0
+	}
+
+	return conversation.LastMessage.ID
+}
@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/repositories"
+	"time"
+)
+
+// Notifications is the in-memory implementation of repositories.NotificationStore
+type Notifications struct {
+	db *Database
+}
+
+var _ repositories.NotificationStore = (*Notifications)(nil)
+
+// group identifies the events that are listed as a single notification
+type group struct {
+	kind   string
+	postID uint64
+	read   bool
+}
+
+func groupOf(notification models.Notification) group {
+	key := group{kind: notification.Type, read: notification.Read}
+	if notification.PostID != nil {
+		key.postID = *notification.PostID
+	}
+
+	return key
+}
+
+func (store Notifications) Create(notification models.Notification) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	store.db.nextNotificationID++
+	store.db.notifications[store.db.nextNotificationID] = models.Notification{
+		ID:        store.db.nextNotificationID,
+		UserID:    notification.UserID,
+		ActorID:   notification.ActorID,
+		Type:      notification.Type,
+		PostID:    notification.PostID,
+		CreatedAt: time.Now(),
+	}
+
+	return nil
+}
+
+func (store Notifications) Search(userID uint64, unreadOnly bool, page pagination.Params) ([]models.Notification, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	groups := map[group]*models.Notification{}
+	actors := map[group]map[uint64]struct{}{}
+	var order []group
+
+	for _, id := range sortedIDs(store.db.notifications) {
+		event := store.db.notifications[id]
+		if event.UserID != userID || (unreadOnly && event.Read) {
+			continue
+		}
+
+		key := groupOf(event)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+			actors[key] = map[uint64]struct{}{}
+		}
+
+		notification := event
+		groups[key] = &notification
+		actors[key][event.ActorID] = struct{}{}
+	}
+
+	var notifications []models.Notification
+	for _, key := range order {
+		notification := *groups[key]
+		notification.ActorNick = store.db.users[notification.ActorID].Nick
+		notification.ActorCount = uint64(len(actors[key]))
+		notification.Describe()
+		notifications = append(notifications, notification)
+	}
+
+	sortByID(notifications, notificationKey)
+	return pageDescending(notifications, page, notificationKey), nil
+}
+
+func (store Notifications) MarkRead(userID uint64, notificationIDs []uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	marked := map[uint64]bool{}
+	for _, id := range notificationIDs {
+		if selected, ok := store.db.notifications[id]; ok && selected.UserID == userID {
+			for eventID, event := range store.db.notifications {
+				if eventID <= id && event.UserID == userID && groupOf(event) == groupOf(selected) {
+					marked[eventID] = true
+				}
+			}
+		}
+	}
+
+	for id, event := range store.db.notifications {
+		if event.UserID == userID && !event.Read && (len(notificationIDs) == 0 || marked[id]) {
+			event.Read = true
+			store.db.notifications[id] = event
+		}
+	}
+
+	return nil
+}
+
+func (store Notifications) CountUnread(userID uint64) (uint64, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	groups := map[group]struct{}{}
+	for _, event := range store.db.notifications {
+		if event.UserID == userID && !event.Read {
+			groups[groupOf(event)] = struct{}{}
+		}
+	}
+
+	return uint64(len(groups)), nil
+}
@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"api/src/models"
+	"api/src/repositories"
+	"time"
+)
+
+// PasswordResets is the in-memory implementation of repositories.PasswordResetStore
+type PasswordResets struct {
+	db *Database
+}
+
+var _ repositories.PasswordResetStore = (*PasswordResets)(nil)
+
+func (store PasswordResets) Create(reset models.PasswordReset) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	reset.UsedAt = nil
+	reset.CreatedAt = time.Now()
+	store.db.passwordResets[reset.TokenHash] = reset
+	return nil
+}
+
+func (store PasswordResets) Consume(tokenHash string) (uint64, bool, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	now := time.Now()
+	reset, ok := store.db.passwordResets[tokenHash]
+	user, exists := store.db.users[reset.UserID]
+	if !ok || !exists || reset.UsedAt != nil || !now.Before(reset.ExpiresAt) || user.Email != reset.Email {
+		return 0, false, nil
+	}
+
+	for hash, other := range store.db.passwordResets {
+		if other.UserID == user.ID && other.UsedAt == nil {
+			other.UsedAt = &now
+			store.db.passwordResets[hash] = other
+		}
+	}
+
+	return user.ID, true, nil
+}
@@ -0,0 +1,310 @@
+package memory
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/repositories"
+	"api/src/search"
+	"math"
+	"sort"
+	"time"
+)
+
+// Posts is the in-memory implementation of repositories.PostStore
+type Posts struct {
+	db *Database
+}
+
+var _ repositories.PostStore = (*Posts)(nil)
+
+func (store Posts) Create(post models.Post) (uint64, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	store.db.nextPostID++
+	post.ID = store.db.nextPostID
+	post.CreatedAt = time.Now()
+	store.db.posts[post.ID] = models.Post{
+		ID:        post.ID,
+		Title:     post.Title,
+		Content:   post.Content,
+		AuthorID:  post.AuthorID,
+		CreatedAt: post.CreatedAt,
+	}
+	store.db.syncHashtags(post.ID, post.Hashtags)
+	store.db.syncMentions(post.ID, post.Mentions)
+
+	return post.ID, nil
+}
+
+func (store Posts) SearchByID(postID, viewerID uint64) (models.Post, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	post, ok := store.db.posts[postID]
+	if !ok {
+		return models.Post{}, repositories.ErrNotFound
+	}
+
+	return store.view(post, viewerID), nil
+}
+
+func (store Posts) Search(userID uint64, page pagination.Params) ([]models.Post, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	return store.filter(userID, page, func(post models.Post) bool {
+		_, following := store.db.followers[follow{post.AuthorID, userID}]
+		_, muted := store.db.mutes[relation{userID, post.AuthorID}]
+		return post.AuthorID == userID || following && !muted
+	}), nil
+}
+
+func (store Posts) Update(postID uint64, post models.Post) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	saved, ok := store.db.posts[postID]
+	if !ok {
+		return nil
+	}
+
+	saved.Title, saved.Content = post.Title, post.Content
+	store.db.posts[postID] = saved
+	store.db.syncHashtags(postID, post.Hashtags)
+	store.db.syncMentions(postID, post.Mentions)
+	return nil
+}
+
+func (store Posts) Delete(postID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	store.db.deletePost(postID)
+	return nil
+}
+
+func (store Posts) SearchByUser(userID, viewerID uint64, page pagination.Params) ([]models.Post, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	return store.filter(viewerID, page, func(post models.Post) bool {
+		return post.AuthorID == userID
+	}), nil
+}
+
+func (store Posts) SearchByHashtag(tag string, viewerID uint64, page pagination.Params) ([]models.Post, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	return store.filter(viewerID, page, func(post models.Post) bool {
+		_, tagged := store.db.hashtags[hashtag{post.ID, tag}]
+		return tagged && store.visible(post, viewerID)
+	}), nil
+}
+
+func (store Posts) SearchByMention(userID, viewerID uint64, page pagination.Params) ([]models.Post, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	return store.filter(viewerID, page, func(post models.Post) bool {
+		for _, mention := range store.db.mentions[post.ID] {
+			if mention.UserID == userID {
+				return store.visible(post, viewerID)
+			}
+		}
+		return false
+	}), nil
+}
+
+func (store Posts) SearchMentions(postIDs []uint64) ([]models.Mention, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	var mentions []models.Mention
+	for _, postID := range postIDs {
+		for _, mention := range store.db.mentions[postID] {
+			mention.Nick = store.db.users[mention.UserID].Nick
+			mentions = append(mentions, mention)
+		}
+	}
+
+	return mentions, nil
+}
+
+// SearchText ranks the matching posts with TF-IDF, matches in the title count twice
+func (store Posts) SearchText(query search.Query, viewerID uint64, page pagination.Params) ([]models.PostSearchResult, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	type document struct {
+		post           models.Post
+		title, content []search.Token
+	}
+
+	var documents []document
+	for _, id := range sortedIDs(store.db.posts) {
+		post := store.db.posts[id]
+		documents = append(documents, document{post, search.Tokenize(post.Title), search.Tokenize(post.Content)})
+	}
+
+	// Terms and phrases are both clauses, a term only needs to start a word
+	type clause struct {
+		words  []string
+		prefix bool
+	}
+
+	var clauses []clause
+	for _, term := range query.Terms {
+		clauses = append(clauses, clause{[]string{term}, true})
+	}
+	for _, phrase := range query.Phrases {
+		clauses = append(clauses, clause{phrase, false})
+	}
+
+	frequencies := make([][]int, len(documents))
+	found := make([]int, len(clauses))
+	for i, document := range documents {
+		frequencies[i] = make([]int, len(clauses))
+		for j, clause := range clauses {
+			frequencies[i][j] = 2*search.Occurrences(document.title, clause.words, clause.prefix) +
+				search.Occurrences(document.content, clause.words, clause.prefix)
+			if frequencies[i][j] > 0 {
+				found[j]++
+			}
+		}
+	}
+
+	var results []models.PostSearchResult
+	for i, document := range documents {
+		post := document.post
+		if !store.visible(post, viewerID) ||
+			(query.AuthorID != 0 && post.AuthorID != query.AuthorID) ||
+			(!query.Since.IsZero() && post.CreatedAt.Before(query.Since)) ||
+			(!query.Until.IsZero() && !post.CreatedAt.Before(query.Until)) {
+			continue
+		}
+
+		excluded := false
+		for _, word := range query.Excluded {
+			excluded = excluded || search.Occurrences(document.title, []string{word}, false) > 0 ||
+				search.Occurrences(document.content, []string{word}, false) > 0
+		}
+		if excluded {
+			continue
+		}
+
+		score := 0.0
+		for j, frequency := range frequencies[i] {
+			if frequency == 0 {
+				score = 0
+				break
+			}
+			score += (1 + math.Log(float64(frequency))) * math.Log(1+float64(len(documents))/float64(found[j]))
+		}
+
+		if score > 0 {
+			results = append(results, models.PostSearchResult{Post: store.view(post, viewerID), Score: score})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Post.ID > results[j].Post.ID
+	})
+
+	if page.After >= uint64(len(results)) {
+		return nil, nil
+	}
+
+	results = results[page.After:min(len(results), int(page.After)+page.Fetch())]
+	for i := range results {
+		results[i].Rank = page.After + uint64(i) + 1
+	}
+
+	return results, nil
+}
+
+func (store Posts) Like(postID, userID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	if _, ok := store.db.posts[postID]; !ok {
+		return nil
+	}
+
+	key := like{postID, userID}
+	if _, ok := store.db.likes[key]; !ok {
+		store.db.likes[key] = time.Now()
+	}
+
+	return nil
+}
+
+func (store Posts) Dislike(postID, userID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	delete(store.db.likes, like{postID, userID})
+	return nil
+}
+
+func (store Posts) SearchLikes(postID uint64, page pagination.Params) ([]models.User, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	var users []models.User
+	for _, id := range sortedIDs(store.db.users) {
+		if _, liked := store.db.likes[like{postID, id}]; liked {
+			users = append(users, publicUser(store.db.users[id]))
+		}
+	}
+
+	return pageAscending(users, page, userKey), nil
+}
+
+// filter returns a page of the posts accepted by keep, newest first, the read lock must be held
+func (store Posts) filter(viewerID uint64, page pagination.Params, keep func(post models.Post) bool) []models.Post {
+	var posts []models.Post
+	for _, id := range sortedIDs(store.db.posts) {
+		if post := store.db.posts[id]; keep(post) {
+			posts = append(posts, store.view(post, viewerID))
+		}
+	}
+
+	return pageDescending(posts, page, postKey)
+}
+
+// visible reports whether the viewer can see the post, the posts of private
+// accounts are only shown to their followers and blocks hide the posts both
+// ways, the read lock must be held
+func (store Posts) visible(post models.Post, viewerID uint64) bool {
+	if store.db.blocked(post.AuthorID, viewerID) {
+		return false
+	}
+
+	_, following := store.db.followers[follow{post.AuthorID, viewerID}]
+	return !store.db.users[post.AuthorID].Private || post.AuthorID == viewerID || following
+}
+
+// view fills in the columns the SQL repository computes, the read lock must be held
+func (store Posts) view(post models.Post, viewerID uint64) models.Post {
+	post.AuthorNick = store.db.users[post.AuthorID].Nick
+
+	for key := range store.db.likes {
+		if key.postID == post.ID {
+			post.Likes++
+			post.LikedByMe = post.LikedByMe || key.userID == viewerID
+		}
+	}
+
+	for _, comment := range store.db.comments {
+		if comment.PostID == post.ID {
+			post.CommentCount++
+		}
+	}
+
+	return post
+}
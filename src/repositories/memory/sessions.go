@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"api/src/models"
+	"api/src/repositories"
+	"time"
+)
+
+// Sessions is the in-memory implementation of repositories.SessionStore
+type Sessions struct {
+	db *Database
+}
+
+var _ repositories.SessionStore = (*Sessions)(nil)
+
+func (store Sessions) Create(session models.Session) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	session.CreatedAt = time.Now()
+	store.db.sessions[session.ID] = session
+	return nil
+}
+
+func (store Sessions) SearchByRefreshToken(refreshTokenHash string) (models.Session, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	for _, session := range store.db.sessions {
+		if session.RefreshTokenHash == refreshTokenHash {
+			return session, nil
+		}
+	}
+
+	return models.Session{}, repositories.ErrNotFound
+}
+
+func (store Sessions) Rotate(sessionID, oldHash, newHash string, expiresAt time.Time) (bool, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	session, ok := store.db.sessions[sessionID]
+	if !ok || session.RefreshTokenHash != oldHash || session.RevokedAt != nil {
+		return false, nil
+	}
+
+	session.RefreshTokenHash = newHash
+	session.ExpiresAt = expiresAt
+	store.db.sessions[sessionID] = session
+	return true, nil
+}
+
+func (store Sessions) IsActive(sessionID string) (bool, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	session, ok := store.db.sessions[sessionID]
+	return ok && session.RevokedAt == nil && time.Now().Before(session.ExpiresAt), nil
+}
+
+func (store Sessions) Revoke(sessionID string) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	if session, ok := store.db.sessions[sessionID]; ok && session.RevokedAt == nil {
+		now := time.Now()
+		session.RevokedAt = &now
+		store.db.sessions[sessionID] = session
+	}
+
+	return nil
+}
+
+func (store Sessions) RevokeAllExcept(userID uint64, sessionID string) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	now := time.Now()
+	for id, session := range store.db.sessions {
+		if session.UserID == userID && id != sessionID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			store.db.sessions[id] = session
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package memory
+
+import (
+	"api/src/models"
+	"api/src/repositories"
+	"time"
+)
+
+// TwoFactor is the in-memory implementation of repositories.TwoFactorStore,
+// the recovery codes of a user map their hash to whether they were used
+type TwoFactor struct {
+	db *Database
+}
+
+var _ repositories.TwoFactorStore = (*TwoFactor)(nil)
+
+func (store TwoFactor) Search(userID uint64) (models.TwoFactor, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	twoFactor, ok := store.db.twoFactor[userID]
+	if !ok {
+		return models.TwoFactor{}, repositories.ErrNotFound
+	}
+
+	for _, used := range store.db.recoveryCodes[userID] {
+		if !used {
+			twoFactor.RecoveryCodesLeft++
+		}
+	}
+
+	return twoFactor, nil
+}
+
+func (store TwoFactor) Setup(userID uint64, secret string) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	if twoFactor, ok := store.db.twoFactor[userID]; ok && twoFactor.Enabled() {
+		return nil
+	}
+
+	store.db.twoFactor[userID] = models.TwoFactor{UserID: userID, Secret: secret}
+	return nil
+}
+
+func (store TwoFactor) Enable(userID uint64, step int64, recoveryCodeHashes []string) (bool, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	twoFactor, ok := store.db.twoFactor[userID]
+	if !ok || twoFactor.Enabled() {
+		return false, nil
+	}
+
+	now := time.Now()
+	twoFactor.EnabledAt, twoFactor.LastStep = &now, step
+	store.db.twoFactor[userID] = twoFactor
+
+	codes := map[string]bool{}
+	for _, codeHash := range recoveryCodeHashes {
+		codes[codeHash] = false
+	}
+	store.db.recoveryCodes[userID] = codes
+
+	return true, nil
+}
+
+func (store TwoFactor) Disable(userID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	delete(store.db.twoFactor, userID)
+	delete(store.db.recoveryCodes, userID)
+	return nil
+}
+
+func (store TwoFactor) UseStep(userID uint64, step int64) (bool, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	twoFactor, ok := store.db.twoFactor[userID]
+	if !ok || twoFactor.LastStep >= step {
+		return false, nil
+	}
+
+	twoFactor.LastStep = step
+	store.db.twoFactor[userID] = twoFactor
+	return true, nil
+}
+
+func (store TwoFactor) UseRecoveryCode(userID uint64, codeHash string) (bool, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	used, ok := store.db.recoveryCodes[userID][codeHash]
+	if !ok || used {
+		return false, nil
+	}
+
+	store.db.recoveryCodes[userID][codeHash] = true
+	return true, nil
+}
+
+func (store TwoFactor) CreateChallenge(challenge models.LoginChallenge) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	challenge.Attempts, challenge.UsedAt = 0, nil
+	store.db.loginChallenges[challenge.TokenHash] = challenge
+	return nil
+}
+
+func (store TwoFactor) AttemptChallenge(tokenHash string, maxAttempts int) (uint64, bool, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	challenge, ok := store.db.loginChallenges[tokenHash]
+	if !ok || challenge.UsedAt != nil || !time.Now().Before(challenge.ExpiresAt) || challenge.Attempts >= maxAttempts {
+		return 0, false, nil
+	}
+
+	challenge.Attempts++
+	store.db.loginChallenges[tokenHash] = challenge
+	return challenge.UserID, true, nil
+}
+
+func (store TwoFactor) ConsumeChallenge(tokenHash string) (bool, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	challenge, ok := store.db.loginChallenges[tokenHash]
+	if !ok || challenge.UsedAt != nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	challenge.UsedAt = &now
+	store.db.loginChallenges[tokenHash] = challenge
+	return true, nil
+}
@@ -0,0 +1,407 @@
+package memory
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/repositories"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Users is the in-memory implementation of repositories.UserStore
+type Users struct {
+	db *Database
+}
+
+var _ repositories.UserStore = (*Users)(nil)
+
+func (store Users) Create(user models.User) (uint64, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	if err := store.checkUnique(0, user); err != nil {
+		return 0, err
+	}
+
+	store.db.nextUserID++
+	user.ID = store.db.nextUserID
+	user.Role = models.RoleUser
+	user.SuspendedAt = nil
+	user.EmailVerifiedAt = nil
+	user.CreatedAt = time.Now()
+	store.db.users[user.ID] = user
+
+	return user.ID, nil
+}
+
+func (store Users) Search(nameOrNick string, viewerID uint64, page pagination.Params) ([]models.User, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	nameOrNick = strings.ToLower(nameOrNick)
+
+	var users []models.User
+	for _, id := range sortedIDs(store.db.users) {
+		user := store.db.users[id]
+		if store.db.blocked(user.ID, viewerID) {
+			continue
+		}
+
+		if strings.Contains(strings.ToLower(user.Name), nameOrNick) ||
+			strings.Contains(strings.ToLower(user.Nick), nameOrNick) {
+			users = append(users, publicUser(user))
+		}
+	}
+
+	return pageAscending(users, page, userKey), nil
+}
+
+func (store Users) SearchByID(userID uint64) (models.User, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	user, ok := store.db.users[userID]
+	if !ok {
+		return models.User{}, repositories.ErrNotFound
+	}
+
+	user.Password = ""
+	return user, nil
+}
+
+func (store Users) Update(ID uint64, user models.User) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	saved, ok := store.db.users[ID]
+	if !ok {
+		return nil
+	}
+
+	if err := store.checkUnique(ID, user); err != nil {
+		return err
+	}
+
+	if saved.Email != user.Email {
+		saved.EmailVerifiedAt = nil
+	}
+
+	saved.Name, saved.Nick, saved.Email = user.Name, user.Nick, user.Email
+	store.db.users[ID] = saved
+	return nil
+}
+
+// Delete removes the user and everything that references them, like the foreign keys do
+func (store Users) Delete(ID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	delete(store.db.users, ID)
+
+	for key := range store.db.followers {
+		if key.userID == ID || key.followerID == ID {
+			delete(store.db.followers, key)
+		}
+	}
+
+	for id, request := range store.db.followRequests {
+		if request.userID == ID || request.followerID == ID {
+			delete(store.db.followRequests, id)
+		}
+	}
+
+	for _, relations := range []map[relation]time.Time{store.db.blocks, store.db.mutes} {
+		for key := range relations {
+			if key.userID == ID || key.otherID == ID {
+				delete(relations, key)
+			}
+		}
+	}
+
+	for id, post := range store.db.posts {
+		if post.AuthorID == ID {
+			store.db.deletePost(id)
+		}
+	}
+
+	for key := range store.db.likes {
+		if key.userID == ID {
+			delete(store.db.likes, key)
+		}
+	}
+
+	for id, comment := range store.db.comments {
+		if comment.AuthorID == ID {
+			store.db.deleteComment(id)
+		}
+	}
+
+	for id, session := range store.db.sessions {
+		if session.UserID == ID {
+			delete(store.db.sessions, id)
+		}
+	}
+
+	for hash, verification := range store.db.emailVerifications {
+		if verification.UserID == ID {
+			delete(store.db.emailVerifications, hash)
+		}
+	}
+
+	for hash, reset := range store.db.passwordResets {
+		if reset.UserID == ID {
+			delete(store.db.passwordResets, hash)
+		}
+	}
+
+	for i, attempt := range store.db.loginAttempts {
+		if attempt.UserID != nil && *attempt.UserID == ID {
+			store.db.loginAttempts[i].UserID = nil
+		}
+	}
+
+	delete(store.db.twoFactor, ID)
+	delete(store.db.recoveryCodes, ID)
+
+	for hash, challenge := range store.db.loginChallenges {
+		if challenge.UserID == ID {
+			delete(store.db.loginChallenges, hash)
+		}
+	}
+
+	for id, token := range store.db.accessTokens {
+		if token.UserID == ID {
+			delete(store.db.accessTokens, id)
+		}
+	}
+
+	for id, notification := range store.db.notifications {
+		if notification.UserID == ID || notification.ActorID == ID {
+			delete(store.db.notifications, id)
+		}
+	}
+
+	for id, conversation := range store.db.conversations {
+		if conversation.userOne == ID || conversation.userTwo == ID {
+			store.db.deleteConversation(id)
+		}
+	}
+
+	for postID, mentions := range store.db.mentions {
+		var kept []models.Mention
+		for _, mention := range mentions {
+			if mention.UserID != ID {
+				kept = append(kept, mention)
+			}
+		}
+		store.db.syncMentions(postID, kept)
+	}
+
+	return nil
+}
+
+func (store Users) SearchByEmail(email string) (models.User, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	for _, user := range store.db.users {
+		if user.Email == email {
+			return models.User{
+				ID:              user.ID,
+				Email:           user.Email,
+				Password:        user.Password,
+				Role:            user.Role,
+				SuspendedAt:     user.SuspendedAt,
+				EmailVerifiedAt: user.EmailVerifiedAt,
+			}, nil
+		}
+	}
+
+	return models.User{}, nil
+}
+
+func (store Users) SearchByNicks(nicks []string) ([]models.User, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	var users []models.User
+	for _, id := range sortedIDs(store.db.users) {
+		user := store.db.users[id]
+		for _, nick := range nicks {
+			if strings.EqualFold(user.Nick, nick) {
+				users = append(users, publicUser(user))
+				break
+			}
+		}
+	}
+
+	return users, nil
+}
+
+func (store Users) Follow(userID, followerID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	store.db.followers[follow{userID, followerID}] = struct{}{}
+	return nil
+}
+
+func (store Users) Unfollow(userID, followerID uint64) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	delete(store.db.followers, follow{userID, followerID})
+	return nil
+}
+
+func (store Users) SearchFollowers(userID uint64, page pagination.Params) ([]models.User, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	var users []models.User
+	for _, id := range sortedIDs(store.db.users) {
+		if _, ok := store.db.followers[follow{userID, id}]; ok {
+			users = append(users, publicUser(store.db.users[id]))
+		}
+	}
+
+	return pageAscending(users, page, userKey), nil
+}
+
+func (store Users) SearchFollowing(userID uint64, page pagination.Params) ([]models.User, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	var users []models.User
+	for _, id := range sortedIDs(store.db.users) {
+		if _, ok := store.db.followers[follow{id, userID}]; ok {
+			users = append(users, publicUser(store.db.users[id]))
+		}
+	}
+
+	return pageAscending(users, page, userKey), nil
+}
+
+func (store Users) IsFollowing(userID, followerID uint64) (bool, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	_, following := store.db.followers[follow{userID, followerID}]
+	return following, nil
+}
+
+func (store Users) SearchFollowerIDs(userID uint64) ([]uint64, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	var followerIDs []uint64
+	for key := range store.db.followers {
+		if key.userID == userID {
+			followerIDs = append(followerIDs, key.followerID)
+		}
+	}
+
+	return followerIDs, nil
+}
+
+func (store Users) SearchPassword(userID uint64) (string, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	user, ok := store.db.users[userID]
+	if !ok {
+		return "", repositories.ErrNotFound
+	}
+
+	return user.Password, nil
+}
+
+func (store Users) UpdatePassword(userID uint64, password string) error {
+	return store.update(userID, func(user *models.User) {
+		user.Password = password
+	})
+}
+
+func (store Users) UpdateRole(userID uint64, role string) error {
+	return store.update(userID, func(user *models.User) {
+		user.Role = role
+	})
+}
+
+func (store Users) Suspend(userID uint64) error {
+	return store.update(userID, func(user *models.User) {
+		if user.SuspendedAt == nil {
+			now := time.Now()
+			user.SuspendedAt = &now
+		}
+	})
+}
+
+func (store Users) Unsuspend(userID uint64) error {
+	return store.update(userID, func(user *models.User) {
+		user.SuspendedAt = nil
+	})
+}
+
+func (store Users) UpdateMessageSettings(userID uint64, messagesFromAnyone bool) error {
+	return store.update(userID, func(user *models.User) {
+		user.MessagesFromAnyone = messagesFromAnyone
+	})
+}
+
+func (store Users) UpdatePrivacy(userID uint64, private bool) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	user, ok := store.db.users[userID]
+	if !ok {
+		return nil
+	}
+
+	user.Private = private
+	store.db.users[userID] = user
+
+	if !private {
+		for id, request := range store.db.followRequests {
+			if request.userID == userID {
+				store.db.followers[follow{request.userID, request.followerID}] = struct{}{}
+				delete(store.db.followRequests, id)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (store Users) IsEmailVerified(userID uint64) (bool, error) {
+	store.db.mu.RLock()
+	defer store.db.mu.RUnlock()
+
+	return store.db.users[userID].EmailVerifiedAt != nil, nil
+}
+
+func (store Users) update(userID uint64, change func(user *models.User)) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+
+	user, ok := store.db.users[userID]
+	if !ok {
+		return nil
+	}
+
+	change(&user)
+	store.db.users[userID] = user
+	return nil
+}
+
+// checkUnique mirrors the unique keys of the users table, the lock must be held
+func (store Users) checkUnique(ID uint64, user models.User) error {
+	for _, saved := range store.db.users {
+		if saved.ID != ID && (saved.Nick == user.Nick || saved.Email == user.Email) {
+			return fmt.Errorf("%w: %s", repositories.ErrConflict, "the nick or email is already in use")
+		}
+	}
+
+	return nil
+}
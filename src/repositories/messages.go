@@ -0,0 +1,296 @@
+package repositories
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"database/sql"
+	"time"
+)
+
+// Represent a direct messages repository
+type Messages struct {
+	db *sql.DB
+}
+
+// Create a direct messages repository
+func NewMessagesRepository(db *sql.DB) *Messages {
+	return &Messages{db}
+}
+
+// conversationColumns selects a conversation as seen by the participant bound
+// to the first placeholder, c, me, other, u and m must be joined by the query
+const conversationColumns = `c.id, c.created_at,
+	coalesce(me.last_read_message_id, 0), me.muted,
+	other.user_id, u.name, u.nick, coalesce(other.last_read_message_id, 0),
+	m.id, m.sender_id, m.content, m.created_at,
+	(select count(*) from messages x where x.conversation_id = c.id
+	and x.sender_id <> ? and x.id > coalesce(me.last_read_message_id, 0))`
+
+// conversationJoins joins the participants bound to the second and third
+// placeholders, the user and their counterpart
+const conversationJoins = `from conversations c
+	join conversation_participants me on me.conversation_id = c.id and me.user_id = ?
+	join conversation_participants other on other.conversation_id = c.id and other.user_id <> ?
+	join users u on u.id = other.user_id`
+
+// StartConversation saves the first message of the sender to the recipient together with
+// their conversation, the unique pair of users makes concurrent first messages share
+// the same one, and returns the IDs of the conversation and of the message
+func (repository Messages) StartConversation(message models.Message, recipientID uint64) (uint64, uint64, error) {
+	userOne, userTwo := min(message.SenderID, recipientID), max(message.SenderID, recipientID)
+
+	tx, err := repository.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(
+		"insert ignore into conversations (user_one_id, user_two_id) values (?, ?)", userOne, userTwo,
+	); err != nil {
+		return 0, 0, err
+	}
+
+	if err = tx.QueryRow(
+		"select id from conversations where user_one_id = ? and user_two_id = ?", userOne, userTwo,
+	).Scan(&message.ConversationID); err != nil {
+		return 0, 0, err
+	}
+
+	if _, err = tx.Exec(
+		"insert ignore into conversation_participants (conversation_id, user_id) values (?, ?), (?, ?)",
+		message.ConversationID, userOne, message.ConversationID, userTwo,
+	); err != nil {
+		return 0, 0, err
+	}
+
+	messageID, err := insertMessage(tx, message)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return message.ConversationID, messageID, tx.Commit()
+}
+
+// SearchConversationBetween returns the ID of the conversation between the two users
+func (repository Messages) SearchConversationBetween(userID, otherUserID uint64) (uint64, error) {
+	rows, err := repository.db.Query(
+		"select id from conversations where user_one_id = ? and user_two_id = ?",
+		min(userID, otherUserID), max(userID, otherUserID),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return 0, ErrNotFound
+	}
+
+	var conversationID uint64
+	if err = rows.Scan(&conversationID); err != nil {
+		return 0, err
+	}
+
+	return conversationID, nil
+}
+
+// SearchConversation returns the conversation as seen by the user, it is not
+// found when the user does not take part in it
+func (repository Messages) SearchConversation(conversationID, userID uint64) (models.Conversation, error) {
+	rows, err := repository.db.Query(`
+	select `+conversationColumns+` `+conversationJoins+`
+	left join messages m on m.id = c.last_message_id
+	where c.id = ?`, userID, userID, userID, conversationID,
+	)
+	if err != nil {
+		return models.Conversation{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return models.Conversation{}, ErrNotFound
+	}
+
+	return scanConversation(rows)
+}
+
+// SearchConversations returns the conversations of the user, the one with the
+// most recent message first, the cursor is the ID of that message
+func (repository Messages) SearchConversations(userID uint64, page pagination.Params) ([]models.Conversation, error) {
+	rows, err := repository.db.Query(`
+	select `+conversationColumns+` `+conversationJoins+`
+	join messages m on m.id = c.last_message_id
+	where (? = 0 or c.last_message_id < ?)
+	order by c.last_message_id desc limit ?`,
+		userID, userID, userID, page.After, page.After, page.Fetch(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conversations []models.Conversation
+
+	for rows.Next() {
+		conversation, err := scanConversation(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		conversations = append(conversations, conversation)
+	}
+
+	return conversations, nil
+}
+
+// Create inserts the message and moves the read marker of the sender past it
+func (repository Messages) Create(message models.Message) (uint64, error) {
+	tx, err := repository.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	messageID, err := insertMessage(tx, message)
+	if err != nil {
+		return 0, err
+	}
+
+	return messageID, tx.Commit()
+}
+
+// insertMessage saves the message as the last one of its conversation, read by its sender
+func insertMessage(tx *sql.Tx, message models.Message) (uint64, error) {
+	result, err := tx.Exec(
+		"insert into messages (conversation_id, sender_id, content) values (?, ?, ?)",
+		message.ConversationID, message.SenderID, message.Content,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	lastInsertedID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = tx.Exec(
+		"update conversations set last_message_id = ? where id = ?", lastInsertedID, message.ConversationID,
+	); err != nil {
+		return 0, err
+	}
+
+	if _, err = tx.Exec(
+		"update conversation_participants set last_read_message_id = ? where conversation_id = ? and user_id = ?",
+		lastInsertedID, message.ConversationID, message.SenderID,
+	); err != nil {
+		return 0, err
+	}
+
+	return uint64(lastInsertedID), nil
+}
+
+// Search returns the messages of the conversation, newest first
+func (repository Messages) Search(conversationID uint64, page pagination.Params) ([]models.Message, error) {
+	rows, err := repository.db.Query(`
+	select id, conversation_id, sender_id, content, created_at from messages
+	where conversation_id = ? and (? = 0 or id < ?)
+	order by id desc limit ?`, conversationID, page.After, page.After, page.Fetch(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.Message
+
+	for rows.Next() {
+		var message models.Message
+
+		if err = rows.Scan(
+			&message.ID,
+			&message.ConversationID,
+			&message.SenderID,
+			&message.Content,
+			&message.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// MarkRead moves the read marker of the user up to the message, it never moves back
+func (repository Messages) MarkRead(conversationID, userID, messageID uint64) error {
+	statement, err := repository.db.Prepare(`update conversation_participants
+	set last_read_message_id = greatest(coalesce(last_read_message_id, 0), ?)
+	where conversation_id = ? and user_id = ?`)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(messageID, conversationID, userID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetMuted changes whether the user is told about new messages of the conversation
+func (repository Messages) SetMuted(conversationID, userID uint64, muted bool) error {
+	statement, err := repository.db.Prepare(
+		"update conversation_participants set muted = ? where conversation_id = ? and user_id = ?",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(muted, conversationID, userID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func scanConversation(rows *sql.Rows) (models.Conversation, error) {
+	var conversation models.Conversation
+	var messageID, senderID *uint64
+	var content *string
+	var sentAt *time.Time
+
+	if err := rows.Scan(
+		&conversation.ID,
+		&conversation.CreatedAt,
+		&conversation.LastReadMessageID,
+		&conversation.Muted,
+		&conversation.Participant.ID,
+		&conversation.Participant.Name,
+		&conversation.Participant.Nick,
+		&conversation.ParticipantLastReadMessageID,
+		&messageID,
+		&senderID,
+		&content,
+		&sentAt,
+		&conversation.UnreadCount,
+	); err != nil {
+		return models.Conversation{}, err
+	}
+
+	if messageID != nil {
+		conversation.LastMessage = &models.Message{
+			ID:             *messageID,
+			ConversationID: conversation.ID,
+			SenderID:       *senderID,
+			Content:        *content,
+			CreatedAt:      *sentAt,
+		}
+	}
+
+	return conversation, nil
+}
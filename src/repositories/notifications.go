@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"database/sql"
+	"strings"
+)
+
+// Represent a notifications repository
+type Notifications struct {
+	db *sql.DB
+}
+
+// Create a notifications repository
+func NewNotificationsRepository(db *sql.DB) *Notifications {
+	return &Notifications{db}
+}
+
+// Create records one event for the user, events are grouped when they are listed
+func (repository Notifications) Create(notification models.Notification) error {
+	statement, err := repository.db.Prepare(
+		"insert into notifications (user_id, actor_id, type, post_id) values (?, ?, ?, ?)",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(
+		notification.UserID,
+		notification.ActorID,
+		notification.Type,
+		notification.PostID,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Search returns the notifications of the user newest first, events of the
+// same kind on the same post are grouped while they share the read state
+func (repository Notifications) Search(userID uint64, unreadOnly bool, page pagination.Params) ([]models.Notification, error) {
+	rows, err := repository.db.Query(`
+	select g.id, g.type, g.post_id, g.actors, g.is_read, g.created_at, l.actor_id, u.nick
+	from (
+		select max(n.id) id, n.type, n.post_id, count(distinct n.actor_id) actors,
+		n.read_at is not null is_read, max(n.created_at) created_at
+		from notifications n where n.user_id = ? and (? = false or n.read_at is null)
+		group by n.type, n.post_id, n.read_at is not null
+		having ? = 0 or max(n.id) < ?
+	) g
+	join notifications l on l.id = g.id
+	join users u on u.id = l.actor_id
+	order by g.id desc limit ?`,
+		userID, unreadOnly, page.After, page.After, page.Fetch(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+
+	for rows.Next() {
+		var notification models.Notification
+
+		if err = rows.Scan(
+			&notification.ID,
+			&notification.Type,
+			&notification.PostID,
+			&notification.ActorCount,
+			&notification.Read,
+			&notification.CreatedAt,
+			&notification.ActorID,
+			&notification.ActorNick,
+		); err != nil {
+			return nil, err
+		}
+
+		notification.UserID = userID
+		notification.Describe()
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, nil
+}
+
+// MarkRead marks the given notifications of the user as read, every event of
+// a group up to its ID is marked, an empty list marks everything
+func (repository Notifications) MarkRead(userID uint64, notificationIDs []uint64) error {
+	if len(notificationIDs) == 0 {
+		statement, err := repository.db.Prepare(
+			"update notifications set read_at = current_timestamp where user_id = ? and read_at is null",
+		)
+		if err != nil {
+			return err
+		}
+		defer statement.Close()
+
+		if _, err = statement.Exec(userID); err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	arguments := []any{userID}
+	for _, notificationID := range notificationIDs {
+		arguments = append(arguments, notificationID)
+	}
+
+	statement, err := repository.db.Prepare(`
+	update notifications n join notifications g
+	on g.user_id = n.user_id and g.type = n.type and g.post_id <=> n.post_id and n.id <= g.id
+	set n.read_at = current_timestamp
+	where n.user_id = ? and n.read_at is null
+	and g.id in (?` + strings.Repeat(", ?", len(notificationIDs)-1) + `)`)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(arguments...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CountUnread returns how many unread notifications Search would list
+func (repository Notifications) CountUnread(userID uint64) (uint64, error) {
+	row := repository.db.QueryRow(`select count(*) from (
+		select 1 from notifications where user_id = ? and read_at is null group by type, post_id
+	) g`, userID)
+
+	var unread uint64
+	if err := row.Scan(&unread); err != nil {
+		return 0, err
+	}
+
+	return unread, nil
+}
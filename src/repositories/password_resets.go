@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"api/src/models"
+	"database/sql"
+	"errors"
+)
+
+// Represent a password resets repository
+type PasswordResets struct {
+	db *sql.DB
+}
+
+// Create a password resets repository
+func NewPasswordResetsRepository(db *sql.DB) *PasswordResets {
+	return &PasswordResets{db}
+}
+
+// Inserts a password reset into the database
+func (repository PasswordResets) Create(reset models.PasswordReset) error {
+	statement, err := repository.db.Prepare(
+		"insert into password_resets (token_hash, user_id, email, expires_at) values (?, ?, ?, ?)",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(reset.TokenHash, reset.UserID, reset.Email, reset.ExpiresAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Consume uses the token and returns the user it was sent to, every other pending token of the
+// user stops working as well. It reports false when the token was already used, expired or the
+// user changed their address since
+func (repository PasswordResets) Consume(tokenHash string) (uint64, bool, error) {
+	tx, err := repository.db.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	var userID uint64
+	err = tx.QueryRow(`select r.user_id from password_resets r join users u on u.id = r.user_id
+	where r.token_hash = ? and r.used_at is null and r.expires_at > current_timestamp and u.email = r.email
+	for update`, tokenHash).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	if _, err = tx.Exec(
+		"update password_resets set used_at = current_timestamp where user_id = ? and used_at is null", userID,
+	); err != nil {
+		return 0, false, err
+	}
+
+	return userID, true, tx.Commit()
+}
@@ -2,25 +2,44 @@ package repositories
 
 import (
 	"api/src/models"
+	"api/src/pagination"
+	"api/src/search"
 	"database/sql"
+	"strings"
 )
 
 type Posts struct {
 	db *sql.DB
 }
 
+// postColumns selects a post together with its author nick, the number of
+// likes and comments and whether the viewer bound to the first placeholder liked it
+const postColumns = `p.id, p.title, p.content, p.authorId, p.createdAt, u.nick,
+	(select count(*) from post_likes l where l.post_id = p.id),
+	exists(select 1 from post_likes l where l.post_id = p.id and l.user_id = ?),
+	(select count(*) from comments c where c.post_id = p.id)`
+
+// visibleToViewer keeps the posts the viewer, bound to its four placeholders,
+// can see: the authors did not block them nor were blocked by them and are
+// either public accounts, the viewer or private accounts the viewer follows
+const visibleToViewer = `not exists(select 1 from user_blocks b
+	where (b.user_id = u.id and b.blocked_id = ?) or (b.user_id = ? and b.blocked_id = u.id))
+	and (not u.private or u.id = ? or
+	exists(select 1 from followers f where f.user_id = u.id and f.follower_id = ?))`
+
 func NewPostsRepository(db *sql.DB) *Posts {
 	return &Posts{db}
 }
 
+// Create inserts the post together with its hashtags and mentions
 func (repository Posts) Create(post models.Post) (uint64, error) {
-	statement, err := repository.db.Prepare("insert into posts (title, content, authorId) values (?, ?, ?)")
+	tx, err := repository.db.Begin()
 	if err != nil {
 		return 0, err
 	}
-	defer statement.Close()
+	defer tx.Rollback()
 
-	result, err := statement.Exec(post.Title, post.Content, post.AuthorID)
+	result, err := tx.Exec("insert into posts (title, content, authorId) values (?, ?, ?)", post.Title, post.Content, post.AuthorID)
 	if err != nil {
 		return 0, err
 	}
@@ -30,43 +49,59 @@ func (repository Posts) Create(post models.Post) (uint64, error) {
 		return 0, err
 	}
 
-	return uint64(lastInsertedID), nil
+	if err = syncHashtags(tx, uint64(lastInsertedID), post.Hashtags); err != nil {
+		return 0, err
+	}
+
+	if err = syncMentions(tx, uint64(lastInsertedID), post.Mentions); err != nil {
+		return 0, err
+	}
+
+	return uint64(lastInsertedID), tx.Commit()
 }
 
-func (repository Posts) SearchByID(postID uint64) (models.Post, error) {
-	rows, err := repository.db.Query(
-		"SELECT P.*, U.nick from posts P, users U where U.id = authorId and p.id = ?", postID,
+func (repository Posts) SearchByID(postID, viewerID uint64) (models.Post, error) {
+	rows, err := repository.db.Query(`
+	select `+postColumns+` from posts p, users u
+	where u.id = p.authorId and p.id = ?`, viewerID, postID,
 	)
 	if err != nil {
-		return models.Post{}, nil
+		return models.Post{}, err
 	}
 	defer rows.Close()
 
-	var post models.Post
+	if !rows.Next() {
+		return models.Post{}, ErrNotFound
+	}
 
-	if rows.Next() {
-		if err = rows.Scan(
-			&post.ID,
-			&post.Title,
-			&post.Content,
-			&post.AuthorID,
-			&post.Likes,
-			&post.CreatedAt,
-			&post.AuthorNick,
-		); err != nil {
-			return models.Post{}, nil
-		}
+	var post models.Post
+	if err = rows.Scan(
+		&post.ID,
+		&post.Title,
+		&post.Content,
+		&post.AuthorID,
+		&post.CreatedAt,
+		&post.AuthorNick,
+		&post.Likes,
+		&post.LikedByMe,
+		&post.CommentCount,
+	); err != nil {
+		return models.Post{}, err
 	}
 
 	return post, nil
 }
 
-func (repository Posts) Search(userID uint64) ([]models.Post, error) {
+// Search returns the feed of the user, their own posts and the posts of the users they follow and did not mute
+func (repository Posts) Search(userID uint64, page pagination.Params) ([]models.Post, error) {
 	rows, err := repository.db.Query(`
-	select p.*, u.nick from posts p, users u, followers f
-	where u.id = p.authorId and p.authorId = f.user_id 
-	and u.id = ? or f.follower_id = ?
-	order by 1 desc`, userID, userID)
+	select `+postColumns+` from posts p, users u
+	where u.id = p.authorId
+	and (p.authorId = ? or p.authorId in (select user_id from followers where follower_id = ?))
+	and p.authorId not in (select muted_id from user_mutes where user_id = ?)
+	and (? = 0 or p.id < ?)
+	order by p.id desc limit ?`,
+		userID, userID, userID, userID, page.After, page.After, page.Fetch())
 
 	if err != nil {
 		return nil, err
@@ -83,9 +118,11 @@ func (repository Posts) Search(userID uint64) ([]models.Post, error) {
 			&post.Title,
 			&post.Content,
 			&post.AuthorID,
-			&post.Likes,
 			&post.CreatedAt,
 			&post.AuthorNick,
+			&post.Likes,
+			&post.LikedByMe,
+			&post.CommentCount,
 		); err != nil {
 			return nil, err
 		}
@@ -96,18 +133,27 @@ func (repository Posts) Search(userID uint64) ([]models.Post, error) {
 	return posts, nil
 }
 
+// Update changes the title and content of the post and re-syncs its hashtags and mentions
 func (repository Posts) Update(postID uint64, post models.Post) error {
-	statement, err := repository.db.Prepare("update posts set title = ?, content = ? where id = ?")
+	tx, err := repository.db.Begin()
 	if err != nil {
 		return err
 	}
-	defer statement.Close()
+	defer tx.Rollback()
 
-	if _, err = statement.Exec(post.Title, post.Content, postID); err != nil {
+	if _, err = tx.Exec("update posts set title = ?, content = ? where id = ?", post.Title, post.Content, postID); err != nil {
 		return err
 	}
 
-	return nil
+	if err = syncHashtags(tx, postID, post.Hashtags); err != nil {
+		return err
+	}
+
+	if err = syncMentions(tx, postID, post.Mentions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (repository Posts) Delete(postID uint64) error {
@@ -124,11 +170,13 @@ func (repository Posts) Delete(postID uint64) error {
 	return nil
 }
 
-func (repository Posts) SearchByUser(userID uint64) ([]models.Post, error) {
+func (repository Posts) SearchByUser(userID, viewerID uint64, page pagination.Params) ([]models.Post, error) {
 	rows, err := repository.db.Query(`
-	select p.*, u.nick from posts p, users u
-	where u.id = p.authorId and p.authorId = ?`,
-		userID)
+	select `+postColumns+` from posts p, users u
+	where u.id = p.authorId and p.authorId = ?
+	and (? = 0 or p.id < ?)
+	order by p.id desc limit ?`,
+		viewerID, userID, page.After, page.After, page.Fetch())
 
 	if err != nil {
 		return nil, err
@@ -145,9 +193,103 @@ func (repository Posts) SearchByUser(userID uint64) ([]models.Post, error) {
 			&post.Title,
 			&post.Content,
 			&post.AuthorID,
+			&post.CreatedAt,
+			&post.AuthorNick,
 			&post.Likes,
+			&post.LikedByMe,
+			&post.CommentCount,
+		); err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
+
+// SearchText returns the posts matching the full-text query, the most relevant
+// first, the page cursor is the rank of the last result of the previous page
+func (repository Posts) SearchText(query search.Query, viewerID uint64, page pagination.Params) ([]models.PostSearchResult, error) {
+	against := query.Boolean()
+	since := sql.NullTime{Time: query.Since, Valid: !query.Since.IsZero()}
+	until := sql.NullTime{Time: query.Until, Valid: !query.Until.IsZero()}
+
+	rows, err := repository.db.Query(`
+	select `+postColumns+`, match(p.title, p.content) against (? in boolean mode) as score
+	from posts p, users u
+	where u.id = p.authorId
+	and match(p.title, p.content) against (? in boolean mode)
+	and `+visibleToViewer+`
+	and (? = 0 or p.authorId = ?)
+	and (? is null or p.createdAt >= ?)
+	and (? is null or p.createdAt < ?)
+	order by score desc, p.id desc limit ? offset ?`,
+		viewerID, against, against, viewerID, viewerID, viewerID, viewerID, query.AuthorID, query.AuthorID,
+		since, since, until, until, page.Fetch(), page.After)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.PostSearchResult
+
+	for rows.Next() {
+		result := models.PostSearchResult{Rank: page.After + uint64(len(results)) + 1}
+
+		if err = rows.Scan(
+			&result.Post.ID,
+			&result.Post.Title,
+			&result.Post.Content,
+			&result.Post.AuthorID,
+			&result.Post.CreatedAt,
+			&result.Post.AuthorNick,
+			&result.Post.Likes,
+			&result.Post.LikedByMe,
+			&result.Post.CommentCount,
+			&result.Score,
+		); err != nil {
+			return nil, err
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// SearchByHashtag returns the posts tagged with the tag, newest first
+func (repository Posts) SearchByHashtag(tag string, viewerID uint64, page pagination.Params) ([]models.Post, error) {
+	rows, err := repository.db.Query(`
+	select `+postColumns+` from posts p
+	join users u on u.id = p.authorId
+	join post_hashtags h on h.post_id = p.id and h.tag = ?
+	where `+visibleToViewer+`
+	and (? = 0 or p.id < ?)
+	order by p.id desc limit ?`,
+		viewerID, tag, viewerID, viewerID, viewerID, viewerID, page.After, page.After, page.Fetch())
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []models.Post
+
+	for rows.Next() {
+		var post models.Post
+
+		if err = rows.Scan(
+			&post.ID,
+			&post.Title,
+			&post.Content,
+			&post.AuthorID,
 			&post.CreatedAt,
 			&post.AuthorNick,
+			&post.Likes,
+			&post.LikedByMe,
+			&post.CommentCount,
 		); err != nil {
 			return nil, err
 		}
@@ -158,33 +300,193 @@ func (repository Posts) SearchByUser(userID uint64) ([]models.Post, error) {
 	return posts, nil
 }
 
-func (repository Posts) Like(postID uint64) error {
-	statement, err := repository.db.Prepare("update posts set likes = likes + 1 where id = ?")
+// SearchByMention returns the posts that mention the user, newest first
+func (repository Posts) SearchByMention(userID, viewerID uint64, page pagination.Params) ([]models.Post, error) {
+	rows, err := repository.db.Query(`
+	select `+postColumns+` from posts p
+	join users u on u.id = p.authorId
+	where exists(select 1 from post_mentions m where m.post_id = p.id and m.user_id = ?)
+	and `+visibleToViewer+`
+	and (? = 0 or p.id < ?)
+	order by p.id desc limit ?`,
+		viewerID, userID, viewerID, viewerID, viewerID, viewerID, page.After, page.After, page.Fetch())
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []models.Post
+
+	for rows.Next() {
+		var post models.Post
+
+		if err = rows.Scan(
+			&post.ID,
+			&post.Title,
+			&post.Content,
+			&post.AuthorID,
+			&post.CreatedAt,
+			&post.AuthorNick,
+			&post.Likes,
+			&post.LikedByMe,
+			&post.CommentCount,
+		); err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
+
+// SearchMentions returns the mentions of the posts with the current nicks of the users, in the order they appear
+func (repository Posts) SearchMentions(postIDs []uint64) ([]models.Mention, error) {
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+
+	arguments := make([]any, 0, len(postIDs))
+	for _, postID := range postIDs {
+		arguments = append(arguments, postID)
+	}
+
+	rows, err := repository.db.Query(`
+	select m.post_id, m.user_id, u.nick, m.start_offset, m.end_offset
+	from post_mentions m join users u on u.id = m.user_id
+	where m.post_id in (?`+strings.Repeat(", ?", len(postIDs)-1)+`)
+	order by m.post_id, m.start_offset`, arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mentions []models.Mention
+
+	for rows.Next() {
+		var mention models.Mention
+
+		if err = rows.Scan(
+			&mention.PostID,
+			&mention.UserID,
+			&mention.Nick,
+			&mention.Start,
+			&mention.End,
+		); err != nil {
+			return nil, err
+		}
+
+		mentions = append(mentions, mention)
+	}
+
+	return mentions, nil
+}
+
+// syncHashtags makes the tags of the post match the given ones, the tags the
+// post already had keep the time they were first used
+func syncHashtags(tx *sql.Tx, postID uint64, tags []string) error {
+	if len(tags) == 0 {
+		_, err := tx.Exec("delete from post_hashtags where post_id = ?", postID)
+		return err
+	}
+
+	arguments := []any{postID}
+	for _, tag := range tags {
+		arguments = append(arguments, tag)
+	}
+
+	if _, err := tx.Exec(
+		"delete from post_hashtags where post_id = ? and tag not in (?"+strings.Repeat(", ?", len(tags)-1)+")",
+		arguments...,
+	); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if _, err := tx.Exec("insert ignore into post_hashtags (post_id, tag) values (?, ?)", postID, tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncMentions replaces the mentions of the post, their offsets change whenever the content does
+func syncMentions(tx *sql.Tx, postID uint64, mentions []models.Mention) error {
+	if _, err := tx.Exec("delete from post_mentions where post_id = ?", postID); err != nil {
+		return err
+	}
+
+	for _, mention := range mentions {
+		if _, err := tx.Exec(
+			"insert into post_mentions (post_id, user_id, start_offset, end_offset) values (?, ?, ?, ?)",
+			postID, mention.UserID, mention.Start, mention.End,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Like records that the user liked the post, liking twice has no effect
+func (repository Posts) Like(postID, userID uint64) error {
+	statement, err := repository.db.Prepare(
+		"insert ignore into post_likes (post_id, user_id) values (?, ?)")
 	if err != nil {
 		return err
 	}
 	defer statement.Close()
 
-	if _, err = statement.Exec(postID); err != nil {
+	if _, err = statement.Exec(postID, userID); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (repository Posts) Dislike(postID uint64) error {
-	statement, err := repository.db.Prepare(`
-	update posts set likes = 
-	CASE WHEN likes > 0 THEN likes - 1
-	ELSE 0 END where id = ?`)
+// Dislike removes the like the user gave to the post, if there is one
+func (repository Posts) Dislike(postID, userID uint64) error {
+	statement, err := repository.db.Prepare(
+		"delete from post_likes where post_id = ? and user_id = ?")
 	if err != nil {
 		return err
 	}
 	defer statement.Close()
 
-	if _, err = statement.Exec(postID); err != nil {
+	if _, err = statement.Exec(postID, userID); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// SearchLikes returns the users who liked the post
+func (repository Posts) SearchLikes(postID uint64, page pagination.Params) ([]models.User, error) {
+	rows, err := repository.db.Query(`select u.id, u.name, u.nick, u.email, u.createdAt
+	from users u, post_likes l where u.id = l.user_id and l.post_id = ?
+	and u.id > ? order by u.id limit ?`, postID, page.After, page.Fetch())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err = rows.Scan(
+			&user.ID,
+			&user.Name,
+			&user.Nick,
+			&user.Email,
+			&user.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	return users, nil
+}
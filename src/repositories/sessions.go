@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"api/src/models"
+	"database/sql"
+	"time"
+)
+
+// Represent a sessions repository
+type Sessions struct {
+	db *sql.DB
+}
+
+// Create a sessions repository
+func NewSessionsRepository(db *sql.DB) *Sessions {
+	return &Sessions{db}
+}
+
+// Inserts a session into the database
+func (repository Sessions) Create(session models.Session) error {
+	statement, err := repository.db.Prepare(
+		"insert into sessions (id, user_id, refresh_token_hash, expires_at) values (?, ?, ?, ?)",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(session.ID, session.UserID, session.RefreshTokenHash, session.ExpiresAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SearchByRefreshToken returns the session that currently owns the hashed refresh token
+func (repository Sessions) SearchByRefreshToken(refreshTokenHash string) (models.Session, error) {
+	rows, err := repository.db.Query(
+		"select id, user_id, expires_at, revoked_at, created_at from sessions where refresh_token_hash = ?",
+		refreshTokenHash,
+	)
+	if err != nil {
+		return models.Session{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return models.Session{}, ErrNotFound
+	}
+
+	var session models.Session
+	if err = rows.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+		&session.CreatedAt,
+	); err != nil {
+		return models.Session{}, err
+	}
+
+	return session, nil
+}
+
+// Rotate replaces the refresh token of the session, it reports false when the
+// old token was already rotated by a concurrent request
+func (repository Sessions) Rotate(sessionID, oldHash, newHash string, expiresAt time.Time) (bool, error) {
+	statement, err := repository.db.Prepare(`update sessions set refresh_token_hash = ?, expires_at = ?
+	where id = ? and refresh_token_hash = ? and revoked_at is null`)
+	if err != nil {
+		return false, err
+	}
+	defer statement.Close()
+
+	result, err := statement.Exec(newHash, expiresAt, sessionID, oldHash)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// IsActive reports whether the session exists, has not expired and was not revoked
+func (repository Sessions) IsActive(sessionID string) (bool, error) {
+	row := repository.db.QueryRow(`select exists(select 1 from sessions
+	where id = ? and revoked_at is null and expires_at > current_timestamp)`, sessionID)
+
+	var active bool
+	if err := row.Scan(&active); err != nil {
+		return false, err
+	}
+
+	return active, nil
+}
+
+func (repository Sessions) Revoke(sessionID string) error {
+	statement, err := repository.db.Prepare(
+		"update sessions set revoked_at = current_timestamp where id = ? and revoked_at is null",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(sessionID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RevokeAllExcept revokes every session of the user but the one given, pass an
+// empty session ID to revoke all of them
+func (repository Sessions) RevokeAllExcept(userID uint64, sessionID string) error {
+	statement, err := repository.db.Prepare(
+		"update sessions set revoked_at = current_timestamp where user_id = ? and id <> ? and revoked_at is null",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(userID, sessionID); err != nil {
+		return err
+	}
+
+	return nil
+}
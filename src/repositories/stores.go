@@ -0,0 +1,200 @@
+package repositories
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/search"
+	"time"
+)
+
+// UserStore is implemented by the repositories that persist users and the followers relation
+type UserStore interface {
+	Create(user models.User) (uint64, error)
+	Search(nameOrNick string, viewerID uint64, page pagination.Params) ([]models.User, error)
+	SearchByID(userID uint64) (models.User, error)
+	Update(ID uint64, user models.User) error
+	Delete(ID uint64) error
+	SearchByEmail(email string) (models.User, error)
+	SearchByNicks(nicks []string) ([]models.User, error)
+	Follow(userID, followerID uint64) error
+	Unfollow(userID, followerID uint64) error
+	SearchFollowers(userID uint64, page pagination.Params) ([]models.User, error)
+	SearchFollowing(userID uint64, page pagination.Params) ([]models.User, error)
+	SearchFollowerIDs(userID uint64) ([]uint64, error)
+	IsFollowing(userID, followerID uint64) (bool, error)
+	SearchPassword(userID uint64) (string, error)
+	UpdatePassword(userID uint64, password string) error
+	UpdateRole(userID uint64, role string) error
+	Suspend(userID uint64) error
+	Unsuspend(userID uint64) error
+	UpdateMessageSettings(userID uint64, messagesFromAnyone bool) error
+	UpdatePrivacy(userID uint64, private bool) error
+	IsEmailVerified(userID uint64) (bool, error)
+}
+
+// EmailVerificationStore is implemented by the repositories that persist the email confirmation tokens
+type EmailVerificationStore interface {
+	Create(verification models.EmailVerification) error
+	SearchByToken(tokenHash string) (models.EmailVerification, error)
+	Confirm(tokenHash string) (bool, error)
+}
+
+// PasswordResetStore is implemented by the repositories that persist the password reset tokens
+type PasswordResetStore interface {
+	Create(reset models.PasswordReset) error
+	Consume(tokenHash string) (uint64, bool, error)
+}
+
+// LoginAttemptStore is implemented by the repositories that keep the audit trail of the logins
+type LoginAttemptStore interface {
+	Create(attempt models.LoginAttempt) error
+	// SearchFailures returns the failures since the given time of the email, not counting the
+	// ones before its last successful login, and of the IP address
+	SearchFailures(email, ip string, since time.Time) (models.LoginFailures, models.LoginFailures, error)
+	SearchByUser(userID uint64, page pagination.Params) ([]models.LoginAttempt, error)
+}
+
+// TwoFactorStore is implemented by the repositories that persist the TOTP secrets, the recovery codes
+// and the logins waiting for a second factor
+type TwoFactorStore interface {
+	// Search returns ErrNotFound when the user never set up two-factor authentication
+	Search(userID uint64) (models.TwoFactor, error)
+	// Setup saves a pending secret, it does nothing when two-factor authentication is already enabled
+	Setup(userID uint64, secret string) error
+	// Enable turns on the pending secret with the step of the code that confirmed it and replaces
+	// the recovery codes, it reports false when there was nothing pending
+	Enable(userID uint64, step int64, recoveryCodeHashes []string) (bool, error)
+	Disable(userID uint64) error
+	// UseStep reports false when a code of the step or of a later one was already used
+	UseStep(userID uint64, step int64) (bool, error)
+	UseRecoveryCode(userID uint64, codeHash string) (bool, error)
+	CreateChallenge(challenge models.LoginChallenge) error
+	// AttemptChallenge counts an attempt at the challenge and returns its user, it reports false when
+	// the challenge is unknown, expired, used or out of attempts
+	AttemptChallenge(tokenHash string, maxAttempts int) (uint64, bool, error)
+	// ConsumeChallenge reports false when the challenge was already used
+	ConsumeChallenge(tokenHash string) (bool, error)
+}
+
+// AccessTokenStore is implemented by the repositories that persist the personal access tokens
+type AccessTokenStore interface {
+	Create(token models.AccessToken) (uint64, error)
+	SearchByUser(userID uint64, page pagination.Params) ([]models.AccessToken, error)
+	// Delete returns ErrNotFound when the user has no token with the ID
+	Delete(tokenID, userID uint64) error
+	// Use returns the token and records that it was used, it reports false when the token is unknown,
+	// expired or its owner is suspended
+	Use(tokenHash string) (models.AccessToken, bool, error)
+}
+
+// PostStore is implemented by the repositories that persist posts and their likes
+type PostStore interface {
+	Create(post models.Post) (uint64, error)
+	SearchByID(postID, viewerID uint64) (models.Post, error)
+	Search(userID uint64, page pagination.Params) ([]models.Post, error)
+	Update(postID uint64, post models.Post) error
+	Delete(postID uint64) error
+	SearchByUser(userID, viewerID uint64, page pagination.Params) ([]models.Post, error)
+	SearchText(query search.Query, viewerID uint64, page pagination.Params) ([]models.PostSearchResult, error)
+	SearchByHashtag(tag string, viewerID uint64, page pagination.Params) ([]models.Post, error)
+	SearchByMention(userID, viewerID uint64, page pagination.Params) ([]models.Post, error)
+	SearchMentions(postIDs []uint64) ([]models.Mention, error)
+	Like(postID, userID uint64) error
+	Dislike(postID, userID uint64) error
+	SearchLikes(postID uint64, page pagination.Params) ([]models.User, error)
+}
+
+// CommentStore is implemented by the repositories that persist comments
+type CommentStore interface {
+	Create(comment models.Comment) (uint64, error)
+	SearchByID(commentID uint64) (models.Comment, error)
+	SearchByPost(postID uint64, page pagination.Params) ([]models.Comment, error)
+	Update(commentID uint64, comment models.Comment) error
+	Delete(commentID uint64) error
+}
+
+// SessionStore is implemented by the repositories that persist login sessions
+type SessionStore interface {
+	Create(session models.Session) error
+	SearchByRefreshToken(refreshTokenHash string) (models.Session, error)
+	Rotate(sessionID, oldHash, newHash string, expiresAt time.Time) (bool, error)
+	IsActive(sessionID string) (bool, error)
+	Revoke(sessionID string) error
+	RevokeAllExcept(userID uint64, sessionID string) error
+}
+
+// NotificationStore is implemented by the repositories that persist notifications
+type NotificationStore interface {
+	Create(notification models.Notification) error
+	Search(userID uint64, unreadOnly bool, page pagination.Params) ([]models.Notification, error)
+	MarkRead(userID uint64, notificationIDs []uint64) error
+	CountUnread(userID uint64) (uint64, error)
+}
+
+// MessageStore is implemented by the repositories that persist conversations and direct messages
+type MessageStore interface {
+	StartConversation(message models.Message, recipientID uint64) (uint64, uint64, error)
+	SearchConversationBetween(userID, otherUserID uint64) (uint64, error)
+	SearchConversation(conversationID, userID uint64) (models.Conversation, error)
+	SearchConversations(userID uint64, page pagination.Params) ([]models.Conversation, error)
+	Create(message models.Message) (uint64, error)
+	Search(conversationID uint64, page pagination.Params) ([]models.Message, error)
+	MarkRead(conversationID, userID, messageID uint64) error
+	SetMuted(conversationID, userID uint64, muted bool) error
+}
+
+// AttachmentStore is implemented by the repositories that persist the images attached to posts
+type AttachmentStore interface {
+	// Create returns ErrConflict when the post already carries models.MaxAttachments images
+	Create(attachment models.Attachment) (uint64, error)
+	SearchByID(attachmentID uint64) (models.Attachment, error)
+	SearchByPosts(postIDs []uint64) ([]models.Attachment, error)
+	SearchByUser(userID uint64) ([]models.Attachment, error)
+	Delete(attachmentID uint64) error
+}
+
+// HashtagStore is implemented by the repositories that rank the hashtags of the posts
+type HashtagStore interface {
+	Trending(window, halfLife time.Duration, limit int) ([]models.TrendingHashtag, error)
+}
+
+// FollowRequestStore is implemented by the repositories that persist the requests to follow private accounts
+type FollowRequestStore interface {
+	Create(userID, followerID uint64) error
+	SearchByID(requestID uint64) (models.FollowRequest, error)
+	Search(userID uint64, page pagination.Params) ([]models.FollowRequest, error)
+	Approve(requestID uint64) error
+	Delete(requestID uint64) error
+	Cancel(userID, followerID uint64) error
+}
+
+// BlockStore is implemented by the repositories that persist the blocks and mutes between users
+type BlockStore interface {
+	Block(userID, blockedID uint64) error
+	Unblock(userID, blockedID uint64) error
+	IsBlocked(userID, otherID uint64) (bool, error)
+	SearchBlockedIDs(userID uint64) ([]uint64, error)
+	SearchBlocked(userID uint64, page pagination.Params) ([]models.User, error)
+	Mute(userID, mutedID uint64) error
+	Unmute(userID, mutedID uint64) error
+	SearchMuted(userID uint64, page pagination.Params) ([]models.User, error)
+}
+
+var (
+	_ UserStore          = (*Users)(nil)
+	_ PostStore          = (*Posts)(nil)
+	_ CommentStore       = (*Comments)(nil)
+	_ SessionStore       = (*Sessions)(nil)
+	_ NotificationStore  = (*Notifications)(nil)
+	_ MessageStore       = (*Messages)(nil)
+	_ AttachmentStore    = (*Attachments)(nil)
+	_ HashtagStore       = (*Hashtags)(nil)
+	_ FollowRequestStore = (*FollowRequests)(nil)
+	_ BlockStore         = (*Blocks)(nil)
+
+	_ EmailVerificationStore = (*EmailVerifications)(nil)
+	_ PasswordResetStore     = (*PasswordResets)(nil)
+	_ LoginAttemptStore      = (*LoginAttempts)(nil)
+	_ TwoFactorStore         = (*TwoFactor)(nil)
+	_ AccessTokenStore       = (*AccessTokens)(nil)
+)
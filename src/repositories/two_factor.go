@@ -0,0 +1,191 @@
+package repositories
+
+import (
+	"api/src/models"
+	"database/sql"
+)
+
+// Represent a two-factor authentication repository
+type TwoFactor struct {
+	db *sql.DB
+}
+
+// Create a two-factor authentication repository
+func NewTwoFactorRepository(db *sql.DB) *TwoFactor {
+	return &TwoFactor{db}
+}
+
+// Search returns the TOTP settings of the user with the number of unused recovery codes
+func (repository TwoFactor) Search(userID uint64) (models.TwoFactor, error) {
+	rows, err := repository.db.Query(`select t.user_id, t.secret, t.enabled_at, t.last_step,
+	(select count(*) from recovery_codes r where r.user_id = t.user_id and r.used_at is null)
+	from two_factor t where t.user_id = ?`, userID)
+	if err != nil {
+		return models.TwoFactor{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return models.TwoFactor{}, ErrNotFound
+	}
+
+	var twoFactor models.TwoFactor
+	if err = rows.Scan(
+		&twoFactor.UserID,
+		&twoFactor.Secret,
+		&twoFactor.EnabledAt,
+		&twoFactor.LastStep,
+		&twoFactor.RecoveryCodesLeft,
+	); err != nil {
+		return models.TwoFactor{}, err
+	}
+
+	return twoFactor, nil
+}
+
+// Setup saves a new pending secret for the user, an enabled secret is kept
+func (repository TwoFactor) Setup(userID uint64, secret string) error {
+	statement, err := repository.db.Prepare(`insert into two_factor (user_id, secret) values (?, ?)
+	on duplicate key update secret = if(enabled_at is null, values(secret), secret)`)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(userID, secret); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Enable turns on the pending secret of the user and replaces their recovery codes
+func (repository TwoFactor) Enable(userID uint64, step int64, recoveryCodeHashes []string) (bool, error) {
+	tx, err := repository.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"update two_factor set enabled_at = current_timestamp, last_step = ? where user_id = ? and enabled_at is null",
+		step, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if enabled, err := result.RowsAffected(); err != nil || enabled == 0 {
+		return false, err
+	}
+
+	if _, err = tx.Exec("delete from recovery_codes where user_id = ?", userID); err != nil {
+		return false, err
+	}
+
+	for _, codeHash := range recoveryCodeHashes {
+		if _, err = tx.Exec("insert into recovery_codes (user_id, code_hash) values (?, ?)", userID, codeHash); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+// Disable removes the secret and the recovery codes of the user
+func (repository TwoFactor) Disable(userID uint64) error {
+	tx, err := repository.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("delete from recovery_codes where user_id = ?", userID); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec("delete from two_factor where user_id = ?", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseStep records the step of an accepted code, codes of that step or older ones stop working
+func (repository TwoFactor) UseStep(userID uint64, step int64) (bool, error) {
+	return repository.update(
+		"update two_factor set last_step = ? where user_id = ? and last_step < ?", step, userID, step,
+	)
+}
+
+// UseRecoveryCode marks the recovery code of the user as used
+func (repository TwoFactor) UseRecoveryCode(userID uint64, codeHash string) (bool, error) {
+	return repository.update(
+		"update recovery_codes set used_at = current_timestamp where user_id = ? and code_hash = ? and used_at is null",
+		userID, codeHash,
+	)
+}
+
+// Inserts a login challenge into the database
+func (repository TwoFactor) CreateChallenge(challenge models.LoginChallenge) error {
+	statement, err := repository.db.Prepare(
+		"insert into login_challenges (token_hash, user_id, expires_at) values (?, ?, ?)",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(challenge.TokenHash, challenge.UserID, challenge.ExpiresAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AttemptChallenge counts an attempt at the challenge and returns the user who started the login
+func (repository TwoFactor) AttemptChallenge(tokenHash string, maxAttempts int) (uint64, bool, error) {
+	attempted, err := repository.update(`update login_challenges set attempts = attempts + 1
+	where token_hash = ? and used_at is null and expires_at > current_timestamp and attempts < ?`,
+		tokenHash, maxAttempts)
+	if err != nil || !attempted {
+		return 0, false, err
+	}
+
+	var userID uint64
+	if err = repository.db.QueryRow(
+		"select user_id from login_challenges where token_hash = ?", tokenHash,
+	).Scan(&userID); err != nil {
+		return 0, false, err
+	}
+
+	return userID, true, nil
+}
+
+// ConsumeChallenge marks the challenge as used once the second factor was accepted
+func (repository TwoFactor) ConsumeChallenge(tokenHash string) (bool, error) {
+	return repository.update(
+		"update login_challenges set used_at = current_timestamp where token_hash = ? and used_at is null", tokenHash,
+	)
+}
+
+// update runs the statement and reports whether it changed a row
+func (repository TwoFactor) update(query string, arguments ...any) (bool, error) {
+	statement, err := repository.db.Prepare(query)
+	if err != nil {
+		return false, err
+	}
+	defer statement.Close()
+
+	result, err := statement.Exec(arguments...)
+	if err != nil {
+		return false, err
+	}
+
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return changed > 0, nil
+}
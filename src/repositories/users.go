@@ -2,8 +2,10 @@ package repositories
 
 import (
 	"api/src/models"
+	"api/src/pagination"
 	"database/sql"
 	"fmt"
+	"strings"
 )
 
 // Represent a user repository
@@ -28,7 +30,7 @@ func (repository Users) Create(user models.User) (uint64, error) {
 
 	result, err := statement.Exec(user.Name, user.Nick, user.Email, user.Password)
 	if err != nil {
-		return 0, err
+		return 0, translateError(err, "the nick or email is already in use")
 	}
 	lastInsertedID, err := result.LastInsertId()
 	if err != nil {
@@ -37,10 +39,14 @@ func (repository Users) Create(user models.User) (uint64, error) {
 	return uint64(lastInsertedID), nil
 }
 
-func (repository Users) Search(nameOrNick string) ([]models.User, error) {
+func (repository Users) Search(nameOrNick string, viewerID uint64, page pagination.Params) ([]models.User, error) {
 	nameOrNick = fmt.Sprintf("%%%s%%", nameOrNick)
 	rows, err := repository.db.Query(
-		"SELECT id, name, nick, email, createdAt from users where name LIKE ? or nick LIKE ?", nameOrNick, nameOrNick,
+		`SELECT u.id, u.name, u.nick, u.email, u.createdAt from users u where (u.name LIKE ? or u.nick LIKE ?)
+		and not exists(select 1 from user_blocks b
+			where (b.user_id = u.id and b.blocked_id = ?) or (b.user_id = ? and b.blocked_id = u.id))
+		and u.id > ? order by u.id limit ?`,
+		nameOrNick, nameOrNick, viewerID, viewerID, page.After, page.Fetch(),
 	)
 
 	if err != nil {
@@ -72,7 +78,7 @@ func (repository Users) Search(nameOrNick string) ([]models.User, error) {
 
 func (repository Users) SearchByID(userID uint64) (models.User, error) {
 	rows, err := repository.db.Query(
-		"SELECT id, name, nick, email, createdAt from users where id = ?", userID,
+		"SELECT id, name, nick, email, email_verified_at, role, suspended_at, messages_from_anyone, private, createdAt from users where id = ?", userID,
 	)
 
 	if err != nil {
@@ -80,34 +86,42 @@ func (repository Users) SearchByID(userID uint64) (models.User, error) {
 	}
 	defer rows.Close()
 
-	var user models.User
+	if !rows.Next() {
+		return models.User{}, ErrNotFound
+	}
 
-	if rows.Next() {
-		if err = rows.Scan(
-			&user.ID,
-			&user.Name,
-			&user.Nick,
-			&user.Email,
-			&user.CreatedAt,
-		); err != nil {
-			return models.User{}, err
-		}
+	var user models.User
+	if err = rows.Scan(
+		&user.ID,
+		&user.Name,
+		&user.Nick,
+		&user.Email,
+		&user.EmailVerifiedAt,
+		&user.Role,
+		&user.SuspendedAt,
+		&user.MessagesFromAnyone,
+		&user.Private,
+		&user.CreatedAt,
+	); err != nil {
+		return models.User{}, err
 	}
 
 	return user, nil
 }
 
+// Update changes the profile of the user, a new email address has to be confirmed again
 func (repository Users) Update(ID uint64, user models.User) error {
+	// MySQL assigns from left to right, the address is compared before it is replaced
 	statement, err := repository.db.Prepare(
-		"update users set name = ?, nick = ?, email = ? where id = ?",
+		"update users set email_verified_at = if(email = ?, email_verified_at, null), name = ?, nick = ?, email = ? where id = ?",
 	)
 	if err != nil {
 		return err
 	}
 	defer statement.Close()
 
-	if _, err = statement.Exec(user.Name, user.Nick, user.Email, ID); err != nil {
-		return err
+	if _, err = statement.Exec(user.Email, user.Name, user.Nick, user.Email, ID); err != nil {
+		return translateError(err, "the nick or email is already in use")
 	}
 
 	return nil
@@ -129,7 +143,7 @@ func (repository Users) Delete(ID uint64) error {
 }
 
 func (repository Users) SearchByEmail(email string) (models.User, error) {
-	row, err := repository.db.Query("select id, password from users where email = ?", email)
+	row, err := repository.db.Query("select id, email, password, role, suspended_at, email_verified_at from users where email = ?", email)
 	if err != nil {
 		return models.User{}, err
 	}
@@ -138,7 +152,7 @@ func (repository Users) SearchByEmail(email string) (models.User, error) {
 	var user models.User
 
 	if row.Next() {
-		if err = row.Scan(&user.ID, &user.Password); err != nil {
+		if err = row.Scan(&user.ID, &user.Email, &user.Password, &user.Role, &user.SuspendedAt, &user.EmailVerifiedAt); err != nil {
 			return models.User{}, err
 		}
 	}
@@ -146,6 +160,47 @@ func (repository Users) SearchByEmail(email string) (models.User, error) {
 	return user, nil
 }
 
+// SearchByNicks returns the users with the nicks, ignoring case
+func (repository Users) SearchByNicks(nicks []string) ([]models.User, error) {
+	if len(nicks) == 0 {
+		return nil, nil
+	}
+
+	arguments := make([]any, 0, len(nicks))
+	for _, nick := range nicks {
+		arguments = append(arguments, nick)
+	}
+
+	rows, err := repository.db.Query(
+		"select id, name, nick, email, createdAt from users where nick in (?"+strings.Repeat(", ?", len(nicks)-1)+")",
+		arguments...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+
+	for rows.Next() {
+		var user models.User
+
+		if err = rows.Scan(
+			&user.ID,
+			&user.Name,
+			&user.Nick,
+			&user.Email,
+			&user.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
 func (repository Users) Follow(userID, followerID uint64) error {
 	statement, err := repository.db.Prepare(
 		"insert ignore into followers (user_id, follower_id) values (?, ?)")
@@ -163,7 +218,7 @@ func (repository Users) Follow(userID, followerID uint64) error {
 
 func (repository Users) Unfollow(userID, followerID uint64) error {
 	statement, err := repository.db.Prepare(
-		"delete from followers where userID = ? and followerID = ?",
+		"delete from followers where user_id = ? and follower_id = ?",
 	)
 	if err != nil {
 		return err
@@ -177,9 +232,46 @@ func (repository Users) Unfollow(userID, followerID uint64) error {
 	return nil
 }
 
-func (repository Users) SearchFollowers(userID uint64) ([]models.User, error) {
+// IsFollowing reports whether the follower follows the user
+func (repository Users) IsFollowing(userID, followerID uint64) (bool, error) {
+	row := repository.db.QueryRow(
+		"select exists(select 1 from followers where user_id = ? and follower_id = ?)", userID, followerID,
+	)
+
+	var following bool
+	if err := row.Scan(&following); err != nil {
+		return false, err
+	}
+
+	return following, nil
+}
+
+// SearchFollowerIDs returns the IDs of every follower of the user, it is used to fan out events
+func (repository Users) SearchFollowerIDs(userID uint64) ([]uint64, error) {
+	rows, err := repository.db.Query("select follower_id from followers where user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var followerIDs []uint64
+
+	for rows.Next() {
+		var followerID uint64
+		if err = rows.Scan(&followerID); err != nil {
+			return nil, err
+		}
+
+		followerIDs = append(followerIDs, followerID)
+	}
+
+	return followerIDs, nil
+}
+
+func (repository Users) SearchFollowers(userID uint64, page pagination.Params) ([]models.User, error) {
 	rows, err := repository.db.Query(`select u.id, u.name, u.nick, u.email, u.createdAt 
-	from users u, followers f where u.id = f.follower_id AND f.user_id = ?`, userID)
+	from users u, followers f where u.id = f.follower_id AND f.user_id = ?
+	and u.id > ? order by u.id limit ?`, userID, page.After, page.Fetch())
 	if err != nil {
 		return nil, err
 	}
@@ -205,9 +297,10 @@ func (repository Users) SearchFollowers(userID uint64) ([]models.User, error) {
 
 }
 
-func (repository Users) SearchFollowing(userID uint64) ([]models.User, error) {
+func (repository Users) SearchFollowing(userID uint64, page pagination.Params) ([]models.User, error) {
 	rows, err := repository.db.Query(`select u.id, u.name, u.nick, u.email, u.createdAt 
-	from users u, followers f where u.id = f.user_id AND f.follower_id = ?`, userID)
+	from users u, followers f where u.id = f.user_id AND f.follower_id = ?
+	and u.id > ? order by u.id limit ?`, userID, page.After, page.Fetch())
 	if err != nil {
 		return nil, err
 	}
@@ -236,16 +329,17 @@ func (repository Users) SearchPassword(userID uint64) (string, error) {
 	row, err := repository.db.Query("select password from users where id = ?", userID)
 
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 	defer row.Close()
 
-	var user models.User
+	if !row.Next() {
+		return "", ErrNotFound
+	}
 
-	if row.Next() {
-		if err = row.Scan(&user.Password); err != nil {
-			return "", err
-		}
+	var user models.User
+	if err = row.Scan(&user.Password); err != nil {
+		return "", err
 	}
 
 	return user.Password, nil
@@ -265,3 +359,104 @@ func (repository Users) UpdatePassword(userID uint64, password string) error {
 
 	return nil
 }
+
+func (repository Users) UpdateRole(userID uint64, role string) error {
+	statement, err := repository.db.Prepare("update users set role = ? where id = ?")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(role, userID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Suspend marks the user as suspended, suspended users cannot log in
+func (repository Users) Suspend(userID uint64) error {
+	statement, err := repository.db.Prepare(
+		"update users set suspended_at = current_timestamp where id = ? and suspended_at is null")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(userID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Unsuspend lifts the suspension of the user
+func (repository Users) Unsuspend(userID uint64) error {
+	statement, err := repository.db.Prepare("update users set suspended_at = null where id = ?")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(userID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateMessageSettings changes whether anyone can start a conversation with the user
+func (repository Users) UpdateMessageSettings(userID uint64, messagesFromAnyone bool) error {
+	statement, err := repository.db.Prepare("update users set messages_from_anyone = ? where id = ?")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(messagesFromAnyone, userID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdatePrivacy makes the account private or public, the pending follow
+// requests are approved when it becomes public
+func (repository Users) UpdatePrivacy(userID uint64, private bool) error {
+	tx, err := repository.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("update users set private = ? where id = ?", private, userID); err != nil {
+		return err
+	}
+
+	if !private {
+		if _, err = tx.Exec(`insert ignore into followers (user_id, follower_id)
+		select user_id, follower_id from follow_requests where user_id = ?`, userID); err != nil {
+			return err
+		}
+
+		if _, err = tx.Exec("delete from follow_requests where user_id = ?", userID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// IsEmailVerified reports whether the user confirmed their current email address
+func (repository Users) IsEmailVerified(userID uint64) (bool, error) {
+	row := repository.db.QueryRow(
+		"select exists(select 1 from users where id = ? and email_verified_at is not null)", userID,
+	)
+
+	var verified bool
+	if err := row.Scan(&verified); err != nil {
+		return false, err
+	}
+
+	return verified, nil
+}
@@ -1,11 +1,24 @@
 package responses
 
 import (
+	"api/src/models"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
 )
 
+// Problem represents an error response in the RFC 7807 problem details format
+type Problem struct {
+	Type   string              `json:"type"`
+	Title  string              `json:"title"`
+	Status int                 `json:"status"`
+	Detail string              `json:"detail,omitempty"`
+	Code   string              `json:"code"`
+	Errors []models.FieldError `json:"errors,omitempty"`
+}
+
 func JSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -21,10 +34,54 @@ func JSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	}
 }
 
+// Error writes the error as a problem details response, the errors of the models
+// override the status code with the one that matches them
 func Error(w http.ResponseWriter, statusCode int, err error) {
-	JSON(w, statusCode, struct {
-		Error string `json:"error"`
-	}{
-		Error: err.Error(),
-	})
+	problem := newProblem(statusCode, err)
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		log.Printf("Error encoding JSON: %v", err)
+	}
+}
+
+// newProblem builds the problem details of an error
+func newProblem(statusCode int, err error) Problem {
+	code := ""
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		statusCode, code = http.StatusNotFound, "not_found"
+	case errors.Is(err, models.ErrConflict):
+		statusCode, code = http.StatusConflict, "conflict"
+	case errors.Is(err, models.ErrForbidden):
+		statusCode, code = http.StatusForbidden, "forbidden"
+	}
+
+	problem := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: err.Error(),
+		Code:   code,
+	}
+
+	var fieldError *models.FieldError
+	if errors.As(err, &fieldError) {
+		problem.Code = "validation_failed"
+		problem.Errors = []models.FieldError{*fieldError}
+	}
+
+	if statusCode >= http.StatusInternalServerError {
+		// Unexpected errors may carry driver details, they are only logged
+		log.Printf("Internal error: %v", err)
+		problem.Detail = "An unexpected error occurred"
+	}
+
+	if problem.Code == "" {
+		problem.Code = strings.ReplaceAll(strings.ToLower(problem.Title), " ", "_")
+	}
+
+	return problem
 }
@@ -1,13 +1,14 @@
 package router
 
 import (
+	"api/src/controllers"
 	"api/src/router/routes"
 
 	"github.com/gorilla/mux"
 )
 
-func Generate() *mux.Router {
+func Generate(controller *controllers.Controller) *mux.Router {
 	r := mux.NewRouter()
-	return routes.Configure(r)
+	return routes.Configure(r, controller)
 
 }
@@ -0,0 +1,1574 @@
+package router_test
+
+import (
+	"api/src/config"
+	"api/src/controllers"
+	"api/src/events"
+	"api/src/mail"
+	"api/src/models"
+	"api/src/ratelimit"
+	"api/src/repositories/memory"
+	"api/src/router"
+	"api/src/security"
+	"api/src/storage"
+	"api/src/totp"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// api drives the router through a real HTTP server backed by the in-memory stores
+type api struct {
+	t      *testing.T
+	server *httptest.Server
+	db     *memory.Database
+	// mediaDir is where the local storage backend writes the uploads
+	mediaDir string
+	outbox   *mail.Outbox
+	// clock drives the rate limits and the login delays, it only moves when a test advances it
+	clock *fakeClock
+}
+
+// fakeClock is a time source shared by everything that limits requests over time
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newAPI(t *testing.T) *api {
+	t.Helper()
+
+	config.SecretKey = []byte("test-secret")
+
+	db := memory.New()
+	mediaDir := t.TempDir()
+	outbox := mail.NewOutbox("")
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	controller := &controllers.Controller{
+		Users:          db.Users(),
+		Posts:          db.Posts(),
+		Comments:       db.Comments(),
+		Sessions:       db.Sessions(),
+		Verifications:  db.EmailVerifications(),
+		PasswordResets: db.PasswordResets(),
+		LoginAttempts:  db.LoginAttempts(),
+		TwoFactor:      db.TwoFactor(),
+		AccessTokens:   db.AccessTokens(),
+		FollowRequests: db.FollowRequests(),
+		Blocks:         db.Blocks(),
+		Notifications:  db.Notifications(),
+		Messages:       db.Messages(),
+		Attachments:    db.Attachments(),
+		Hashtags:       db.Hashtags(),
+		Storage:        storage.NewLocal(mediaDir, "/media"),
+		Mailer:         outbox,
+		Events:         events.NewHub(events.DefaultHistory),
+		RateLimits:     ratelimit.NewMemory(clock.Now),
+		Clock:          clock.Now,
+	}
+
+	server := httptest.NewServer(router.Generate(controller))
+	t.Cleanup(server.Close)
+
+	return &api{t: t, server: server, db: db, mediaDir: mediaDir, outbox: outbox, clock: clock}
+}
+
+// do sends the request and decodes the response body into out when it is not nil
+func (a *api) do(method, path, token string, body, out any, wantStatus int) {
+	a.t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			a.t.Fatal(err)
+		}
+	}
+
+	request, err := http.NewRequest(method, a.server.URL+path, &payload)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response, err := a.server.Client().Do(request)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != wantStatus {
+		var problem map[string]any
+		json.NewDecoder(response.Body).Decode(&problem)
+		a.t.Fatalf("%s %s: got status %d, want %d (%v)", method, path, response.StatusCode, wantStatus, problem)
+	}
+
+	if out != nil {
+		if err = json.NewDecoder(response.Body).Decode(out); err != nil {
+			a.t.Fatalf("%s %s: decoding response: %v", method, path, err)
+		}
+	}
+}
+
+// upload attaches the image to the post through a multipart form
+func (a *api) upload(postID uint64, token string, data []byte, altText string, out any, wantStatus int) {
+	a.t.Helper()
+
+	response, err := a.sendUpload(postID, token, data, altText)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != wantStatus {
+		a.t.Fatalf("uploading to post %d: got status %d, want %d", postID, response.StatusCode, wantStatus)
+	}
+
+	if out != nil {
+		if err = json.NewDecoder(response.Body).Decode(out); err != nil {
+			a.t.Fatalf("uploading to post %d: decoding response: %v", postID, err)
+		}
+	}
+}
+
+// sendUpload posts the multipart form of an upload, it does not fail the test so it can run in other goroutines
+func (a *api) sendUpload(postID uint64, token string, data []byte, altText string) (*http.Response, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", "image")
+	if err != nil {
+		return nil, err
+	}
+	part.Write(data)
+	form.WriteField("altText", altText)
+	form.Close()
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/posts/%d/attachments", a.server.URL, postID), &body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	return a.server.Client().Do(request)
+}
+
+// register creates a user, confirms their email address and logs them in
+func (a *api) register(nick string) (models.User, models.AuthTokens) {
+	a.t.Helper()
+
+	user, tokens := a.signup(nick)
+	a.do(http.MethodPost, "/auth/verify-email", "", models.VerifyEmailRequest{
+		Token: a.mailedToken(user.Email, "/verify-email"),
+	}, nil, http.StatusNoContent)
+
+	return user, tokens
+}
+
+// signup creates a user without confirming their email address and logs them in
+func (a *api) signup(nick string) (models.User, models.AuthTokens) {
+	a.t.Helper()
+
+	var user models.User
+	a.do(http.MethodPost, "/users", "", map[string]string{
+		"name":     nick,
+		"nick":     nick,
+		"email":    nick + "@example.com",
+		"password": "secret",
+	}, &user, http.StatusCreated)
+
+	return user, a.login(nick)
+}
+
+func (a *api) login(nick string) models.AuthTokens {
+	a.t.Helper()
+
+	var tokens models.AuthTokens
+	a.do(http.MethodPost, "/login", "", models.UserRequest{
+		Email:    nick + "@example.com",
+		Password: "secret",
+	}, &tokens, http.StatusOK)
+
+	return tokens
+}
+
+// mailedToken returns the token of the last link to the page of the web application
+// emailed to the address, waiting a little for the emails sent after the response
+func (a *api) mailedToken(email, page string) string {
+	a.t.Helper()
+
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		messages := a.outbox.Messages()
+		for i := len(messages) - 1; i >= 0; i-- {
+			if messages[i].To != email {
+				continue
+			}
+
+			_, link, found := strings.Cut(messages[i].Body, page+"?")
+			if !found {
+				continue
+			}
+
+			query, err := url.ParseQuery(strings.Fields(link)[0])
+			if err != nil {
+				a.t.Fatal(err)
+			}
+
+			return query.Get("token")
+		}
+	}
+
+	a.t.Fatalf("no %s link was sent to %s", page, email)
+	return ""
+}
+
+type page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"nextCursor"`
+}
+
+func TestUsers(t *testing.T) {
+	a := newAPI(t)
+	alice, aliceTokens := a.register("alice")
+	bob, bobTokens := a.register("bob")
+	token := aliceTokens.AccessToken
+
+	a.do(http.MethodPost, "/users", "", map[string]string{
+		"name": "Alice", "nick": "alice", "email": "alice@example.com", "password": "secret",
+	}, nil, http.StatusConflict)
+
+	a.do(http.MethodPost, "/users", "", map[string]string{"nick": "nobody"}, nil, http.StatusBadRequest)
+
+	var users page[models.User]
+	a.do(http.MethodGet, "/users?user=bo", token, nil, &users, http.StatusOK)
+	if len(users.Data) != 1 || users.Data[0].ID != bob.ID {
+		t.Fatalf("searching bo returned %+v", users.Data)
+	}
+
+	var user models.User
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d", bob.ID), token, nil, &user, http.StatusOK)
+	if user.Nick != "bob" || user.Password != "" {
+		t.Fatalf("got user %+v", user)
+	}
+
+	a.do(http.MethodGet, "/users/999", token, nil, nil, http.StatusNotFound)
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d", bob.ID), "", nil, nil, http.StatusUnauthorized)
+
+	a.do(http.MethodPut, fmt.Sprintf("/users/%d", alice.ID), token, map[string]string{
+		"name": "Alice Liddell", "nick": "alice", "email": "alice@example.com",
+	}, nil, http.StatusNoContent)
+
+	a.do(http.MethodPut, fmt.Sprintf("/users/%d", bob.ID), token, map[string]string{
+		"name": "Bob", "nick": "bob", "email": "bob@example.com",
+	}, nil, http.StatusForbidden)
+
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/follow", bob.ID), token, nil, nil, http.StatusNoContent)
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/follow", alice.ID), token, nil, nil, http.StatusForbidden)
+
+	var followers page[models.User]
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d/followers", bob.ID), token, nil, &followers, http.StatusOK)
+	if len(followers.Data) != 1 || followers.Data[0].ID != alice.ID {
+		t.Fatalf("followers of bob are %+v", followers.Data)
+	}
+
+	var following page[models.User]
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d/following", alice.ID), token, nil, &following, http.StatusOK)
+	if len(following.Data) != 1 || following.Data[0].ID != bob.ID {
+		t.Fatalf("alice follows %+v", following.Data)
+	}
+
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/unfollow", bob.ID), token, nil, nil, http.StatusNoContent)
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d/followers", bob.ID), token, nil, &followers, http.StatusOK)
+	if len(followers.Data) != 0 {
+		t.Fatalf("bob still has followers %+v", followers.Data)
+	}
+
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/update-password", alice.ID), token,
+		models.Password{Current: "wrong", New: "changed"}, nil, http.StatusUnauthorized)
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/update-password", alice.ID), token,
+		models.Password{Current: "secret", New: "secret"}, nil, http.StatusNoContent)
+
+	a.do(http.MethodDelete, fmt.Sprintf("/users/%d", alice.ID), bobTokens.AccessToken, nil, nil, http.StatusForbidden)
+	a.do(http.MethodDelete, fmt.Sprintf("/users/%d", bob.ID), bobTokens.AccessToken, nil, nil, http.StatusNoContent)
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d", bob.ID), token, nil, nil, http.StatusNotFound)
+}
+
+func TestEmailCase(t *testing.T) {
+	a := newAPI(t)
+
+	var alice models.User
+	a.do(http.MethodPost, "/users", "", map[string]string{
+		"name": "Alice", "nick": "alice", "email": " Alice@Example.com ", "password": "secret",
+	}, &alice, http.StatusCreated)
+	if alice.Email != "alice@example.com" {
+		t.Fatalf("the email was saved as %q", alice.Email)
+	}
+
+	a.do(http.MethodPost, "/users", "", map[string]string{
+		"name": "Other", "nick": "other", "email": "ALICE@example.com", "password": "secret",
+	}, nil, http.StatusConflict)
+
+	a.do(http.MethodPost, "/login", "", models.UserRequest{Email: "Alice@Example.com", Password: "secret"}, nil, http.StatusOK)
+	a.login("alice")
+}
+
+func TestSessions(t *testing.T) {
+	a := newAPI(t)
+	_, tokens := a.register("alice")
+
+	a.do(http.MethodPost, "/login", "", models.UserRequest{
+		Email: "alice@example.com", Password: "wrong",
+	}, nil, http.StatusUnauthorized)
+
+	var refreshed models.AuthTokens
+	a.do(http.MethodPost, "/auth/refresh", "", models.RefreshRequest{RefreshToken: tokens.RefreshToken}, &refreshed, http.StatusOK)
+	if refreshed.RefreshToken == tokens.RefreshToken {
+		t.Fatal("the refresh token was not rotated")
+	}
+
+	a.do(http.MethodPost, "/auth/refresh", "", models.RefreshRequest{RefreshToken: tokens.RefreshToken}, nil, http.StatusUnauthorized)
+
+	a.do(http.MethodPost, "/auth/logout", refreshed.AccessToken, nil, nil, http.StatusNoContent)
+	a.do(http.MethodGet, "/posts", refreshed.AccessToken, nil, nil, http.StatusUnauthorized)
+	a.do(http.MethodPost, "/auth/refresh", "", models.RefreshRequest{RefreshToken: refreshed.RefreshToken}, nil, http.StatusUnauthorized)
+}
+
+func TestPosts(t *testing.T) {
+	a := newAPI(t)
+	alice, aliceTokens := a.register("alice")
+	bob, bobTokens := a.register("bob")
+	token := aliceTokens.AccessToken
+
+	a.do(http.MethodPost, "/posts", token, map[string]string{"content": "no title"}, nil, http.StatusBadRequest)
+
+	var first, second models.Post
+	a.do(http.MethodPost, "/posts", token, map[string]string{"title": "First", "content": "Hello"}, &first, http.StatusCreated)
+	a.do(http.MethodPost, "/posts", token, map[string]string{"title": "Second", "content": "World"}, &second, http.StatusCreated)
+
+	var feed page[models.Post]
+	a.do(http.MethodGet, "/posts?limit=1", token, nil, &feed, http.StatusOK)
+	if len(feed.Data) != 1 || feed.Data[0].ID != second.ID || feed.NextCursor == "" {
+		t.Fatalf("first page of the feed is %+v", feed)
+	}
+
+	cursor := feed.NextCursor
+	feed = page[models.Post]{}
+	a.do(http.MethodGet, "/posts?limit=1&cursor="+cursor, token, nil, &feed, http.StatusOK)
+	if len(feed.Data) != 1 || feed.Data[0].ID != first.ID || feed.NextCursor != "" {
+		t.Fatalf("second page of the feed is %+v", feed)
+	}
+
+	a.do(http.MethodGet, "/posts", bobTokens.AccessToken, nil, &feed, http.StatusOK)
+	if len(feed.Data) != 0 {
+		t.Fatalf("bob sees %+v before following alice", feed.Data)
+	}
+
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/follow", alice.ID), bobTokens.AccessToken, nil, nil, http.StatusNoContent)
+	a.do(http.MethodGet, "/posts", bobTokens.AccessToken, nil, &feed, http.StatusOK)
+	if len(feed.Data) != 2 {
+		t.Fatalf("bob sees %+v after following alice", feed.Data)
+	}
+
+	a.do(http.MethodPut, fmt.Sprintf("/posts/%d", first.ID), token,
+		map[string]string{"title": "First", "content": "Hello again"}, nil, http.StatusNoContent)
+	a.do(http.MethodPut, fmt.Sprintf("/posts/%d", first.ID), bobTokens.AccessToken,
+		map[string]string{"title": "Mine", "content": "Now"}, nil, http.StatusForbidden)
+
+	a.do(http.MethodPost, fmt.Sprintf("/posts/%d/like", first.ID), bobTokens.AccessToken, nil, nil, http.StatusNoContent)
+	a.do(http.MethodPost, fmt.Sprintf("/posts/%d/like", first.ID), bobTokens.AccessToken, nil, nil, http.StatusNoContent)
+
+	var post models.Post
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d", first.ID), bobTokens.AccessToken, nil, &post, http.StatusOK)
+	if post.Content != "Hello again" || post.Likes != 1 || !post.LikedByMe || post.AuthorNick != "alice" {
+		t.Fatalf("got post %+v", post)
+	}
+
+	a.do(http.MethodPost, fmt.Sprintf("/posts/%d/like", first.ID), token, nil, nil, http.StatusNoContent)
+
+	var likes page[models.User]
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d/likes?limit=1", first.ID), token, nil, &likes, http.StatusOK)
+	if len(likes.Data) != 1 || likes.Data[0].ID != alice.ID || likes.NextCursor == "" {
+		t.Fatalf("first page of the likes is %+v", likes)
+	}
+
+	cursor = likes.NextCursor
+	likes = page[models.User]{}
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d/likes?limit=1&cursor=%s", first.ID, cursor), token, nil, &likes, http.StatusOK)
+	if len(likes.Data) != 1 || likes.Data[0].ID != bob.ID || likes.NextCursor != "" {
+		t.Fatalf("second page of the likes is %+v", likes)
+	}
+
+	a.do(http.MethodPost, fmt.Sprintf("/posts/%d/dislike", first.ID), token, nil, nil, http.StatusNoContent)
+	a.do(http.MethodPost, fmt.Sprintf("/posts/%d/dislike", first.ID), bobTokens.AccessToken, nil, nil, http.StatusNoContent)
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d", first.ID), token, nil, &post, http.StatusOK)
+	if post.Likes != 0 {
+		t.Fatalf("post still has %d likes", post.Likes)
+	}
+
+	var byUser page[models.Post]
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d/posts", alice.ID), bobTokens.AccessToken, nil, &byUser, http.StatusOK)
+	if len(byUser.Data) != 2 {
+		t.Fatalf("alice has posts %+v", byUser.Data)
+	}
+
+	a.do(http.MethodDelete, fmt.Sprintf("/posts/%d", first.ID), bobTokens.AccessToken, nil, nil, http.StatusForbidden)
+	a.do(http.MethodDelete, fmt.Sprintf("/posts/%d", first.ID), token, nil, nil, http.StatusNoContent)
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d", first.ID), token, nil, nil, http.StatusNotFound)
+}
+
+func TestAttachments(t *testing.T) {
+	a := newAPI(t)
+	_, aliceTokens := a.register("alice")
+	_, bobTokens := a.register("bob")
+	token := aliceTokens.AccessToken
+
+	var post models.Post
+	a.do(http.MethodPost, "/posts", token, map[string]string{"title": "Holiday", "content": "Pictures"}, &post, http.StatusCreated)
+	if post.Attachments == nil || len(post.Attachments) != 0 {
+		t.Fatalf("new post has attachments %+v", post.Attachments)
+	}
+
+	picture := image.NewRGBA(image.Rect(0, 0, 640, 480))
+	for x := 0; x < 640; x++ {
+		picture.Set(x, x%480, color.RGBA{R: 255, A: 255})
+	}
+	var encoded bytes.Buffer
+	png.Encode(&encoded, picture)
+
+	a.upload(post.ID, token, []byte("not an image"), "", nil, http.StatusBadRequest)
+	a.upload(post.ID, bobTokens.AccessToken, encoded.Bytes(), "", nil, http.StatusForbidden)
+	a.upload(post.ID, token, encoded.Bytes(), strings.Repeat("a", 501), nil, http.StatusBadRequest)
+
+	var attachment models.Attachment
+	a.upload(post.ID, token, encoded.Bytes(), "  A red line  ", &attachment, http.StatusCreated)
+	if attachment.Width != 640 || attachment.Height != 480 || attachment.AltText != "A red line" ||
+		attachment.ContentType != "image/png" || !strings.HasPrefix(attachment.URL, "/media/posts/") {
+		t.Fatalf("got attachment %+v", attachment)
+	}
+
+	stored := filepath.Join(a.mediaDir, strings.TrimPrefix(attachment.URL, "/media/"))
+	if _, err := os.Stat(stored); err != nil {
+		t.Fatalf("uploaded image was not stored: %v", err)
+	}
+
+	thumbnailFile, err := os.Open(filepath.Join(a.mediaDir, strings.TrimPrefix(attachment.ThumbnailURL, "/media/")))
+	if err != nil {
+		t.Fatalf("thumbnail was not stored: %v", err)
+	}
+	thumbnail, err := png.DecodeConfig(thumbnailFile)
+	thumbnailFile.Close()
+	if err != nil || thumbnail.Width != 320 || thumbnail.Height != 240 {
+		t.Fatalf("thumbnail is %+v (%v)", thumbnail, err)
+	}
+
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), bobTokens.AccessToken, nil, &post, http.StatusOK)
+	if len(post.Attachments) != 1 || post.Attachments[0].URL != attachment.URL {
+		t.Fatalf("post has attachments %+v", post.Attachments)
+	}
+
+	for i := 1; i < models.MaxAttachments; i++ {
+		a.upload(post.ID, token, encoded.Bytes(), "", nil, http.StatusCreated)
+	}
+	a.upload(post.ID, token, encoded.Bytes(), "", nil, http.StatusConflict)
+
+	a.do(http.MethodDelete, fmt.Sprintf("/posts/%d/attachments/%d", post.ID, attachment.ID), bobTokens.AccessToken, nil, nil, http.StatusForbidden)
+	a.do(http.MethodDelete, fmt.Sprintf("/posts/%d/attachments/%d", post.ID, attachment.ID), token, nil, nil, http.StatusNoContent)
+	if _, err := os.Stat(stored); !os.IsNotExist(err) {
+		t.Fatalf("deleted image is still stored: %v", err)
+	}
+
+	a.do(http.MethodDelete, fmt.Sprintf("/posts/%d", post.ID), token, nil, nil, http.StatusNoContent)
+	entries, _ := filepath.Glob(filepath.Join(a.mediaDir, "posts", "*", "*"))
+	if len(entries) != 0 {
+		t.Fatalf("files left after deleting the post: %v", entries)
+	}
+
+	// Parallel uploads all pass the first check, the limit still holds when they are saved
+	a.do(http.MethodPost, "/posts", token, map[string]string{"title": "Album", "content": "More pictures"}, &post, http.StatusCreated)
+
+	statuses := make(chan int, 2*models.MaxAttachments)
+	var uploads sync.WaitGroup
+	for i := 0; i < 2*models.MaxAttachments; i++ {
+		uploads.Add(1)
+		go func() {
+			defer uploads.Done()
+
+			response, err := a.sendUpload(post.ID, token, encoded.Bytes(), "")
+			if err != nil {
+				statuses <- 0
+				return
+			}
+			response.Body.Close()
+			statuses <- response.StatusCode
+		}()
+	}
+	uploads.Wait()
+	close(statuses)
+
+	created := 0
+	for status := range statuses {
+		switch status {
+		case http.StatusCreated:
+			created++
+		case http.StatusConflict:
+		default:
+			t.Fatalf("a parallel upload got status %d", status)
+		}
+	}
+
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), token, nil, &post, http.StatusOK)
+	if created != models.MaxAttachments || len(post.Attachments) != models.MaxAttachments {
+		t.Fatalf("%d parallel uploads were accepted and the post has %d images", created, len(post.Attachments))
+	}
+
+	entries, _ = filepath.Glob(filepath.Join(a.mediaDir, "posts", fmt.Sprint(post.ID), "*"))
+	if len(entries) != 2*models.MaxAttachments {
+		t.Fatalf("the refused uploads left files behind: %v", entries)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	a := newAPI(t)
+	alice, aliceTokens := a.register("alice")
+	_, bobTokens := a.register("bob")
+
+	var golang, gopher, draft models.Post
+	a.do(http.MethodPost, "/posts", aliceTokens.AccessToken,
+		map[string]string{"title": "Learning Go", "content": "Go channels and goroutines are great"}, &golang, http.StatusCreated)
+	a.do(http.MethodPost, "/posts", bobTokens.AccessToken,
+		map[string]string{"title": "Goroutines everywhere", "content": "My goroutines leak, goroutines are hard"}, &gopher, http.StatusCreated)
+	a.do(http.MethodPost, "/posts", aliceTokens.AccessToken,
+		map[string]string{"title": "Draft", "content": "Notes about goroutines"}, &draft, http.StatusCreated)
+
+	a.do(http.MethodGet, "/search/posts?q=go", aliceTokens.AccessToken, nil, nil, http.StatusBadRequest)
+	a.do(http.MethodGet, "/search/posts?q=goroutine&since=yesterday", aliceTokens.AccessToken, nil, nil, http.StatusBadRequest)
+
+	var results page[models.PostSearchResult]
+	a.do(http.MethodGet, "/search/posts?q=goroutine", aliceTokens.AccessToken, nil, &results, http.StatusOK)
+	if len(results.Data) != 3 || results.Data[0].Post.ID != gopher.ID || results.Data[0].Rank != 1 {
+		t.Fatalf("search returned %+v", results.Data)
+	}
+
+	first := results.Data[0]
+	if len(first.Title.Highlights) != 1 || len(first.Snippet.Highlights) != 2 || first.Post.AuthorNick != "bob" {
+		t.Fatalf("first result is %+v", first)
+	}
+
+	results = page[models.PostSearchResult]{}
+	a.do(http.MethodGet, "/search/posts?q=goroutine&limit=2", aliceTokens.AccessToken, nil, &results, http.StatusOK)
+	cursor := results.NextCursor
+	results = page[models.PostSearchResult]{}
+	a.do(http.MethodGet, "/search/posts?q=goroutine&limit=2&cursor="+cursor, aliceTokens.AccessToken, nil, &results, http.StatusOK)
+	if len(results.Data) != 1 || results.Data[0].Rank != 3 || results.NextCursor != "" {
+		t.Fatalf("second page is %+v", results)
+	}
+
+	a.do(http.MethodGet, `/search/posts?q=%22channels+and+goroutines%22`, aliceTokens.AccessToken, nil, &results, http.StatusOK)
+	if len(results.Data) != 1 || results.Data[0].Post.ID != golang.ID {
+		t.Fatalf("phrase search returned %+v", results.Data)
+	}
+
+	a.do(http.MethodGet, fmt.Sprintf("/search/posts?q=goroutines+-notes&authorId=%d", alice.ID), aliceTokens.AccessToken, nil, &results, http.StatusOK)
+	if len(results.Data) != 1 || results.Data[0].Post.ID != golang.ID {
+		t.Fatalf("search by author returned %+v", results.Data)
+	}
+
+	a.do(http.MethodGet, "/search/posts?q=goroutines&until=2000-01-01", aliceTokens.AccessToken, nil, &results, http.StatusOK)
+	if len(results.Data) != 0 {
+		t.Fatalf("search before the posts existed returned %+v", results.Data)
+	}
+}
+
+func TestHashtags(t *testing.T) {
+	a := newAPI(t)
+	_, tokens := a.register("alice")
+	token := tokens.AccessToken
+
+	var first, second models.Post
+	a.do(http.MethodPost, "/posts", token,
+		map[string]string{"title": "Trip", "content": "Off to #Lisbon! #travel #TRAVEL a#b #2024"}, &first, http.StatusCreated)
+	a.do(http.MethodPost, "/posts", token,
+		map[string]string{"title": "Food", "content": "Pastéis in #lisbon #food"}, &second, http.StatusCreated)
+
+	var tagged page[models.Post]
+	a.do(http.MethodGet, "/hashtags/LISBON/posts", token, nil, &tagged, http.StatusOK)
+	if len(tagged.Data) != 2 || tagged.Data[0].ID != second.ID {
+		t.Fatalf("posts tagged lisbon are %+v", tagged.Data)
+	}
+
+	tagged = page[models.Post]{}
+	a.do(http.MethodGet, "/hashtags/b/posts", token, nil, &tagged, http.StatusOK)
+	if len(tagged.Data) != 0 {
+		t.Fatalf("a#b is not a tag but b has posts %+v", tagged.Data)
+	}
+	a.do(http.MethodGet, "/hashtags/2024/posts", token, nil, nil, http.StatusBadRequest)
+	a.do(http.MethodGet, "/hashtags/not-a-tag/posts", token, nil, nil, http.StatusBadRequest)
+
+	var trending []models.TrendingHashtag
+	a.do(http.MethodGet, "/hashtags/trending", token, nil, &trending, http.StatusOK)
+	if len(trending) != 3 || trending[0].Tag != "lisbon" || trending[0].Posts != 2 || trending[0].Score <= trending[1].Score {
+		t.Fatalf("trending tags are %+v", trending)
+	}
+
+	a.do(http.MethodGet, "/hashtags/trending?hours=1000", token, nil, nil, http.StatusBadRequest)
+
+	a.do(http.MethodPut, fmt.Sprintf("/posts/%d", first.ID), token,
+		map[string]string{"title": "Trip", "content": "Off to #Porto"}, nil, http.StatusNoContent)
+
+	tagged = page[models.Post]{}
+	a.do(http.MethodGet, "/hashtags/lisbon/posts", token, nil, &tagged, http.StatusOK)
+	if len(tagged.Data) != 1 || tagged.Data[0].ID != second.ID {
+		t.Fatalf("after the edit posts tagged lisbon are %+v", tagged.Data)
+	}
+
+	a.do(http.MethodGet, "/hashtags/porto/posts", token, nil, &tagged, http.StatusOK)
+	if len(tagged.Data) != 1 || tagged.Data[0].ID != first.ID {
+		t.Fatalf("posts tagged porto are %+v", tagged.Data)
+	}
+
+	a.do(http.MethodGet, "/hashtags/trending?limit=1", token, nil, &trending, http.StatusOK)
+	if len(trending) != 1 {
+		t.Fatalf("trending tags are %+v", trending)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	a := newAPI(t)
+	_, aliceTokens := a.register("alice")
+	bob, bobTokens := a.register("bob")
+	carol, carolTokens := a.register("carol")
+	token := aliceTokens.AccessToken
+
+	var post models.Post
+	a.do(http.MethodPost, "/posts", token,
+		map[string]string{"title": "Hi", "content": "Olá @Bob and @nobody, mail me at me@carol."}, &post, http.StatusCreated)
+	if len(post.Mentions) != 1 || post.Mentions[0] != (models.Mention{UserID: bob.ID, Nick: "bob", Start: 4, End: 8}) {
+		t.Fatalf("post mentions %+v", post.Mentions)
+	}
+
+	var notifications page[models.Notification]
+	a.do(http.MethodGet, "/notifications", bobTokens.AccessToken, nil, &notifications, http.StatusOK)
+	if len(notifications.Data) != 1 || notifications.Data[0].Type != models.NotificationMention {
+		t.Fatalf("bob was notified of %+v", notifications.Data)
+	}
+
+	var mentions page[models.Post]
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d/mentions", bob.ID), carolTokens.AccessToken, nil, &mentions, http.StatusOK)
+	if len(mentions.Data) != 1 || mentions.Data[0].ID != post.ID || len(mentions.Data[0].Mentions) != 1 {
+		t.Fatalf("posts mentioning bob are %+v", mentions.Data)
+	}
+
+	a.do(http.MethodPut, fmt.Sprintf("/posts/%d", post.ID), token,
+		map[string]string{"title": "Hi", "content": "@carol @bob @carol."}, nil, http.StatusNoContent)
+
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), token, nil, &post, http.StatusOK)
+	if len(post.Mentions) != 3 || post.Mentions[1].UserID != bob.ID || post.Mentions[2].Start != 12 || post.Mentions[2].End != 18 {
+		t.Fatalf("edited post mentions %+v", post.Mentions)
+	}
+
+	notifications = page[models.Notification]{}
+	a.do(http.MethodGet, "/notifications", bobTokens.AccessToken, nil, &notifications, http.StatusOK)
+	if len(notifications.Data) != 1 || notifications.Data[0].ActorCount != 1 {
+		t.Fatalf("bob was notified again: %+v", notifications.Data)
+	}
+
+	a.do(http.MethodGet, "/notifications", carolTokens.AccessToken, nil, &notifications, http.StatusOK)
+	if len(notifications.Data) != 1 || notifications.Data[0].Type != models.NotificationMention {
+		t.Fatalf("carol was notified of %+v", notifications.Data)
+	}
+
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d/mentions", carol.ID), token, nil, &mentions, http.StatusOK)
+	if len(mentions.Data) != 1 {
+		t.Fatalf("posts mentioning carol are %+v", mentions.Data)
+	}
+}
+
+func TestPrivateAccounts(t *testing.T) {
+	a := newAPI(t)
+	alice, aliceTokens := a.register("alice")
+	bob, bobTokens := a.register("bob")
+	carol, carolTokens := a.register("carol")
+
+	a.do(http.MethodPut, fmt.Sprintf("/users/%d/privacy", alice.ID), bobTokens.AccessToken, map[string]bool{"private": true}, nil, http.StatusForbidden)
+	a.do(http.MethodPut, fmt.Sprintf("/users/%d/privacy", alice.ID), aliceTokens.AccessToken, map[string]bool{"private": true}, nil, http.StatusNoContent)
+
+	var post models.Post
+	a.do(http.MethodPost, "/posts", aliceTokens.AccessToken, map[string]string{"title": "Secret", "content": "Only for #friends"}, &post, http.StatusCreated)
+
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), bobTokens.AccessToken, nil, nil, http.StatusNotFound)
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d/posts", alice.ID), bobTokens.AccessToken, nil, nil, http.StatusForbidden)
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d/followers", alice.ID), bobTokens.AccessToken, nil, nil, http.StatusForbidden)
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d/following", alice.ID), bobTokens.AccessToken, nil, nil, http.StatusForbidden)
+
+	var tagged page[models.Post]
+	a.do(http.MethodGet, "/hashtags/friends/posts", bobTokens.AccessToken, nil, &tagged, http.StatusOK)
+	if len(tagged.Data) != 0 {
+		t.Fatalf("bob found private posts %+v", tagged.Data)
+	}
+
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/follow", alice.ID), bobTokens.AccessToken, nil, nil, http.StatusAccepted)
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/follow", alice.ID), carolTokens.AccessToken, nil, nil, http.StatusAccepted)
+
+	var requests page[models.FollowRequest]
+	a.do(http.MethodGet, "/follow-requests", aliceTokens.AccessToken, nil, &requests, http.StatusOK)
+	if len(requests.Data) != 2 || requests.Data[0].Follower.ID != bob.ID || requests.Data[1].Follower.ID != carol.ID {
+		t.Fatalf("alice has follow requests %+v", requests.Data)
+	}
+
+	var followers page[models.User]
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d/followers", alice.ID), aliceTokens.AccessToken, nil, &followers, http.StatusOK)
+	if len(followers.Data) != 0 {
+		t.Fatalf("requests became follows before being approved: %+v", followers.Data)
+	}
+
+	bobRequest, carolRequest := requests.Data[0].ID, requests.Data[1].ID
+	a.do(http.MethodPost, fmt.Sprintf("/follow-requests/%d/approve", bobRequest), bobTokens.AccessToken, nil, nil, http.StatusNotFound)
+	a.do(http.MethodPost, fmt.Sprintf("/follow-requests/%d/approve", bobRequest), aliceTokens.AccessToken, nil, nil, http.StatusNoContent)
+	a.do(http.MethodPost, fmt.Sprintf("/follow-requests/%d/reject", carolRequest), aliceTokens.AccessToken, nil, nil, http.StatusNoContent)
+	a.do(http.MethodPost, fmt.Sprintf("/follow-requests/%d/reject", carolRequest), aliceTokens.AccessToken, nil, nil, http.StatusNotFound)
+
+	var notifications page[models.Notification]
+	a.do(http.MethodGet, "/notifications", bobTokens.AccessToken, nil, &notifications, http.StatusOK)
+	if len(notifications.Data) != 1 || notifications.Data[0].Type != models.NotificationFollowAccepted {
+		t.Fatalf("bob was notified of %+v", notifications.Data)
+	}
+
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), bobTokens.AccessToken, nil, nil, http.StatusOK)
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d/followers", alice.ID), bobTokens.AccessToken, nil, &followers, http.StatusOK)
+	if len(followers.Data) != 1 || followers.Data[0].ID != bob.ID {
+		t.Fatalf("alice is followed by %+v", followers.Data)
+	}
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), carolTokens.AccessToken, nil, nil, http.StatusNotFound)
+
+	// Withdrawing a request and making the account public again
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/follow", alice.ID), carolTokens.AccessToken, nil, nil, http.StatusAccepted)
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/unfollow", alice.ID), carolTokens.AccessToken, nil, nil, http.StatusNoContent)
+	requests = page[models.FollowRequest]{}
+	a.do(http.MethodGet, "/follow-requests", aliceTokens.AccessToken, nil, &requests, http.StatusOK)
+	if len(requests.Data) != 0 {
+		t.Fatalf("withdrawn requests are still pending: %+v", requests.Data)
+	}
+
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/follow", alice.ID), carolTokens.AccessToken, nil, nil, http.StatusAccepted)
+	a.do(http.MethodPut, fmt.Sprintf("/users/%d/privacy", alice.ID), aliceTokens.AccessToken, map[string]bool{"private": false}, nil, http.StatusNoContent)
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d/followers", alice.ID), carolTokens.AccessToken, nil, &followers, http.StatusOK)
+	if len(followers.Data) != 2 {
+		t.Fatalf("pending requests were not approved when alice became public: %+v", followers.Data)
+	}
+}
+
+func TestComments(t *testing.T) {
+	a := newAPI(t)
+	_, aliceTokens := a.register("alice")
+	_, bobTokens := a.register("bob")
+	token := aliceTokens.AccessToken
+
+	var post models.Post
+	a.do(http.MethodPost, "/posts", token, map[string]string{"title": "Post", "content": "Content"}, &post, http.StatusCreated)
+	path := fmt.Sprintf("/posts/%d/comments", post.ID)
+
+	var comment, reply models.Comment
+	a.do(http.MethodPost, path, bobTokens.AccessToken, map[string]any{"content": "Nice"}, &comment, http.StatusCreated)
+	a.do(http.MethodPost, path, token, map[string]any{"content": "Thanks", "parentCommentId": comment.ID}, &reply, http.StatusCreated)
+	a.do(http.MethodPost, path, token, map[string]any{"content": ""}, nil, http.StatusBadRequest)
+	a.do(http.MethodPost, path, token, map[string]any{"content": strings.Repeat("é", 301)}, nil, http.StatusBadRequest)
+	a.do(http.MethodPost, "/posts/999/comments", token, map[string]any{"content": "Lost"}, nil, http.StatusNotFound)
+
+	var comments page[models.Comment]
+	a.do(http.MethodGet, path, token, nil, &comments, http.StatusOK)
+	if len(comments.Data) != 2 || comments.Data[1].ParentCommentID == nil || *comments.Data[1].ParentCommentID != comment.ID {
+		t.Fatalf("comments are %+v", comments.Data)
+	}
+
+	commentPath := fmt.Sprintf("%s/%d", path, comment.ID)
+	a.do(http.MethodPut, commentPath, token, map[string]any{"content": "Edited"}, nil, http.StatusForbidden)
+	a.do(http.MethodPut, commentPath, bobTokens.AccessToken, map[string]any{"content": "Edited"}, nil, http.StatusNoContent)
+
+	var got models.Post
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), token, nil, &got, http.StatusOK)
+	if got.CommentCount != 2 {
+		t.Fatalf("post has %d comments", got.CommentCount)
+	}
+
+	// The author of the post can moderate the comments under it
+	a.do(http.MethodDelete, commentPath, token, nil, nil, http.StatusNoContent)
+	a.do(http.MethodGet, path, token, nil, &comments, http.StatusOK)
+	if len(comments.Data) != 0 {
+		t.Fatalf("replies survived their parent: %+v", comments.Data)
+	}
+
+	// The limit counts characters, not bytes
+	long := strings.Repeat("é", 150) + strings.Repeat("🙂", 150)
+	a.do(http.MethodPost, path, bobTokens.AccessToken, map[string]any{"content": long}, &comment, http.StatusCreated)
+	if comment.Content != long {
+		t.Fatalf("the comment was saved as %q", comment.Content)
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	a := newAPI(t)
+	admin, adminTokens := a.register("admin")
+	bob, bobTokens := a.register("bob")
+
+	a.do(http.MethodPost, fmt.Sprintf("/admin/users/%d/suspend", admin.ID), bobTokens.AccessToken, nil, nil, http.StatusForbidden)
+
+	if err := a.db.Users().UpdateRole(admin.ID, models.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	a.do(http.MethodPost, fmt.Sprintf("/admin/users/%d/suspend", bob.ID), adminTokens.AccessToken, nil, nil, http.StatusForbidden)
+
+	token := a.login("admin").AccessToken
+
+	a.do(http.MethodPut, fmt.Sprintf("/admin/users/%d/role", bob.ID), token, models.RoleRequest{Role: "owner"}, nil, http.StatusBadRequest)
+	a.do(http.MethodPut, fmt.Sprintf("/admin/users/%d/role", bob.ID), token, models.RoleRequest{Role: models.RoleModerator}, nil, http.StatusNoContent)
+	a.do(http.MethodGet, "/posts", bobTokens.AccessToken, nil, nil, http.StatusUnauthorized)
+
+	moderator := a.login("bob").AccessToken
+	var post models.Post
+	a.do(http.MethodPost, "/posts", token, map[string]string{"title": "Rules", "content": "Be nice"}, &post, http.StatusCreated)
+	a.do(http.MethodPut, fmt.Sprintf("/admin/users/%d/role", admin.ID), moderator, models.RoleRequest{Role: models.RoleUser}, nil, http.StatusForbidden)
+	a.do(http.MethodPost, fmt.Sprintf("/admin/users/%d/suspend", admin.ID), moderator, nil, nil, http.StatusForbidden)
+	a.do(http.MethodDelete, fmt.Sprintf("/admin/posts/%d", post.ID), moderator, nil, nil, http.StatusNoContent)
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), token, nil, nil, http.StatusNotFound)
+
+	a.do(http.MethodPost, fmt.Sprintf("/admin/users/%d/suspend", bob.ID), token, nil, nil, http.StatusNoContent)
+	a.do(http.MethodPost, "/login", "", models.UserRequest{Email: "bob@example.com", Password: "secret"}, nil, http.StatusForbidden)
+
+	// Only admins lift the suspension of another moderator
+	carol, _ := a.register("carol")
+	a.do(http.MethodPut, fmt.Sprintf("/admin/users/%d/role", carol.ID), token, models.RoleRequest{Role: models.RoleModerator}, nil, http.StatusNoContent)
+	otherModerator := a.login("carol").AccessToken
+	a.do(http.MethodPost, fmt.Sprintf("/admin/users/%d/unsuspend", bob.ID), otherModerator, nil, nil, http.StatusForbidden)
+	a.do(http.MethodPost, "/admin/users/9999/unsuspend", otherModerator, nil, nil, http.StatusNotFound)
+
+	a.do(http.MethodPost, fmt.Sprintf("/admin/users/%d/unsuspend", bob.ID), token, nil, nil, http.StatusNoContent)
+	a.login("bob")
+}
+
+func TestNotifications(t *testing.T) {
+	a := newAPI(t)
+	alice, aliceTokens := a.register("alice")
+	_, bobTokens := a.register("bob")
+	_, carolTokens := a.register("carol")
+	token := aliceTokens.AccessToken
+
+	var post models.Post
+	a.do(http.MethodPost, "/posts", token, map[string]string{"title": "Post", "content": "Content"}, &post, http.StatusCreated)
+
+	a.do(http.MethodPost, fmt.Sprintf("/posts/%d/like", post.ID), token, nil, nil, http.StatusNoContent)
+	a.do(http.MethodPost, fmt.Sprintf("/posts/%d/like", post.ID), bobTokens.AccessToken, nil, nil, http.StatusNoContent)
+	a.do(http.MethodPost, fmt.Sprintf("/posts/%d/like", post.ID), carolTokens.AccessToken, nil, nil, http.StatusNoContent)
+	a.do(http.MethodPost, fmt.Sprintf("/posts/%d/comments", post.ID), bobTokens.AccessToken, map[string]any{"content": "Hi"}, nil, http.StatusCreated)
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/follow", alice.ID), bobTokens.AccessToken, nil, nil, http.StatusNoContent)
+
+	var count models.UnreadCount
+	a.do(http.MethodGet, "/notifications/unread-count", token, nil, &count, http.StatusOK)
+	if count.Unread != 3 {
+		t.Fatalf("alice has %d unread notifications, want 3", count.Unread)
+	}
+
+	var notifications page[models.Notification]
+	a.do(http.MethodGet, "/notifications", token, nil, &notifications, http.StatusOK)
+	if len(notifications.Data) != 3 {
+		t.Fatalf("notifications are %+v", notifications.Data)
+	}
+
+	likes := notifications.Data[2]
+	if likes.Type != models.NotificationLike || likes.ActorCount != 2 || likes.Message != "carol and 1 other liked your post" {
+		t.Fatalf("likes were grouped as %+v", likes)
+	}
+
+	a.do(http.MethodPost, "/notifications/read", token, models.NotificationsReadRequest{IDs: []uint64{likes.ID}}, nil, http.StatusNoContent)
+	a.do(http.MethodGet, "/notifications?unread=true", token, nil, &notifications, http.StatusOK)
+	if len(notifications.Data) != 2 {
+		t.Fatalf("unread notifications are %+v", notifications.Data)
+	}
+
+	a.do(http.MethodPost, "/notifications/read", token, nil, nil, http.StatusNoContent)
+	a.do(http.MethodGet, "/notifications/unread-count", token, nil, &count, http.StatusOK)
+	if count.Unread != 0 {
+		t.Fatalf("alice has %d unread notifications after reading all", count.Unread)
+	}
+
+	a.do(http.MethodGet, "/notifications?unread=maybe", token, nil, nil, http.StatusBadRequest)
+}
+
+// stream opens the event stream, the subscription is registered once the headers arrive
+func (a *api) stream(token, lastEventID string) (*bufio.Reader, func()) {
+	a.t.Helper()
+
+	request, err := http.NewRequest(http.MethodGet, a.server.URL+"/events", nil)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	request.Header.Set("Authorization", "Bearer "+token)
+	if lastEventID != "" {
+		request.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	response, err := a.server.Client().Do(request)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "text/event-stream" {
+		a.t.Fatalf("GET /events: got status %d and content type %q", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+
+	return bufio.NewReader(response.Body), func() { response.Body.Close() }
+}
+
+// nextEvent reads the fields of the next event of the stream, skipping comments and the retry hint
+func nextEvent(t *testing.T, reader *bufio.Reader) map[string]string {
+	t.Helper()
+
+	fields := map[string]string{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading the stream: %v", err)
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if fields["event"] != "" {
+				return fields
+			}
+			continue
+		}
+
+		if name, value, found := strings.Cut(line, ": "); found && name != "" {
+			fields[name] = value
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	a := newAPI(t)
+	alice, aliceTokens := a.register("alice")
+	bob, bobTokens := a.register("bob")
+
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/follow", alice.ID), bobTokens.AccessToken, nil, nil, http.StatusNoContent)
+
+	aliceStream, closeAlice := a.stream(aliceTokens.AccessToken, "")
+	defer closeAlice()
+	bobStream, closeBob := a.stream(bobTokens.AccessToken, "")
+
+	var post models.Post
+	a.do(http.MethodPost, "/posts", aliceTokens.AccessToken, map[string]string{"title": "Live", "content": "Now"}, &post, http.StatusCreated)
+
+	event := nextEvent(t, bobStream)
+	if event["event"] != events.PostCreated || !strings.Contains(event["data"], `"title":"Live"`) {
+		t.Fatalf("bob received %v", event)
+	}
+	bobLastEventID := event["id"]
+	closeBob()
+
+	a.do(http.MethodPost, fmt.Sprintf("/posts/%d/like", post.ID), bobTokens.AccessToken, nil, nil, http.StatusNoContent)
+	event = nextEvent(t, aliceStream)
+	if event["event"] != events.PostLiked || event["data"] != fmt.Sprintf(`{"postId":%d,"userId":%d}`, post.ID, bob.ID) {
+		t.Fatalf("alice received %v", event)
+	}
+
+	// Bob missed this post while disconnected and receives it after reconnecting
+	a.do(http.MethodPost, "/posts", aliceTokens.AccessToken, map[string]string{"title": "Missed", "content": "It"}, nil, http.StatusCreated)
+
+	bobStream, closeBob = a.stream(bobTokens.AccessToken, bobLastEventID)
+	defer closeBob()
+
+	event = nextEvent(t, bobStream)
+	if event["event"] != events.PostCreated || !strings.Contains(event["data"], `"title":"Missed"`) {
+		t.Fatalf("bob replayed %v", event)
+	}
+
+	a.do(http.MethodGet, "/events", "", nil, nil, http.StatusUnauthorized)
+}
+
+func TestMessages(t *testing.T) {
+	a := newAPI(t)
+	alice, aliceTokens := a.register("alice")
+	bob, bobTokens := a.register("bob")
+	carol, carolTokens := a.register("carol")
+	token := aliceTokens.AccessToken
+
+	toBob := fmt.Sprintf("/users/%d/messages", bob.ID)
+	a.do(http.MethodPost, toBob, token, map[string]string{"content": "Hi"}, nil, http.StatusForbidden)
+
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/follow", bob.ID), token, nil, nil, http.StatusNoContent)
+	a.do(http.MethodPost, toBob, token, map[string]string{"content": "Hi"}, nil, http.StatusForbidden)
+
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/follow", alice.ID), bobTokens.AccessToken, nil, nil, http.StatusNoContent)
+	a.do(http.MethodPost, toBob, token, map[string]string{"content": " "}, nil, http.StatusBadRequest)
+
+	bobStream, closeBob := a.stream(bobTokens.AccessToken, "")
+	defer closeBob()
+
+	var first models.Message
+	a.do(http.MethodPost, toBob, token, map[string]string{"content": "Hi"}, &first, http.StatusCreated)
+	a.do(http.MethodPost, toBob, token, map[string]string{"content": "Are you there?"}, nil, http.StatusCreated)
+
+	if event := nextEvent(t, bobStream); event["event"] != events.MessageReceived || !strings.Contains(event["data"], `"content":"Hi"`) {
+		t.Fatalf("bob received %v", event)
+	}
+
+	var conversations page[models.Conversation]
+	a.do(http.MethodGet, "/conversations", bobTokens.AccessToken, nil, &conversations, http.StatusOK)
+	if len(conversations.Data) != 1 {
+		t.Fatalf("bob has conversations %+v", conversations.Data)
+	}
+
+	conversation := conversations.Data[0]
+	if conversation.Participant.ID != alice.ID || conversation.UnreadCount != 2 || conversation.LastMessage.Content != "Are you there?" {
+		t.Fatalf("bob sees the conversation as %+v", conversation)
+	}
+
+	messagesPath := fmt.Sprintf("/conversations/%d/messages", conversation.ID)
+	var messages page[models.Message]
+	a.do(http.MethodGet, messagesPath+"?limit=1", bobTokens.AccessToken, nil, &messages, http.StatusOK)
+	if len(messages.Data) != 1 || messages.Data[0].Content != "Are you there?" || messages.NextCursor == "" {
+		t.Fatalf("first page of messages is %+v", messages)
+	}
+	a.do(http.MethodGet, messagesPath, carolTokens.AccessToken, nil, nil, http.StatusNotFound)
+
+	a.do(http.MethodPost, fmt.Sprintf("/conversations/%d/read", conversation.ID), bobTokens.AccessToken,
+		models.ReadMarkerRequest{MessageID: first.ID}, nil, http.StatusNoContent)
+	a.do(http.MethodGet, "/conversations", bobTokens.AccessToken, nil, &conversations, http.StatusOK)
+	if conversations.Data[0].UnreadCount != 1 || conversations.Data[0].LastReadMessageID != first.ID {
+		t.Fatalf("after reading the first message bob sees %+v", conversations.Data[0])
+	}
+
+	// Once the conversation exists either side can write, muted messages are not pushed
+	a.do(http.MethodPost, fmt.Sprintf("/conversations/%d/mute", conversation.ID), token, nil, nil, http.StatusNoContent)
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/messages", alice.ID), bobTokens.AccessToken, map[string]string{"content": "Yes"}, nil, http.StatusCreated)
+	a.do(http.MethodGet, "/conversations", token, nil, &conversations, http.StatusOK)
+	if !conversations.Data[0].Muted || conversations.Data[0].UnreadCount != 1 || conversations.Data[0].ParticipantLastReadMessageID == 0 {
+		t.Fatalf("alice sees the conversation as %+v", conversations.Data[0])
+	}
+
+	a.do(http.MethodPut, fmt.Sprintf("/users/%d/message-settings", carol.ID), token, models.MessageSettings{MessagesFromAnyone: true}, nil, http.StatusForbidden)
+	a.do(http.MethodPut, fmt.Sprintf("/users/%d/message-settings", carol.ID), carolTokens.AccessToken, models.MessageSettings{MessagesFromAnyone: true}, nil, http.StatusNoContent)
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/messages", carol.ID), token, map[string]string{"content": "Hello stranger"}, nil, http.StatusCreated)
+
+	// The limit counts characters, not bytes
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/messages", carol.ID), token, map[string]string{"content": strings.Repeat("🙂", 1000)}, nil, http.StatusCreated)
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/messages", carol.ID), token, map[string]string{"content": strings.Repeat("🙂", 1001)}, nil, http.StatusBadRequest)
+}
+
+func TestBlocksAndMutes(t *testing.T) {
+	a := newAPI(t)
+	alice, aliceTokens := a.register("alice")
+	bob, bobTokens := a.register("bob")
+	carol, carolTokens := a.register("carol")
+
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/follow", bob.ID), aliceTokens.AccessToken, nil, nil, http.StatusNoContent)
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/follow", alice.ID), bobTokens.AccessToken, nil, nil, http.StatusNoContent)
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/follow", carol.ID), aliceTokens.AccessToken, nil, nil, http.StatusNoContent)
+
+	var bobPost, carolPost models.Post
+	a.do(http.MethodPost, "/posts", bobTokens.AccessToken, map[string]string{"title": "Bob", "content": "About #gophers"}, &bobPost, http.StatusCreated)
+	a.do(http.MethodPost, "/posts", carolTokens.AccessToken, map[string]string{"title": "Carol", "content": "Carol posting"}, &carolPost, http.StatusCreated)
+
+	// Muting only hides the posts from the feed
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/mute", alice.ID), aliceTokens.AccessToken, nil, nil, http.StatusForbidden)
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/mute", carol.ID), aliceTokens.AccessToken, nil, nil, http.StatusNoContent)
+
+	var feed page[models.Post]
+	a.do(http.MethodGet, "/posts", aliceTokens.AccessToken, nil, &feed, http.StatusOK)
+	if len(feed.Data) != 1 || feed.Data[0].ID != bobPost.ID {
+		t.Fatalf("alice's feed has %+v", feed.Data)
+	}
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d", carolPost.ID), aliceTokens.AccessToken, nil, nil, http.StatusOK)
+
+	var mutes page[models.User]
+	a.do(http.MethodGet, "/mutes", aliceTokens.AccessToken, nil, &mutes, http.StatusOK)
+	if len(mutes.Data) != 1 || mutes.Data[0].ID != carol.ID {
+		t.Fatalf("alice muted %+v", mutes.Data)
+	}
+
+	a.do(http.MethodDelete, fmt.Sprintf("/users/%d/mute", carol.ID), aliceTokens.AccessToken, nil, nil, http.StatusNoContent)
+	feed = page[models.Post]{}
+	a.do(http.MethodGet, "/posts", aliceTokens.AccessToken, nil, &feed, http.StatusOK)
+	if len(feed.Data) != 2 {
+		t.Fatalf("alice's feed has %+v after unmuting", feed.Data)
+	}
+
+	// Blocking removes the follows both ways and hides the users from each other
+	a.do(http.MethodPost, "/users/999/block", aliceTokens.AccessToken, nil, nil, http.StatusNotFound)
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/block", bob.ID), aliceTokens.AccessToken, nil, nil, http.StatusNoContent)
+
+	var following page[models.User]
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d/following", alice.ID), aliceTokens.AccessToken, nil, &following, http.StatusOK)
+	if len(following.Data) != 1 || following.Data[0].ID != carol.ID {
+		t.Fatalf("alice follows %+v after blocking bob", following.Data)
+	}
+
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/follow", alice.ID), bobTokens.AccessToken, nil, nil, http.StatusForbidden)
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/follow", bob.ID), aliceTokens.AccessToken, nil, nil, http.StatusForbidden)
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d", alice.ID), bobTokens.AccessToken, nil, nil, http.StatusNotFound)
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d/posts", alice.ID), bobTokens.AccessToken, nil, nil, http.StatusNotFound)
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d", bobPost.ID), aliceTokens.AccessToken, nil, nil, http.StatusNotFound)
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/messages", alice.ID), bobTokens.AccessToken, map[string]string{"content": "hi"}, nil, http.StatusForbidden)
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d/mentions", alice.ID), bobTokens.AccessToken, nil, nil, http.StatusNotFound)
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d/mentions", bob.ID), aliceTokens.AccessToken, nil, nil, http.StatusNotFound)
+
+	var found page[models.User]
+	a.do(http.MethodGet, "/users?user=alice", bobTokens.AccessToken, nil, &found, http.StatusOK)
+	if len(found.Data) != 0 {
+		t.Fatalf("bob found %+v", found.Data)
+	}
+	a.do(http.MethodGet, "/users?user=bob", aliceTokens.AccessToken, nil, &found, http.StatusOK)
+	if len(found.Data) != 0 {
+		t.Fatalf("alice found %+v", found.Data)
+	}
+
+	var tagged page[models.Post]
+	a.do(http.MethodGet, "/hashtags/gophers/posts", aliceTokens.AccessToken, nil, &tagged, http.StatusOK)
+	if len(tagged.Data) != 0 {
+		t.Fatalf("alice found the posts of bob %+v", tagged.Data)
+	}
+
+	// Blocked users are not linked nor notified when mentioned
+	var mention models.Post
+	a.do(http.MethodPost, "/posts", bobTokens.AccessToken, map[string]string{"title": "Hey", "content": "Hello @alice and @carol"}, &mention, http.StatusCreated)
+	if len(mention.Mentions) != 1 || mention.Mentions[0].UserID != carol.ID {
+		t.Fatalf("bob mentioned %+v", mention.Mentions)
+	}
+
+	var blocks page[models.User]
+	a.do(http.MethodGet, "/blocks", aliceTokens.AccessToken, nil, &blocks, http.StatusOK)
+	if len(blocks.Data) != 1 || blocks.Data[0].ID != bob.ID {
+		t.Fatalf("alice blocked %+v", blocks.Data)
+	}
+
+	a.do(http.MethodDelete, fmt.Sprintf("/users/%d/block", bob.ID), aliceTokens.AccessToken, nil, nil, http.StatusNoContent)
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d", alice.ID), bobTokens.AccessToken, nil, nil, http.StatusOK)
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d/mentions", alice.ID), bobTokens.AccessToken, nil, nil, http.StatusOK)
+	a.do(http.MethodGet, "/users?user=alice", bobTokens.AccessToken, nil, &found, http.StatusOK)
+	if len(found.Data) != 1 || found.Data[0].ID != alice.ID {
+		t.Fatalf("bob found %+v after the unblock", found.Data)
+	}
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/follow", alice.ID), bobTokens.AccessToken, nil, nil, http.StatusNoContent)
+}
+
+func TestRateLimits(t *testing.T) {
+	a := newAPI(t)
+	_, aliceTokens := a.register("alice")
+
+	// The registration above already logged in once, the emails change so the
+	// failed login throttle of a single account does not step in first
+	var wrong models.UserRequest
+	for i := 0; i < 9; i++ {
+		wrong = models.UserRequest{Email: fmt.Sprintf("nobody%d@example.com", i), Password: "wrong"}
+		a.do(http.MethodPost, "/login", "", wrong, nil, http.StatusUnauthorized)
+	}
+
+	body, _ := json.Marshal(wrong)
+	response, err := a.server.Client().Post(a.server.URL+"/login", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("the eleventh login got status %d", response.StatusCode)
+	}
+	if retryAfter := response.Header.Get("Retry-After"); retryAfter != "6" {
+		t.Fatalf("Retry-After is %q", retryAfter)
+	}
+	if limit, remaining := response.Header.Get("RateLimit-Limit"), response.Header.Get("RateLimit-Remaining"); limit != "10" || remaining != "0" {
+		t.Fatalf("RateLimit-Limit is %q and RateLimit-Remaining is %q", limit, remaining)
+	}
+
+	// A token comes back every six seconds
+	a.clock.Advance(6 * time.Second)
+	a.do(http.MethodPost, "/login", "", wrong, nil, http.StatusUnauthorized)
+	a.do(http.MethodPost, "/login", "", wrong, nil, http.StatusTooManyRequests)
+
+	// The authenticated routes are counted per user, with their own buckets
+	request, _ := http.NewRequest(http.MethodGet, a.server.URL+"/users?user=ali", nil)
+	request.Header.Set("Authorization", "Bearer "+aliceTokens.AccessToken)
+	response, err = a.server.Client().Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK || response.Header.Get("RateLimit-Remaining") != "299" {
+		t.Fatalf("got status %d with RateLimit-Remaining %q", response.StatusCode, response.Header.Get("RateLimit-Remaining"))
+	}
+}
+
+func TestEmailVerification(t *testing.T) {
+	a := newAPI(t)
+	alice, aliceTokens := a.signup("alice")
+	token := aliceTokens.AccessToken
+
+	if alice.EmailVerifiedAt != nil {
+		t.Fatalf("alice is verified before confirming: %+v", alice)
+	}
+
+	post := map[string]string{"title": "Hello", "content": "First post"}
+	a.do(http.MethodPost, "/posts", token, post, nil, http.StatusForbidden)
+
+	first := a.mailedToken(alice.Email, "/verify-email")
+	a.do(http.MethodPost, "/auth/verify-email/resend", token, nil, nil, http.StatusNoContent)
+	second := a.mailedToken(alice.Email, "/verify-email")
+	if first == second {
+		t.Fatal("resending reused the token")
+	}
+
+	a.do(http.MethodPost, "/auth/verify-email", "", models.VerifyEmailRequest{Token: "forged.token"}, nil, http.StatusBadRequest)
+	a.do(http.MethodPost, "/auth/verify-email", "", models.VerifyEmailRequest{Token: second}, nil, http.StatusNoContent)
+	a.do(http.MethodPost, "/auth/verify-email", "", models.VerifyEmailRequest{Token: second}, nil, http.StatusBadRequest)
+	a.do(http.MethodPost, "/auth/verify-email", "", models.VerifyEmailRequest{Token: first}, nil, http.StatusBadRequest)
+	a.do(http.MethodPost, "/auth/verify-email/resend", token, nil, nil, http.StatusConflict)
+
+	a.do(http.MethodPost, "/posts", token, post, nil, http.StatusCreated)
+
+	// A new address has to be confirmed again
+	a.do(http.MethodPut, fmt.Sprintf("/users/%d", alice.ID), token, map[string]string{
+		"name": "Alice", "nick": "alice", "email": "alice@example.org",
+	}, nil, http.StatusNoContent)
+	a.do(http.MethodPost, "/posts", token, post, nil, http.StatusForbidden)
+
+	a.do(http.MethodPost, "/auth/verify-email", "", models.VerifyEmailRequest{
+		Token: a.mailedToken("alice@example.org", "/verify-email"),
+	}, nil, http.StatusNoContent)
+
+	var user models.User
+	a.do(http.MethodGet, fmt.Sprintf("/users/%d", alice.ID), token, nil, &user, http.StatusOK)
+	if user.EmailVerifiedAt == nil {
+		t.Fatalf("alice is not verified after confirming the new address: %+v", user)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	a := newAPI(t)
+	alice, aliceTokens := a.register("alice")
+	otherTokens := a.login("alice")
+
+	// Unknown addresses get the same answer and no email
+	a.do(http.MethodPost, "/auth/forgot-password", "", models.ForgotPasswordRequest{Email: "nobody@example.com"}, nil, http.StatusAccepted)
+
+	// The address is matched the way it was saved and the link goes to the saved one
+	a.do(http.MethodPost, "/auth/forgot-password", "", models.ForgotPasswordRequest{Email: " " + strings.ToUpper(alice.Email) + " "}, nil, http.StatusAccepted)
+	first := a.mailedToken(alice.Email, "/reset-password")
+
+	for _, message := range a.outbox.Messages() {
+		if message.To == "nobody@example.com" {
+			t.Fatalf("an email was sent to an unknown address: %+v", message)
+		}
+	}
+
+	a.do(http.MethodPost, "/auth/forgot-password", "", models.ForgotPasswordRequest{Email: alice.Email}, nil, http.StatusAccepted)
+	second := a.mailedToken(alice.Email, "/reset-password")
+	for deadline := time.Now().Add(2 * time.Second); second == first && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		second = a.mailedToken(alice.Email, "/reset-password")
+	}
+	if second == first {
+		t.Fatal("the second reset link was not sent")
+	}
+
+	a.do(http.MethodPost, "/auth/reset-password", "", models.ResetPasswordRequest{Token: second}, nil, http.StatusBadRequest)
+	a.do(http.MethodPost, "/auth/reset-password", "", models.ResetPasswordRequest{Token: "forged.token", Password: "changed"}, nil, http.StatusBadRequest)
+	a.do(http.MethodPost, "/auth/reset-password", "", models.ResetPasswordRequest{Token: second, Password: "changed"}, nil, http.StatusNoContent)
+
+	// The token and the other pending ones work once
+	a.do(http.MethodPost, "/auth/reset-password", "", models.ResetPasswordRequest{Token: second, Password: "again"}, nil, http.StatusBadRequest)
+	a.do(http.MethodPost, "/auth/reset-password", "", models.ResetPasswordRequest{Token: first, Password: "again"}, nil, http.StatusBadRequest)
+
+	// Every session ended
+	a.do(http.MethodGet, "/posts", aliceTokens.AccessToken, nil, nil, http.StatusUnauthorized)
+	a.do(http.MethodPost, "/auth/refresh", "", models.RefreshRequest{RefreshToken: otherTokens.RefreshToken}, nil, http.StatusUnauthorized)
+
+	a.do(http.MethodPost, "/login", "", models.UserRequest{Email: alice.Email, Password: "secret"}, nil, http.StatusUnauthorized)
+	a.do(http.MethodPost, "/login", "", models.UserRequest{Email: alice.Email, Password: "changed"}, nil, http.StatusOK)
+
+	// An address that was never confirmed does not receive reset links
+	bob, _ := a.signup("bob")
+	a.do(http.MethodPost, "/auth/forgot-password", "", models.ForgotPasswordRequest{Email: bob.Email}, nil, http.StatusAccepted)
+	time.Sleep(100 * time.Millisecond)
+	for _, message := range a.outbox.Messages() {
+		if message.To == bob.Email && strings.Contains(message.Body, "/reset-password") {
+			t.Fatalf("a reset link was sent to an unconfirmed address: %+v", message)
+		}
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	a := newAPI(t)
+	alice, _ := a.register("alice")
+	admin, _ := a.register("admin")
+	if err := a.db.Users().UpdateRole(admin.ID, models.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	adminToken := a.login("admin").AccessToken
+
+	// An unknown email and a wrong password get the same answer
+	var unknown, wrong map[string]any
+	a.do(http.MethodPost, "/login", "", models.UserRequest{Email: "nobody@example.com", Password: "secret"}, &unknown, http.StatusUnauthorized)
+	for i := 0; i < 3; i++ {
+		a.do(http.MethodPost, "/login", "", models.UserRequest{Email: "alice@example.com", Password: "wrong"}, &wrong, http.StatusUnauthorized)
+	}
+	if unknown["detail"] != wrong["detail"] {
+		t.Fatalf("unknown email got %v and wrong password got %v", unknown["detail"], wrong["detail"])
+	}
+
+	// After three failures even the right password has to wait
+	body, _ := json.Marshal(models.UserRequest{Email: "Alice@example.com ", Password: "secret"})
+	response, err := a.server.Client().Post(a.server.URL+"/login", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("the login after three failures got status %d", response.StatusCode)
+	}
+	if retryAfter := response.Header.Get("Retry-After"); retryAfter != "1" {
+		t.Fatalf("Retry-After is %q", retryAfter)
+	}
+
+	a.clock.Advance(time.Second)
+	a.login("alice")
+
+	var attempts page[models.LoginAttempt]
+	a.do(http.MethodGet, fmt.Sprintf("/admin/users/%d/login-attempts", alice.ID), adminToken, nil, &attempts, http.StatusOK)
+
+	var reasons []string
+	for _, attempt := range attempts.Data {
+		reasons = append(reasons, attempt.Reason)
+	}
+	want := []string{
+		models.LoginSucceeded, models.LoginThrottled,
+		models.LoginInvalidCredentials, models.LoginInvalidCredentials, models.LoginInvalidCredentials,
+		models.LoginSucceeded,
+	}
+	if fmt.Sprint(reasons) != fmt.Sprint(want) {
+		t.Fatalf("the login attempts of alice are %v, want %v", reasons, want)
+	}
+
+	a.do(http.MethodGet, fmt.Sprintf("/admin/users/%d/login-attempts", alice.ID), a.login("alice").AccessToken, nil, nil, http.StatusForbidden)
+}
+
+func TestTwoFactor(t *testing.T) {
+	a := newAPI(t)
+	_, aliceTokens := a.register("alice")
+	token := aliceTokens.AccessToken
+
+	a.do(http.MethodPost, "/auth/2fa/enable", token, models.TwoFactorCodeRequest{Code: "123456"}, nil, http.StatusConflict)
+
+	var setup models.TwoFactorSetup
+	a.do(http.MethodPost, "/auth/2fa/setup", token, nil, &setup, http.StatusOK)
+	if !strings.HasPrefix(setup.URI, "otpauth://totp/") || !strings.Contains(setup.URI, "secret="+setup.Secret) {
+		t.Fatalf("unexpected setup %+v", setup)
+	}
+
+	code, err := totp.Code(setup.Secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := fmt.Sprintf("%06d", (mustAtoi(t, code)+1)%1000000)
+
+	a.do(http.MethodPost, "/auth/2fa/enable", token, models.TwoFactorCodeRequest{Code: wrong}, nil, http.StatusBadRequest)
+
+	var recovery models.RecoveryCodes
+	a.do(http.MethodPost, "/auth/2fa/enable", token, models.TwoFactorCodeRequest{Code: code}, &recovery, http.StatusOK)
+	if len(recovery.RecoveryCodes) != 10 {
+		t.Fatalf("got %d recovery codes", len(recovery.RecoveryCodes))
+	}
+	a.do(http.MethodPost, "/auth/2fa/setup", token, nil, nil, http.StatusConflict)
+
+	// The password alone only gets a challenge, and the code that enabled 2FA cannot be replayed
+	var challenge models.TwoFactorChallenge
+	a.do(http.MethodPost, "/login", "", models.UserRequest{Email: "alice@example.com", Password: "secret"}, &challenge, http.StatusAccepted)
+	if challenge.ChallengeToken == "" {
+		t.Fatal("the login did not return a challenge")
+	}
+	a.do(http.MethodPost, "/login/2fa", "", models.TwoFactorLoginRequest{ChallengeToken: challenge.ChallengeToken, Code: code}, nil, http.StatusUnauthorized)
+
+	recoveryCode := strings.ToUpper(strings.ReplaceAll(recovery.RecoveryCodes[0], "-", ""))
+	var tokens models.AuthTokens
+	a.do(http.MethodPost, "/login/2fa", "", models.TwoFactorLoginRequest{ChallengeToken: challenge.ChallengeToken, Code: recoveryCode}, &tokens, http.StatusOK)
+	a.do(http.MethodGet, "/auth/2fa", tokens.AccessToken, nil, nil, http.StatusOK)
+	a.do(http.MethodPost, "/login/2fa", "", models.TwoFactorLoginRequest{ChallengeToken: challenge.ChallengeToken, Code: recovery.RecoveryCodes[1]}, nil, http.StatusUnauthorized)
+
+	// Recovery codes work once
+	a.do(http.MethodPost, "/login", "", models.UserRequest{Email: "alice@example.com", Password: "secret"}, &challenge, http.StatusAccepted)
+	a.do(http.MethodPost, "/login/2fa", "", models.TwoFactorLoginRequest{ChallengeToken: challenge.ChallengeToken, Code: recovery.RecoveryCodes[0]}, nil, http.StatusUnauthorized)
+
+	var status models.TwoFactor
+	a.do(http.MethodGet, "/auth/2fa", token, nil, &status, http.StatusOK)
+	if !status.Enabled() || status.RecoveryCodesLeft != 9 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	// A challenge only takes a few codes before the password is needed again
+	for i := 0; i < 4; i++ {
+		a.do(http.MethodPost, "/login/2fa", "", models.TwoFactorLoginRequest{ChallengeToken: challenge.ChallengeToken, Code: wrong}, nil, http.StatusUnauthorized)
+	}
+	a.do(http.MethodPost, "/login/2fa", "", models.TwoFactorLoginRequest{ChallengeToken: challenge.ChallengeToken, Code: recovery.RecoveryCodes[2]}, nil, http.StatusUnauthorized)
+
+	a.do(http.MethodPost, "/auth/2fa/disable", token, models.DisableTwoFactorRequest{Password: "wrong", Code: recovery.RecoveryCodes[2]}, nil, http.StatusUnauthorized)
+	a.do(http.MethodPost, "/auth/2fa/disable", token, models.DisableTwoFactorRequest{Password: "secret", Code: wrong}, nil, http.StatusUnauthorized)
+	a.do(http.MethodPost, "/auth/2fa/disable", token, models.DisableTwoFactorRequest{Password: "secret", Code: recovery.RecoveryCodes[2]}, nil, http.StatusNoContent)
+
+	status = models.TwoFactor{}
+	a.do(http.MethodGet, "/auth/2fa", token, nil, &status, http.StatusOK)
+	if status.Enabled() {
+		t.Fatal("two-factor authentication is still enabled")
+	}
+}
+
+func mustAtoi(t *testing.T, text string) int {
+	t.Helper()
+
+	value, err := strconv.Atoi(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return value
+}
+
+func TestAccessTokens(t *testing.T) {
+	a := newAPI(t)
+	alice, aliceTokens := a.register("alice")
+	_, bobTokens := a.register("bob")
+	session := aliceTokens.AccessToken
+
+	past := time.Now().Add(-time.Hour)
+	for _, request := range []models.AccessTokenRequest{
+		{Name: "", Scopes: []string{models.ScopePostsRead}},
+		{Name: "bot", Scopes: nil},
+		{Name: "bot", Scopes: []string{"posts:delete"}},
+		{Name: "bot", Scopes: []string{models.ScopePostsRead}, ExpiresAt: &past},
+	} {
+		a.do(http.MethodPost, "/auth/tokens", session, request, nil, http.StatusBadRequest)
+	}
+
+	var created, profile models.NewAccessToken
+	a.do(http.MethodPost, "/auth/tokens", session, models.AccessTokenRequest{
+		Name:   "bot",
+		Scopes: []string{models.ScopePostsWrite, models.ScopePostsRead, models.ScopePostsRead},
+	}, &created, http.StatusCreated)
+	if !strings.HasPrefix(created.Token, models.AccessTokenPrefix) || len(created.Scopes) != 2 {
+		t.Fatalf("unexpected token %+v", created)
+	}
+	token := created.Token
+
+	// The token acts as alice on the routes its scopes open, and nowhere else
+	var post models.Post
+	a.do(http.MethodPost, "/posts", token, map[string]string{"title": "Bot", "content": "Posted by a script"}, &post, http.StatusCreated)
+	if post.AuthorID != alice.ID {
+		t.Fatalf("the post was written by %d", post.AuthorID)
+	}
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), token, nil, nil, http.StatusOK)
+	a.do(http.MethodGet, "/users?user=bob", token, nil, nil, http.StatusForbidden)
+	a.do(http.MethodPost, "/auth/tokens", token, models.AccessTokenRequest{Name: "more", Scopes: []string{models.ScopeUsersRead}}, nil, http.StatusForbidden)
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/update-password", alice.ID), token, models.Password{New: "other", Current: "secret"}, nil, http.StatusForbidden)
+	a.do(http.MethodGet, "/posts", "pat_unknown", nil, nil, http.StatusUnauthorized)
+
+	var tokens page[models.AccessToken]
+	a.do(http.MethodGet, "/auth/tokens", session, nil, &tokens, http.StatusOK)
+	if len(tokens.Data) != 1 || tokens.Data[0].ID != created.ID || tokens.Data[0].LastUsedAt == nil {
+		t.Fatalf("alice has the tokens %+v", tokens.Data)
+	}
+
+	// Expired tokens and the tokens of suspended users stop working
+	if _, err := a.db.AccessTokens().Create(models.AccessToken{
+		UserID:    alice.ID,
+		Name:      "old",
+		TokenHash: security.HashToken("pat_expired"),
+		Scopes:    []string{models.ScopePostsRead},
+		ExpiresAt: &past,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	a.do(http.MethodGet, "/posts", "pat_expired", nil, nil, http.StatusUnauthorized)
+
+	if err := a.db.Users().Suspend(alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	a.do(http.MethodGet, "/posts", token, nil, nil, http.StatusUnauthorized)
+	if err := a.db.Users().Unsuspend(alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	a.do(http.MethodGet, "/posts", token, nil, nil, http.StatusOK)
+
+	// Only a session can change where the password reset links go
+	a.do(http.MethodPost, "/auth/tokens", session, models.AccessTokenRequest{Name: "profile", Scopes: []string{models.ScopeUsersWrite}}, &profile, http.StatusCreated)
+	a.do(http.MethodPut, fmt.Sprintf("/users/%d", alice.ID), profile.Token, models.User{Name: "Alice", Nick: "alice", Email: "mallory@example.com"}, nil, http.StatusForbidden)
+	a.do(http.MethodPut, fmt.Sprintf("/users/%d", alice.ID), profile.Token, models.User{Name: "Alice Liddell", Nick: "alice", Email: alice.Email}, nil, http.StatusNoContent)
+
+	a.do(http.MethodDelete, fmt.Sprintf("/auth/tokens/%d", created.ID), bobTokens.AccessToken, nil, nil, http.StatusNotFound)
+	a.do(http.MethodDelete, fmt.Sprintf("/auth/tokens/%d", created.ID), session, nil, nil, http.StatusNoContent)
+	a.do(http.MethodGet, "/posts", token, nil, nil, http.StatusUnauthorized)
+}
+
+func TestHiddenPostsRejectInteractions(t *testing.T) {
+	a := newAPI(t)
+	_, aliceTokens := a.register("alice")
+	bob, bobTokens := a.register("bob")
+	carol, carolTokens := a.register("carol")
+
+	var alicePost, carolPost models.Post
+	a.do(http.MethodPost, "/posts", aliceTokens.AccessToken, map[string]string{"title": "Hi", "content": "Public post"}, &alicePost, http.StatusCreated)
+	a.do(http.MethodPost, "/posts", carolTokens.AccessToken, map[string]string{"title": "Hi", "content": "Private post"}, &carolPost, http.StatusCreated)
+
+	a.do(http.MethodPost, fmt.Sprintf("/users/%d/block", bob.ID), aliceTokens.AccessToken, nil, nil, http.StatusNoContent)
+	a.do(http.MethodPut, fmt.Sprintf("/users/%d/privacy", carol.ID), carolTokens.AccessToken, map[string]bool{"private": true}, nil, http.StatusNoContent)
+
+	// Bob is blocked by alice and does not follow carol, both posts are hidden from him
+	for _, post := range []models.Post{alicePost, carolPost} {
+		a.do(http.MethodPost, fmt.Sprintf("/posts/%d/like", post.ID), bobTokens.AccessToken, nil, nil, http.StatusNotFound)
+		a.do(http.MethodPost, fmt.Sprintf("/posts/%d/comments", post.ID), bobTokens.AccessToken, map[string]string{"content": "Hey"}, nil, http.StatusNotFound)
+		a.do(http.MethodGet, fmt.Sprintf("/posts/%d/comments", post.ID), bobTokens.AccessToken, nil, nil, http.StatusNotFound)
+		a.do(http.MethodGet, fmt.Sprintf("/posts/%d/likes", post.ID), bobTokens.AccessToken, nil, nil, http.StatusNotFound)
+	}
+
+	for _, tokens := range []models.AuthTokens{aliceTokens, carolTokens} {
+		var notifications page[models.Notification]
+		a.do(http.MethodGet, "/notifications", tokens.AccessToken, nil, &notifications, http.StatusOK)
+		if len(notifications.Data) != 0 {
+			t.Fatalf("bob notified %+v", notifications.Data)
+		}
+	}
+
+	// The authors and the users who can see the posts still interact with them
+	a.do(http.MethodPost, fmt.Sprintf("/posts/%d/like", alicePost.ID), carolTokens.AccessToken, nil, nil, http.StatusNoContent)
+	a.do(http.MethodPost, fmt.Sprintf("/posts/%d/comments", carolPost.ID), carolTokens.AccessToken, map[string]string{"content": "Mine"}, nil, http.StatusCreated)
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d/likes", alicePost.ID), aliceTokens.AccessToken, nil, nil, http.StatusOK)
+	a.do(http.MethodGet, fmt.Sprintf("/posts/%d/comments", carolPost.ID), carolTokens.AccessToken, nil, nil, http.StatusOK)
+}
@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"api/src/models"
+	"net/http"
+)
+
+func adminRoutes(controller *controllers.Controller) []Route {
+	return []Route{
+		{
+			URI:                   "/admin/users/{userID}/suspend",
+			Method:                http.MethodPost,
+			Function:              controller.SuspendUser,
+			RequireAuthentication: true,
+			Roles:                 []string{models.RoleModerator},
+		},
+		{
+			URI:                   "/admin/users/{userID}/unsuspend",
+			Method:                http.MethodPost,
+			Function:              controller.UnsuspendUser,
+			RequireAuthentication: true,
+			Roles:                 []string{models.RoleModerator},
+		},
+		{
+			URI:                   "/admin/users/{userID}/role",
+			Method:                http.MethodPut,
+			Function:              controller.UpdateUserRole,
+			RequireAuthentication: true,
+			Roles:                 []string{models.RoleAdmin},
+		},
+		{
+			URI:                   "/admin/users/{userID}/login-attempts",
+			Method:                http.MethodGet,
+			Function:              controller.GetLoginAttempts,
+			RequireAuthentication: true,
+			Roles:                 []string{models.RoleModerator},
+		},
+		{
+			URI:                   "/admin/posts/{postId}",
+			Method:                http.MethodDelete,
+			Function:              controller.AdminDeletePost,
+			RequireAuthentication: true,
+			Roles:                 []string{models.RoleModerator},
+		},
+	}
+}
@@ -0,0 +1,98 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"api/src/ratelimit"
+	"net/http"
+	"time"
+)
+
+func authRoutes(controller *controllers.Controller) []Route {
+	return []Route{
+		{
+			URI:                   "/auth/refresh",
+			Method:                http.MethodPost,
+			Function:              controller.RefreshToken,
+			RequireAuthentication: false,
+			RateLimit:             ratelimit.Limit{Requests: 30, Window: time.Minute},
+		},
+		{
+			URI:                   "/auth/verify-email",
+			Method:                http.MethodPost,
+			Function:              controller.VerifyEmail,
+			RequireAuthentication: false,
+			RateLimit:             ratelimit.Limit{Requests: 10, Window: time.Minute},
+		},
+		{
+			URI:                   "/auth/verify-email/resend",
+			Method:                http.MethodPost,
+			Function:              controller.ResendEmailVerification,
+			RequireAuthentication: true,
+			RateLimit:             ratelimit.Limit{Requests: 3, Window: time.Hour},
+		},
+		{
+			URI:                   "/auth/forgot-password",
+			Method:                http.MethodPost,
+			Function:              controller.ForgotPassword,
+			RequireAuthentication: false,
+			RateLimit:             ratelimit.Limit{Requests: 5, Window: time.Hour},
+		},
+		{
+			URI:                   "/auth/reset-password",
+			Method:                http.MethodPost,
+			Function:              controller.ResetPassword,
+			RequireAuthentication: false,
+			RateLimit:             ratelimit.Limit{Requests: 10, Window: time.Minute},
+		},
+		{
+			URI:                   "/auth/2fa",
+			Method:                http.MethodGet,
+			Function:              controller.GetTwoFactor,
+			RequireAuthentication: true,
+		},
+		{
+			URI:                   "/auth/2fa/setup",
+			Method:                http.MethodPost,
+			Function:              controller.SetupTwoFactor,
+			RequireAuthentication: true,
+		},
+		{
+			URI:                   "/auth/2fa/enable",
+			Method:                http.MethodPost,
+			Function:              controller.EnableTwoFactor,
+			RequireAuthentication: true,
+			RateLimit:             ratelimit.Limit{Requests: 10, Window: time.Minute},
+		},
+		{
+			URI:                   "/auth/2fa/disable",
+			Method:                http.MethodPost,
+			Function:              controller.DisableTwoFactor,
+			RequireAuthentication: true,
+			RateLimit:             ratelimit.Limit{Requests: 5, Window: time.Minute},
+		},
+		{
+			URI:                   "/auth/tokens",
+			Method:                http.MethodPost,
+			Function:              controller.CreateAccessToken,
+			RequireAuthentication: true,
+		},
+		{
+			URI:                   "/auth/tokens",
+			Method:                http.MethodGet,
+			Function:              controller.GetAccessTokens,
+			RequireAuthentication: true,
+		},
+		{
+			URI:                   "/auth/tokens/{tokenId}",
+			Method:                http.MethodDelete,
+			Function:              controller.DeleteAccessToken,
+			RequireAuthentication: true,
+		},
+		{
+			URI:                   "/auth/logout",
+			Method:                http.MethodPost,
+			Function:              controller.Logout,
+			RequireAuthentication: true,
+		},
+	}
+}
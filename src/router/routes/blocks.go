@@ -0,0 +1,54 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"api/src/models"
+	"net/http"
+)
+
+func blocksRoutes(controller *controllers.Controller) []Route {
+	return []Route{
+		{
+			URI:                   "/users/{userID}/block",
+			Method:                http.MethodPost,
+			Function:              controller.BlockUser,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeUsersWrite},
+		},
+		{
+			URI:                   "/users/{userID}/block",
+			Method:                http.MethodDelete,
+			Function:              controller.UnblockUser,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeUsersWrite},
+		},
+		{
+			URI:                   "/users/{userID}/mute",
+			Method:                http.MethodPost,
+			Function:              controller.MuteUser,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeUsersWrite},
+		},
+		{
+			URI:                   "/users/{userID}/mute",
+			Method:                http.MethodDelete,
+			Function:              controller.UnmuteUser,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeUsersWrite},
+		},
+		{
+			URI:                   "/blocks",
+			Method:                http.MethodGet,
+			Function:              controller.GetBlocks,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeUsersRead},
+		},
+		{
+			URI:                   "/mutes",
+			Method:                http.MethodGet,
+			Function:              controller.GetMutes,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeUsersRead},
+		},
+	}
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+func eventsRoutes(controller *controllers.Controller) []Route {
+	return []Route{
+		{
+			URI:                   "/events",
+			Method:                http.MethodGet,
+			Function:              controller.StreamEvents,
+			RequireAuthentication: true,
+		},
+	}
+}
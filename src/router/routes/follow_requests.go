@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"api/src/models"
+	"net/http"
+)
+
+func followRequestsRoutes(controller *controllers.Controller) []Route {
+	return []Route{
+		{
+			URI:                   "/follow-requests",
+			Method:                http.MethodGet,
+			Function:              controller.GetFollowRequests,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeFollowsRead},
+		},
+		{
+			URI:                   "/follow-requests/{requestId}/approve",
+			Method:                http.MethodPost,
+			Function:              controller.ApproveFollowRequest,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeFollowsWrite},
+		},
+		{
+			URI:                   "/follow-requests/{requestId}/reject",
+			Method:                http.MethodPost,
+			Function:              controller.RejectFollowRequest,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeFollowsWrite},
+		},
+		{
+			URI:                   "/users/{userID}/privacy",
+			Method:                http.MethodPut,
+			Function:              controller.UpdatePrivacy,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeUsersWrite},
+		},
+	}
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"api/src/models"
+	"net/http"
+)
+
+func hashtagsRoutes(controller *controllers.Controller) []Route {
+	return []Route{
+		{
+			URI:                   "/hashtags/trending",
+			Method:                http.MethodGet,
+			Function:              controller.GetTrendingHashtags,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopePostsRead},
+		},
+		{
+			URI:                   "/hashtags/{tag}/posts",
+			Method:                http.MethodGet,
+			Function:              controller.GetHashtagPosts,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopePostsRead},
+		},
+	}
+}
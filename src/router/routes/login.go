@@ -2,12 +2,28 @@ package routes
 
 import (
 	"api/src/controllers"
+	"api/src/ratelimit"
 	"net/http"
+	"time"
 )
 
-var loginRoute = Route{
-	URI:                   "/login",
-	Method:                http.MethodPost,
-	Function:              controllers.Login,
-	RequireAuthentication: false,
+// loginRoutes are throttled harder than the others to slow down credential stuffing
+// and code guessing, every attempt counts whether it succeeds or not
+func loginRoutes(controller *controllers.Controller) []Route {
+	return []Route{
+		{
+			URI:                   "/login",
+			Method:                http.MethodPost,
+			Function:              controller.Login,
+			RequireAuthentication: false,
+			RateLimit:             ratelimit.Limit{Requests: 10, Window: time.Minute},
+		},
+		{
+			URI:                   "/login/2fa",
+			Method:                http.MethodPost,
+			Function:              controller.LoginTwoFactor,
+			RequireAuthentication: false,
+			RateLimit:             ratelimit.Limit{Requests: 10, Window: time.Minute},
+		},
+	}
 }
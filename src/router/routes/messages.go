@@ -0,0 +1,62 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"api/src/models"
+	"net/http"
+)
+
+func messagesRoutes(controller *controllers.Controller) []Route {
+	return []Route{
+		{
+			URI:                   "/users/{userID}/messages",
+			Method:                http.MethodPost,
+			Function:              controller.SendMessage,
+			RequireAuthentication: true,
+			RequireVerifiedEmail:  true,
+			Scopes:                []string{models.ScopeMessagesWrite},
+		},
+		{
+			URI:                   "/users/{userID}/message-settings",
+			Method:                http.MethodPut,
+			Function:              controller.UpdateMessageSettings,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeUsersWrite},
+		},
+		{
+			URI:                   "/conversations",
+			Method:                http.MethodGet,
+			Function:              controller.GetConversations,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeMessagesRead},
+		},
+		{
+			URI:                   "/conversations/{conversationId}/messages",
+			Method:                http.MethodGet,
+			Function:              controller.GetConversationMessages,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeMessagesRead},
+		},
+		{
+			URI:                   "/conversations/{conversationId}/read",
+			Method:                http.MethodPost,
+			Function:              controller.MarkConversationRead,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeMessagesWrite},
+		},
+		{
+			URI:                   "/conversations/{conversationId}/mute",
+			Method:                http.MethodPost,
+			Function:              controller.MuteConversation,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeMessagesWrite},
+		},
+		{
+			URI:                   "/conversations/{conversationId}/unmute",
+			Method:                http.MethodPost,
+			Function:              controller.UnmuteConversation,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeMessagesWrite},
+		},
+	}
+}
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"api/src/models"
+	"net/http"
+)
+
+func notificationsRoutes(controller *controllers.Controller) []Route {
+	return []Route{
+		{
+			URI:                   "/notifications",
+			Method:                http.MethodGet,
+			Function:              controller.GetNotifications,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeNotificationsRead},
+		},
+		{
+			URI:                   "/notifications/read",
+			Method:                http.MethodPost,
+			Function:              controller.MarkNotificationsRead,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeNotificationsWrite},
+		},
+		{
+			URI:                   "/notifications/unread-count",
+			Method:                http.MethodGet,
+			Function:              controller.GetUnreadNotificationsCount,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeNotificationsRead},
+		},
+	}
+}
@@ -2,56 +2,128 @@ package routes
 
 import (
 	"api/src/controllers"
+	"api/src/models"
 	"net/http"
 )
 
-var postsRoutes = []Route{
-	{
-		URI:                   "/posts",
-		Method:                http.MethodPost,
-		Function:              controllers.CreatePost,
-		RequireAuthentication: true,
-	},
-	{
-		URI:                   "/posts",
-		Method:                http.MethodGet,
-		Function:              controllers.GetPosts,
-		RequireAuthentication: true,
-	},
-	{
-		URI:                   "/posts/{postId}",
-		Method:                http.MethodGet,
-		Function:              controllers.GetPost,
-		RequireAuthentication: true,
-	},
-	{
-		URI:                   "/posts/{postId}",
-		Method:                http.MethodPut,
-		Function:              controllers.UpdatePost,
-		RequireAuthentication: true,
-	},
-	{
-		URI:                   "/posts/{postId}",
-		Method:                http.MethodDelete,
-		Function:              controllers.DeletePost,
-		RequireAuthentication: true,
-	},
-	{
-		URI:                   "/users/{userId}/posts",
-		Method:                http.MethodGet,
-		Function:              controllers.GetPostsPerUser,
-		RequireAuthentication: true,
-	},
-	{
-		URI:                   "/posts/{postId}/like",
-		Method:                http.MethodPost,
-		Function:              controllers.LikePost,
-		RequireAuthentication: true,
-	},
-	{
-		URI:                   "/posts/{postId}/dislike",
-		Method:                http.MethodPost,
-		Function:              controllers.DislikePost,
-		RequireAuthentication: true,
-	},
+func postsRoutes(controller *controllers.Controller) []Route {
+	return []Route{
+		{
+			URI:                   "/posts",
+			Method:                http.MethodPost,
+			Function:              controller.CreatePost,
+			RequireAuthentication: true,
+			RequireVerifiedEmail:  true,
+			Scopes:                []string{models.ScopePostsWrite},
+		},
+		{
+			URI:                   "/posts",
+			Method:                http.MethodGet,
+			Function:              controller.GetPosts,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopePostsRead},
+		},
+		{
+			URI:                   "/posts/{postId}",
+			Method:                http.MethodGet,
+			Function:              controller.GetPost,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopePostsRead},
+		},
+		{
+			URI:                   "/posts/{postId}",
+			Method:                http.MethodPut,
+			Function:              controller.UpdatePost,
+			RequireAuthentication: true,
+			RequireVerifiedEmail:  true,
+			Scopes:                []string{models.ScopePostsWrite},
+		},
+		{
+			URI:                   "/posts/{postId}",
+			Method:                http.MethodDelete,
+			Function:              controller.DeletePost,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopePostsWrite},
+		},
+		{
+			URI:                   "/users/{userId}/posts",
+			Method:                http.MethodGet,
+			Function:              controller.GetPostsPerUser,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopePostsRead},
+		},
+		{
+			URI:                   "/users/{userID}/mentions",
+			Method:                http.MethodGet,
+			Function:              controller.GetUserMentions,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopePostsRead},
+		},
+		{
+			URI:                   "/posts/{postId}/like",
+			Method:                http.MethodPost,
+			Function:              controller.LikePost,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopePostsWrite},
+		},
+		{
+			URI:                   "/posts/{postId}/dislike",
+			Method:                http.MethodPost,
+			Function:              controller.DislikePost,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopePostsWrite},
+		},
+		{
+			URI:                   "/posts/{postId}/likes",
+			Method:                http.MethodGet,
+			Function:              controller.GetPostLikes,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopePostsRead},
+		},
+		{
+			URI:                   "/posts/{postId}/comments",
+			Method:                http.MethodPost,
+			Function:              controller.CreateComment,
+			RequireAuthentication: true,
+			RequireVerifiedEmail:  true,
+			Scopes:                []string{models.ScopePostsWrite},
+		},
+		{
+			URI:                   "/posts/{postId}/comments",
+			Method:                http.MethodGet,
+			Function:              controller.GetComments,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopePostsRead},
+		},
+		{
+			URI:                   "/posts/{postId}/comments/{commentId}",
+			Method:                http.MethodPut,
+			Function:              controller.UpdateComment,
+			RequireAuthentication: true,
+			RequireVerifiedEmail:  true,
+			Scopes:                []string{models.ScopePostsWrite},
+		},
+		{
+			URI:                   "/posts/{postId}/comments/{commentId}",
+			Method:                http.MethodDelete,
+			Function:              controller.DeleteComment,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopePostsWrite},
+		},
+		{
+			URI:                   "/posts/{postId}/attachments",
+			Method:                http.MethodPost,
+			Function:              controller.CreateAttachment,
+			RequireAuthentication: true,
+			RequireVerifiedEmail:  true,
+			Scopes:                []string{models.ScopePostsWrite},
+		},
+		{
+			URI:                   "/posts/{postId}/attachments/{attachmentId}",
+			Method:                http.MethodDelete,
+			Function:              controller.DeleteAttachment,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopePostsWrite},
+		},
+	}
 }
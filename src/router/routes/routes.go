@@ -1,7 +1,9 @@
 package routes
 
 import (
+	"api/src/controllers"
 	"api/src/middlewares"
+	"api/src/ratelimit"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -13,23 +15,53 @@ type Route struct {
 	Method                string
 	Function              func(http.ResponseWriter, *http.Request)
 	RequireAuthentication bool
+	// Roles restricts the route to users with one of the roles, it implies authentication
+	Roles []string
+	// RequireVerifiedEmail restricts the route to users who confirmed their email address, it implies authentication
+	RequireVerifiedEmail bool
+	// Scopes are the ones a personal access token needs to use the route, the routes without scopes only accept sessions
+	Scopes []string
+	// RateLimit throttles each user, or each IP address on the anonymous routes, ratelimit.Default when unset
+	RateLimit ratelimit.Limit
 }
 
 // Configure puts the routes inside the router
-func Configure(r *mux.Router) *mux.Router {
-	routes := userRoutes
-	routes = append(routes, loginRoute)
-	routes = append(routes, postsRoutes...)
+func Configure(r *mux.Router, controller *controllers.Controller) *mux.Router {
+	routes := userRoutes(controller)
+	routes = append(routes, loginRoutes(controller)...)
+	routes = append(routes, authRoutes(controller)...)
+	routes = append(routes, postsRoutes(controller)...)
+	routes = append(routes, adminRoutes(controller)...)
+	routes = append(routes, notificationsRoutes(controller)...)
+	routes = append(routes, eventsRoutes(controller)...)
+	routes = append(routes, messagesRoutes(controller)...)
+	routes = append(routes, searchRoutes(controller)...)
+	routes = append(routes, hashtagsRoutes(controller)...)
+	routes = append(routes, followRequestsRoutes(controller)...)
+	routes = append(routes, blocksRoutes(controller)...)
 
 	for _, route := range routes {
+		handler := route.Function
 
-		if route.RequireAuthentication {
-			r.HandleFunc(route.URI,
-				middlewares.Logger(middlewares.Authenticate(route.Function)),
-			).Methods(route.Method)
-		} else {
-			r.HandleFunc(route.URI, middlewares.Logger(route.Function)).Methods(route.Method)
+		if len(route.Roles) > 0 {
+			handler = middlewares.Authorize(handler, route.Roles)
 		}
+
+		if route.RequireVerifiedEmail {
+			handler = middlewares.RequireVerifiedEmail(handler, controller.Users)
+		}
+
+		limit := route.RateLimit
+		if limit.IsZero() {
+			limit = ratelimit.Default
+		}
+		handler = middlewares.RateLimit(handler, controller.RateLimits, route.Method+" "+route.URI, limit)
+
+		if route.RequireAuthentication || len(route.Roles) > 0 || route.RequireVerifiedEmail {
+			handler = middlewares.Authenticate(handler, controller.Sessions, controller.AccessTokens, route.Scopes)
+		}
+
+		r.HandleFunc(route.URI, middlewares.Logger(handler)).Methods(route.Method)
 	}
 
 	return r
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"api/src/models"
+	"net/http"
+)
+
+func searchRoutes(controller *controllers.Controller) []Route {
+	return []Route{
+		{
+			URI:                   "/search/posts",
+			Method:                http.MethodGet,
+			Function:              controller.SearchPosts,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopePostsRead},
+		},
+	}
+}
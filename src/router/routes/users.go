@@ -2,68 +2,81 @@ package routes
 
 import (
 	"api/src/controllers"
+	"api/src/models"
+	"api/src/ratelimit"
 	"net/http"
+	"time"
 )
 
-var userRoutes = []Route{
-	{
-		URI:                   "/users",
-		Method:                http.MethodPost,
-		Function:              controllers.CreateUser,
-		RequireAuthentication: false,
-	},
-	{
-		URI:                   "/users",
-		Method:                http.MethodGet,
-		Function:              controllers.GetUsers,
-		RequireAuthentication: true,
-	},
-	{
-		URI:                   "/users/{userID}",
-		Method:                http.MethodGet,
-		Function:              controllers.GetUser,
-		RequireAuthentication: true,
-	},
-	{
-		URI:                   "/users/{userID}",
-		Method:                http.MethodPut,
-		Function:              controllers.UpdateUser,
-		RequireAuthentication: true,
-	},
-	{
-		URI:                   "/users/{userID}",
-		Method:                http.MethodDelete,
-		Function:              controllers.DeleteUser,
-		RequireAuthentication: true,
-	},
-	{
-		URI:                   "/users/{userID}/follow",
-		Method:                http.MethodPost,
-		Function:              controllers.FollowUser,
-		RequireAuthentication: true,
-	},
-	{
-		URI:                   "/users/{userID}/unfollow",
-		Method:                http.MethodPost,
-		Function:              controllers.UnfollowUser,
-		RequireAuthentication: true,
-	},
-	{
-		URI:                   "/users/{userID}/followers",
-		Method:                http.MethodGet,
-		Function:              controllers.SearchFollowers,
-		RequireAuthentication: true,
-	},
-	{
-		URI:                   "/users/{userID}/following",
-		Method:                http.MethodGet,
-		Function:              controllers.SearchFollowing,
-		RequireAuthentication: true,
-	},
-	{
-		URI:                   "/users/{userID}/update-password",
-		Method:                http.MethodPost,
-		Function:              controllers.UpdatePassword,
-		RequireAuthentication: true,
-	},
+func userRoutes(controller *controllers.Controller) []Route {
+	return []Route{
+		{
+			URI:                   "/users",
+			Method:                http.MethodPost,
+			Function:              controller.CreateUser,
+			RequireAuthentication: false,
+			RateLimit:             ratelimit.Limit{Requests: 10, Window: time.Hour},
+		},
+		{
+			URI:                   "/users",
+			Method:                http.MethodGet,
+			Function:              controller.GetUsers,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeUsersRead},
+		},
+		{
+			URI:                   "/users/{userID}",
+			Method:                http.MethodGet,
+			Function:              controller.GetUser,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeUsersRead},
+		},
+		{
+			URI:                   "/users/{userID}",
+			Method:                http.MethodPut,
+			Function:              controller.UpdateUser,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeUsersWrite},
+		},
+		{
+			URI:                   "/users/{userID}",
+			Method:                http.MethodDelete,
+			Function:              controller.DeleteUser,
+			RequireAuthentication: true,
+		},
+		{
+			URI:                   "/users/{userID}/follow",
+			Method:                http.MethodPost,
+			Function:              controller.FollowUser,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeFollowsWrite},
+		},
+		{
+			URI:                   "/users/{userID}/unfollow",
+			Method:                http.MethodPost,
+			Function:              controller.UnfollowUser,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeFollowsWrite},
+		},
+		{
+			URI:                   "/users/{userID}/followers",
+			Method:                http.MethodGet,
+			Function:              controller.SearchFollowers,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeFollowsRead},
+		},
+		{
+			URI:                   "/users/{userID}/following",
+			Method:                http.MethodGet,
+			Function:              controller.SearchFollowing,
+			RequireAuthentication: true,
+			Scopes:                []string{models.ScopeFollowsRead},
+		},
+		{
+			URI:                   "/users/{userID}/update-password",
+			Method:                http.MethodPost,
+			Function:              controller.UpdatePassword,
+			RequireAuthentication: true,
+		},
+	}
 }
@@ -0,0 +1,291 @@
+// Package search parses the full-text queries users type and builds the
+// highlighted snippets of the results, the repositories do the matching
+package search
+
+import (
+	"api/src/models"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MinTermLength is the shortest word MySQL indexes, shorter words are left out of the query
+	MinTermLength = 3
+	// MaxQueryLength bounds the query, in characters
+	MaxQueryLength = 200
+	// SnippetLength is roughly how many characters of the content a snippet shows
+	SnippetLength = 160
+)
+
+// Query represents a parsed search: every term and phrase must appear in the
+// post and none of the excluded words may
+type Query struct {
+	// Terms match the words that start with them, so photo also finds photos
+	Terms []string
+	// Phrases match the exact sequence of words
+	Phrases [][]string
+	// Excluded are the words written with a leading minus
+	Excluded []string
+
+	// AuthorID restricts the search to the posts of a user when it is not zero
+	AuthorID uint64
+	// Since and Until restrict the search to the posts created in [Since, Until), zero values are open ends
+	Since time.Time
+	Until time.Time
+}
+
+// Token represents a word of a text, offsets count characters
+type Token struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// Parse reads the query typed by the user: plain words, "quoted phrases" and -excluded words
+func Parse(text string) (Query, error) {
+	var query Query
+
+	if utf8.RuneCountInString(text) > MaxQueryLength {
+		return Query{}, invalidQuery("The search cannot be longer than 200 characters")
+	}
+
+	seen := map[string]bool{}
+	for rest := strings.TrimSpace(text); rest != ""; rest = strings.TrimSpace(rest) {
+		if rest[0] == '"' {
+			phrase := rest[1:]
+			rest = ""
+			if end := strings.IndexByte(phrase, '"'); end >= 0 {
+				phrase, rest = phrase[:end], phrase[end+1:]
+			}
+
+			words := words(Tokenize(phrase))
+			switch {
+			case len(words) > 1:
+				query.Phrases = append(query.Phrases, words)
+			case len(words) == 1 && !seen[words[0]] && utf8.RuneCountInString(words[0]) >= MinTermLength:
+				seen[words[0]] = true
+				query.Terms = append(query.Terms, words[0])
+			}
+			continue
+		}
+
+		chunk := rest
+		rest = ""
+		if end := strings.IndexFunc(chunk, unicode.IsSpace); end >= 0 {
+			chunk, rest = chunk[:end], chunk[end:]
+		}
+
+		if strings.HasPrefix(chunk, "-") {
+			query.Excluded = append(query.Excluded, words(Tokenize(chunk[1:]))...)
+			continue
+		}
+
+		for _, word := range words(Tokenize(chunk)) {
+			if !seen[word] && utf8.RuneCountInString(word) >= MinTermLength {
+				seen[word] = true
+				query.Terms = append(query.Terms, word)
+			}
+		}
+	}
+
+	if len(query.Terms) == 0 && len(query.Phrases) == 0 {
+		return Query{}, invalidQuery("The search must have a word of at least 3 characters")
+	}
+
+	return query, nil
+}
+
+// Boolean returns the query in the syntax of MySQL full-text searches in boolean mode
+func (query Query) Boolean() string {
+	var parts []string
+	for _, term := range query.Terms {
+		parts = append(parts, "+"+term+"*")
+	}
+
+	for _, phrase := range query.Phrases {
+		parts = append(parts, `+"`+strings.Join(phrase, " ")+`"`)
+	}
+
+	for _, word := range query.Excluded {
+		parts = append(parts, "-"+word)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Tokenize splits the text into lowercase words, a word is a run of letters, digits and underscores
+func Tokenize(text string) []Token {
+	var tokens []Token
+	var word strings.Builder
+	start, position := -1, 0
+
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			if start < 0 {
+				start = position
+			}
+			word.WriteRune(unicode.ToLower(r))
+		} else if start >= 0 {
+			tokens = append(tokens, Token{Text: word.String(), Start: start, End: position})
+			word.Reset()
+			start = -1
+		}
+		position++
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, Token{Text: word.String(), Start: start, End: position})
+	}
+
+	return tokens
+}
+
+// Occurrences counts where the words appear in sequence in the tokens, the
+// last word only needs to start the token when prefix is set
+func Occurrences(tokens []Token, words []string, prefix bool) int {
+	count := 0
+	for i := range tokens {
+		if matchesAt(tokens, i, words, prefix) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Highlight returns the whole text with the matches of the query marked
+func (query Query) Highlight(text string) models.Snippet {
+	return models.Snippet{Text: text, Highlights: query.highlights(Tokenize(text))}
+}
+
+// Snippet returns the fragment of the text, about length characters long,
+// that has the most matches of the query, cut at word boundaries
+func (query Query) Snippet(text string, length int) models.Snippet {
+	runes := []rune(text)
+	tokens := Tokenize(text)
+	highlights := query.highlights(tokens)
+
+	if len(runes) <= length {
+		return models.Snippet{Text: text, Highlights: highlights}
+	}
+
+	// Start a little before the match that begins the window with the most matches
+	start, best := 0, 0
+	for i, first := range highlights {
+		windowStart := max(0, first.Start-length/4)
+		count := 0
+		for _, highlight := range highlights[i:] {
+			if highlight.End > windowStart+length {
+				break
+			}
+			count++
+		}
+
+		if count > best {
+			start, best = windowStart, count
+		}
+	}
+
+	// Cut at the first word that starts in the window and the last one that ends in it
+	from, to := start, 0
+	for _, token := range tokens {
+		if start > 0 && token.Start >= start {
+			from = token.Start
+			break
+		}
+	}
+
+	for _, token := range tokens {
+		if token.Start >= from && token.End <= from+length {
+			to = token.End
+		}
+	}
+	if to <= from {
+		to = min(len(runes), from+length)
+	}
+
+	snippet := models.Snippet{Text: string(runes[from:to]), Highlights: []models.Highlight{}}
+	shift := -from
+	if from > 0 {
+		snippet.Text = "…" + snippet.Text
+		shift++
+	}
+	if to < len(runes) {
+		snippet.Text += "…"
+	}
+
+	for _, highlight := range highlights {
+		if highlight.Start >= from && highlight.End <= to {
+			snippet.Highlights = append(snippet.Highlights, models.Highlight{
+				Start: highlight.Start + shift,
+				End:   highlight.End + shift,
+			})
+		}
+	}
+
+	return snippet
+}
+
+// highlights returns the matches of the terms and phrases in the tokens, in order and without overlaps
+func (query Query) highlights(tokens []Token) []models.Highlight {
+	highlights := []models.Highlight{}
+
+	for i := 0; i < len(tokens); {
+		length := 0
+		for _, phrase := range query.Phrases {
+			if len(phrase) > length && matchesAt(tokens, i, phrase, false) {
+				length = len(phrase)
+			}
+		}
+
+		for _, term := range query.Terms {
+			if length == 0 && matchesAt(tokens, i, []string{term}, true) {
+				length = 1
+			}
+		}
+
+		if length == 0 {
+			i++
+			continue
+		}
+
+		highlights = append(highlights, models.Highlight{Start: tokens[i].Start, End: tokens[i+length-1].End})
+		i += length
+	}
+
+	return highlights
+}
+
+func matchesAt(tokens []Token, i int, words []string, prefix bool) bool {
+	if len(words) == 0 || i+len(words) > len(tokens) {
+		return false
+	}
+
+	for j, word := range words {
+		text := tokens[i+j].Text
+		if prefix && j == len(words)-1 {
+			if !strings.HasPrefix(text, word) {
+				return false
+			}
+		} else if text != word {
+			return false
+		}
+	}
+
+	return true
+}
+
+func words(tokens []Token) []string {
+	words := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		words = append(words, token.Text)
+	}
+
+	return words
+}
+
+func invalidQuery(message string) error {
+	return &models.FieldError{Field: "q", Message: message}
+}
@@ -0,0 +1,76 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParse(t *testing.T) {
+	query, err := Parse(`Go  "Hello,  World" photos -draft a photos "x"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Query{
+		Terms:    []string{"photos"},
+		Phrases:  [][]string{{"hello", "world"}},
+		Excluded: []string{"draft"},
+	}
+	if !reflect.DeepEqual(query, want) {
+		t.Fatalf("parsed %+v, want %+v", query, want)
+	}
+
+	if boolean := query.Boolean(); boolean != `+photos* +"hello world" -draft` {
+		t.Fatalf("boolean query is %q", boolean)
+	}
+
+	for _, text := range []string{"", "go is ok", "-excluded", strings.Repeat("a", MaxQueryLength+1)} {
+		if _, err := Parse(text); err == nil {
+			t.Fatalf("%q was accepted", text)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	query, _ := Parse(`photo "new york"`)
+	snippet := query.Highlight("Photos from New York, São Paulo and new places")
+
+	var marked []string
+	runes := []rune(snippet.Text)
+	for _, highlight := range snippet.Highlights {
+		marked = append(marked, string(runes[highlight.Start:highlight.End]))
+	}
+
+	if want := []string{"Photos", "New York"}; !reflect.DeepEqual(marked, want) {
+		t.Fatalf("highlighted %q, want %q", marked, want)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	query, _ := Parse("needle")
+	text := strings.Repeat("hay ", 100) + "the needle is here " + strings.Repeat("straw ", 100)
+	snippet := query.Snippet(text, SnippetLength)
+
+	if !strings.HasPrefix(snippet.Text, "…hay") || !strings.HasSuffix(snippet.Text, "…") {
+		t.Fatalf("snippet %q is not cut at words", snippet.Text)
+	}
+
+	if length := utf8.RuneCountInString(snippet.Text); length > SnippetLength+2 {
+		t.Fatalf("snippet has %d characters", length)
+	}
+
+	if len(snippet.Highlights) != 1 {
+		t.Fatalf("snippet has highlights %+v", snippet.Highlights)
+	}
+
+	highlight := snippet.Highlights[0]
+	if marked := string([]rune(snippet.Text)[highlight.Start:highlight.End]); marked != "needle" {
+		t.Fatalf("highlighted %q", marked)
+	}
+
+	if short := query.Snippet("a needle", SnippetLength); short.Text != "a needle" || len(short.Highlights) != 1 {
+		t.Fatalf("short snippet is %+v", short)
+	}
+}
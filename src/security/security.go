@@ -1,6 +1,16 @@
 package security
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 func Hash(password string) ([]byte, error) {
 	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -9,3 +19,43 @@ func Hash(password string) ([]byte, error) {
 func VerifyPassword(hashedPassword, stringPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(stringPassword))
 }
+
+// RandomToken returns a URL safe token built from size random bytes
+func RandomToken(size int) (string, error) {
+	buffer := make([]byte, size)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
+}
+
+// HashToken returns the SHA-256 of a random token, it is what gets stored in the database
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ErrInvalidSignature is returned when a signed token was not signed with the key or was changed
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Sign appends to the value its HMAC-SHA256 made with the key, the value must not contain dots
+func Sign(value string, key []byte) string {
+	return value + "." + signature(value, key)
+}
+
+// Unsign checks the signature of a token made by Sign and returns its value
+func Unsign(token string, key []byte) (string, error) {
+	value, sent, found := strings.Cut(token, ".")
+	if !found || !hmac.Equal([]byte(sent), []byte(signature(value, key))) {
+		return "", ErrInvalidSignature
+	}
+
+	return value, nil
+}
+
+func signature(value string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
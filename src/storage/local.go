@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Local keeps the files in a directory of the server and serves them itself
+type Local struct {
+	dir     string
+	baseURL string
+}
+
+var _ Storage = (*Local)(nil)
+
+// NewLocal creates a backend that writes under dir, files are downloaded from baseURL
+func NewLocal(dir, baseURL string) *Local {
+	return &Local{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (local *Local) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	filename, err := local.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see half of an upload
+	temporary := filename + ".tmp"
+	if err = os.WriteFile(temporary, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(temporary, filename)
+}
+
+func (local *Local) Delete(ctx context.Context, key string) error {
+	filename, err := local.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(filename); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (local *Local) URL(key string) string {
+	return local.baseURL + "/" + key
+}
+
+// ServeHTTP serves the stored files, the request path must already be stripped of the base URL
+func (local *Local) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	filename, err := local.path(strings.TrimPrefix(r.URL.Path, "/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if info, err := os.Stat(filename); err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeFile(w, r, filename)
+}
+
+// path maps the key to a file inside the directory, keys cannot escape it
+func (local *Local) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned == "/" || cleaned != "/"+key {
+		return "", errors.New("invalid storage key")
+	}
+
+	return filepath.Join(local.dir, filepath.FromSlash(cleaned)), nil
+}
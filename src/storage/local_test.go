@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	local := NewLocal(t.TempDir(), "/media/")
+	ctx := context.Background()
+
+	if err := local.Put(ctx, "posts/1/image.png", []byte("image"), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := local.URL("posts/1/image.png"); got != "/media/posts/1/image.png" {
+		t.Fatalf("got URL %s", got)
+	}
+
+	server := httptest.NewServer(http.StripPrefix("/media", local))
+	defer server.Close()
+
+	for path, wantStatus := range map[string]int{
+		"/media/posts/1/image.png": http.StatusOK,
+		"/media/posts/1/":          http.StatusNotFound,
+		"/media/posts/2/image.png": http.StatusNotFound,
+	} {
+		response, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+
+		if response.StatusCode != wantStatus {
+			t.Fatalf("GET %s: got status %d, want %d", path, response.StatusCode, wantStatus)
+		}
+
+		if wantStatus == http.StatusOK && string(body) != "image" {
+			t.Fatalf("GET %s: got %q", path, body)
+		}
+	}
+
+	if err := local.Delete(ctx, "posts/1/image.png"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := local.Delete(ctx, "posts/1/image.png"); err != nil {
+		t.Fatalf("deleting a missing file: %v", err)
+	}
+
+	for _, key := range []string{"", "../escape", "posts/../../escape", "/absolute"} {
+		if err := local.Put(ctx, key, nil, "image/png"); err == nil {
+			t.Fatalf("the key %q was accepted", key)
+		}
+	}
+}
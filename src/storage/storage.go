@@ -0,0 +1,44 @@
+// Package storage keeps the files uploaded by users, like the images attached to posts
+package storage
+
+import (
+	"api/src/config"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Storage is implemented by the backends that keep uploaded files, keys are
+// slash separated paths such as posts/1/abc.jpg
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Delete removes the file, deleting a missing file is not an error
+	Delete(ctx context.Context, key string) error
+	// URL returns the address clients use to download the file
+	URL(key string) string
+}
+
+// FromConfig creates the backend chosen by the STORAGE_BACKEND setting
+func FromConfig() (Storage, error) {
+	switch config.StorageBackend {
+	case "local":
+		return NewLocal(config.MediaDir, config.MediaURL), nil
+
+	case "s3":
+		if config.S3Endpoint == "" || config.S3Bucket == "" {
+			return nil, errors.New("S3_ENDPOINT and S3_BUCKET are required by the s3 storage backend")
+		}
+
+		return NewS3(S3Config{
+			Endpoint:        config.S3Endpoint,
+			Region:          config.S3Region,
+			Bucket:          config.S3Bucket,
+			AccessKeyID:     config.S3AccessKeyID,
+			SecretAccessKey: config.S3SecretAccessKey,
+			PublicURL:       config.S3PublicURL,
+		}), nil
+
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", config.StorageBackend)
+	}
+}
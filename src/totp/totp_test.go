@@ -0,0 +1,71 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the key of the SHA-1 test vectors of RFC 6238, "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// The RFC lists eight digit codes, the six digit ones are their last digits
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for seconds, want := range vectors {
+		code, err := Code(rfcSecret, Step(time.Unix(seconds, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != want {
+			t.Fatalf("code at %d is %s, want %s", seconds, code, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	previous, _ := Code(rfcSecret, current-1)
+	if step, ok := Validate(rfcSecret, previous, now); !ok || step != current-1 {
+		t.Fatalf("the code of the previous period matched %d, %v", step, ok)
+	}
+
+	tooOld, _ := Code(rfcSecret, current-2)
+	if _, ok := Validate(rfcSecret, tooOld, now); ok {
+		t.Fatal("a code from two periods ago was accepted")
+	}
+
+	for _, code := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, ok := Validate(rfcSecret, code, now); ok {
+			t.Fatalf("%q was accepted", code)
+		}
+	}
+}
+
+func TestURI(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link, err := url.Parse(URI("SocialMedia-API", "alice@example.com", secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if link.Scheme != "otpauth" || link.Host != "totp" || link.Path != "/SocialMedia-API:alice@example.com" {
+		t.Fatalf("unexpected link %s", link)
+	}
+	if query := link.Query(); query.Get("secret") != secret || query.Get("issuer") != "SocialMedia-API" {
+		t.Fatalf("unexpected query %v", query)
+	}
+}